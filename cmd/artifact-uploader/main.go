@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+
+	"github.com/knative/build/pkg/artifacts"
+	"github.com/knative/build/pkg/credentials/s3creds"
+	"github.com/knative/build/pkg/s3fetch"
+	"github.com/knative/pkg/logging"
+)
+
+// maxTerminationMessage is the most that the kubelet keeps of a container's
+// termination message.
+const maxTerminationMessage = 4096
+
+// paths implements flag.Value for the repeatable -path flag.
+type paths []string
+
+func (p *paths) String() string { return strings.Join(*p, ",") }
+
+func (p *paths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+var (
+	workspace = flag.String("workspace", "/workspace", "The directory to which the globs are relative.")
+	bucket    = flag.String("bucket", "", "The bucket to which the artifacts are uploaded.")
+	prefix    = flag.String("prefix", "", "The prefix of the keys of the uploaded artifacts.")
+	endpoint  = flag.String("endpoint", "", "The URL of the S3-compatible service; if empty, Amazon S3 is used.")
+	region    = flag.String("region", s3fetch.DefaultRegion, "The region of the bucket.")
+	destDir   = flag.String("dest_dir", "", "If set instead of bucket, the directory of a PersistentVolumeClaim to which the artifacts are copied.")
+	claimName = flag.String("claim_name", "", "The name of the PersistentVolumeClaim mounted at dest_dir.")
+	claimPath = flag.String("claim_path", "", "The directory of the PersistentVolumeClaim mounted at dest_dir.")
+
+	terminationMessagePath = flag.String("terminationMessagePath", "/dev/termination-log",
+		"Path of the file to which the uploaded artifacts, or the reason the upload failed, are written")
+
+	globs paths
+)
+
+func main() {
+	flag.Var(&globs, "path", "A glob, relative to the workspace, of the files to upload; may be repeated.")
+	flag.Parse()
+	logger, _ := logging.NewLogger("", "artifact-uploader")
+	defer logger.Sync()
+
+	var dest artifacts.Destination
+	if *destDir != "" {
+		dest = &artifacts.Volume{Dir: *destDir, ClaimName: *claimName, Path: *claimPath}
+	} else {
+		s3 := &artifacts.S3{
+			Endpoint: *endpoint,
+			Region:   *region,
+			Bucket:   *bucket,
+			Prefix:   *prefix,
+		}
+		// Use the S3 credentials written by creds-init, if any apply.
+		creds, ok, err := s3creds.Lookup(s3creds.ConfigPath(), *endpoint)
+		if err != nil {
+			logger.Fatalf("Failed to read S3 credentials: %v", err)
+		} else if ok {
+			s3.Credentials = &creds
+		}
+		dest = s3
+	}
+
+	uploaded, err := artifacts.Upload(*workspace, globs, dest)
+	for _, a := range uploaded {
+		logger.Infof("Uploaded %s to %s (sha256 %s)", a.Path, a.URI, a.SHA256)
+	}
+	if err != nil {
+		if err := ioutil.WriteFile(*terminationMessagePath, []byte(err.Error()), 0644); err != nil {
+			logger.Errorf("Failed to write termination message to %q: %v", *terminationMessagePath, err)
+		}
+		logger.Fatalf("Failed to upload artifacts: %v", err)
+	}
+
+	status, omitted := artifacts.FormatStatus(uploaded, maxTerminationMessage)
+	if omitted > 0 {
+		logger.Warnf("%d uploaded artifacts are too many to record in the build's status; it counts them in artifactsOmitted", omitted)
+	}
+	if err := ioutil.WriteFile(*terminationMessagePath, []byte(status), 0644); err != nil {
+		logger.Fatalf("Failed to write termination message to %q: %v", *terminationMessagePath, err)
+	}
+	logger.Infof("Successfully uploaded %d artifacts", len(uploaded))
+}
@@ -19,8 +19,7 @@ import (
 	"flag"
 
 	"github.com/knative/build/pkg/credentials"
-	"github.com/knative/build/pkg/credentials/dockercreds"
-	"github.com/knative/build/pkg/credentials/gitcreds"
+	_ "github.com/knative/build/pkg/credentials/all"
 	"github.com/knative/pkg/logging"
 )
 
@@ -31,8 +30,7 @@ func main() {
 	logger, _ := logging.NewLogger("", "creds-init")
 	defer logger.Sync()
 
-	builders := []credentials.Builder{dockercreds.NewBuilder(), gitcreds.NewBuilder()}
-	for _, c := range builders {
+	for _, c := range credentials.Builders() {
 		if err := c.Write(); err != nil {
 			logger.Fatalf("Error initializing credentials: %v", err)
 		}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/knative/build/pkg/entrypoint"
+)
+
+var (
+	ep       = flag.String("entrypoint", "", "The command to run once the previous step has finished")
+	waitFile = flag.String("wait_file", "", "The file to wait for before running the command; if empty, the command runs immediately")
+	postFile = flag.String("post_file", "", "The file to write once the command finishes")
+	copyTo   = flag.String("copy_to", "", "If set, copy this binary to the given path and exit")
+
+	stepName    = flag.String("step_name", "", "The name of the step, under which its outcome is recorded")
+	runPolicy   = flag.String("run_policy", string(entrypoint.RunOnSuccess), "Whether the command runs after an earlier step failed (OnSuccess or Always)")
+	whenStep    = flag.String("when_step", "", "If set, the command runs only if this earlier step's outcome is -when_outcome")
+	whenOutcome = flag.String("when_outcome", string(entrypoint.Failed), "The outcome of -when_step for which the command runs (Succeeded or Failed)")
+
+	terminationPath = flag.String("termination_path", "/dev/termination-log", "The file to which the step's termination message is written if it is skipped")
+)
+
+// pollInterval is how often the waiter checks for the wait file.
+const pollInterval = 100 * time.Millisecond
+
+// waiter waits for the wait file by polling for it.
+type waiter struct{}
+
+func (waiter) Wait(file string) (entrypoint.Outcomes, error) {
+	for {
+		b, err := ioutil.ReadFile(file)
+		if err == nil {
+			outcomes := entrypoint.Outcomes{}
+			return outcomes, json.Unmarshal(b, &outcomes)
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// runner runs the command with the step's standard streams.
+type runner struct{}
+
+func (runner) Run(args ...string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// postWriter writes the outcomes as JSON to the post file. The file is
+// renamed into place so that the next step never reads it partially written.
+type postWriter struct{}
+
+func (postWriter) Write(file string, outcomes entrypoint.Outcomes) error {
+	b, err := json.Marshal(outcomes)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file+".tmp", b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// copyBinary copies the running binary to path, so that step containers
+// can run it.
+func copyBinary(path string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(self)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func main() {
+	flag.Parse()
+
+	if *copyTo != "" {
+		if err := copyBinary(*copyTo); err != nil {
+			log.Fatalf("Error copying entrypoint to %q: %v", *copyTo, err)
+		}
+		return
+	}
+
+	err := entrypoint.Entrypointer{
+		Name:        *stepName,
+		Entrypoint:  *ep,
+		Args:        flag.Args(),
+		WaitFile:    *waitFile,
+		PostFile:    *postFile,
+		RunPolicy:   entrypoint.RunPolicy(*runPolicy),
+		WhenStep:    *whenStep,
+		WhenOutcome: entrypoint.Outcome(*whenOutcome),
+		Waiter:      waiter{},
+		Runner:      runner{},
+		PostWriter:  postWriter{},
+	}.Go()
+	if err == entrypoint.ErrSkipped {
+		// A skipped step succeeds, and is told apart by its termination
+		// message.
+		if err := ioutil.WriteFile(*terminationPath, []byte(entrypoint.SkippedMessage), 0666); err != nil {
+			log.Fatalf("Error writing termination message: %v", err)
+		}
+		return
+	}
+	switch err := err.(type) {
+	case nil:
+	case *exec.ExitError:
+		// Exit with the command's exit code, so that it is reported as
+		// the step's.
+		if status, ok := err.Sys().(syscall.WaitStatus); ok {
+			os.Exit(status.ExitStatus())
+		}
+		log.Fatal(err)
+	default:
+		log.Fatal(err)
+	}
+}
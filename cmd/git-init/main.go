@@ -18,10 +18,14 @@ package main
 import (
 	"bytes"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/knative/build/pkg/gitverify"
 	"github.com/knative/pkg/logging"
 	"go.uber.org/zap"
 )
@@ -30,8 +34,34 @@ var (
 	url      = flag.String("url", "", "The url of the Git repository to initialize.")
 	revision = flag.String("revision", "", "The Git revision to make the repository HEAD")
 	path     = flag.String("path", "", "Path of directory under which git repository will be copied")
+
+	depth      = flag.Int("depth", 1, "The number of commits of history to fetch, or 0 to fetch the full history")
+	submodules = flag.Bool("submodules", true, "Whether to fetch the repository's submodules along with the revision, ignoring failures to do so")
+	checkout   = flag.Bool("checkout_submodules", false, "Whether to check out the repository's submodules recursively, failing if any of them can't be")
+	lfs        = flag.Bool("lfs", false, "Whether to fetch and check out the files tracked by Git LFS")
+	sparse     paths
+
+	verify         = flag.String("verify", "", "If set, the kind of signature (GPG or SSH) that the revision must have")
+	trustedKeysDir = flag.String("trusted_keys_dir", "", "The directory whose files hold the keys that signatures are verified against")
+
+	terminationMessagePath = flag.String("terminationMessagePath", "/dev/termination-log",
+		"Path of the file to which the resolved commit is written, as a line of the form commit=<sha>")
 )
 
+func init() {
+	flag.Var(&sparse, "sparse_checkout", "A path, in the pattern syntax of .gitignore files, to check out; may be repeated. By default all paths are checked out")
+}
+
+// paths collects the values of a repeated flag.
+type paths []string
+
+func (p *paths) String() string { return strings.Join(*p, ",") }
+
+func (p *paths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
 func run(logger *zap.SugaredLogger, cmd string, args ...string) {
 	c := exec.Command(cmd, args...)
 	var output bytes.Buffer
@@ -88,15 +118,90 @@ func main() {
 	}
 
 	run(logger, "git", "remote", "add", "origin", *url)
-	err = runOrFail(logger, "git", "fetch", "--depth=1", "--recurse-submodules=yes", "origin", *revision)
+	if len(sparse) > 0 {
+		if err := enableSparseCheckout(sparse); err != nil {
+			logger.Fatalf("Failed to enable sparse checkout: %v", err)
+		}
+	}
+
+	recurse := "--recurse-submodules=no"
+	if *submodules {
+		recurse = "--recurse-submodules=yes"
+	}
+	fetchArgs := []string{"fetch", recurse}
+	if *depth > 0 {
+		fetchArgs = append(fetchArgs, fmt.Sprintf("--depth=%d", *depth))
+	}
+	err = runOrFail(logger, "git", append(fetchArgs, "origin", *revision)...)
 	if err != nil {
 		// Fetch can fail if an old commitid was used so try git pull, performing regardless of error
 		// as no guarantee that the same error is returned by all git servers gitlab, github etc...
-		run(logger, "git", "pull", "--recurse-submodules=yes", "origin")
+		run(logger, "git", "pull", recurse, "origin")
 		runOrFail(logger, "git", "checkout", *revision)
 	} else {
 		runOrFail(logger, "git", "reset", "--hard", "FETCH_HEAD")
 	}
 
-	logger.Infof("Successfully cloned %q @ %q in path %q", *url, *revision, dir)
+	if *checkout {
+		if err := runOrFail(logger, "git", "submodule", "update", "--init", "--recursive"); err != nil {
+			logger.Fatalf("Failed to check out submodules: %v", err)
+		}
+	}
+	if *lfs {
+		if err := runOrFail(logger, "git", "lfs", "install", "--local"); err != nil {
+			logger.Fatalf("Failed to install Git LFS: %v", err)
+		}
+		if err := runOrFail(logger, "git", "lfs", "pull", "origin"); err != nil {
+			logger.Fatalf("Failed to fetch Git LFS files: %v", err)
+		}
+	}
+
+	var sig *gitverify.Signature
+	if *verify != "" {
+		sig, err = gitverify.Verify(gitverify.Type(*verify), *trustedKeysDir)
+		if err != nil {
+			if err := ioutil.WriteFile(*terminationMessagePath, []byte(err.Error()), 0644); err != nil {
+				logger.Errorf("Failed to write termination message to %q: %v", *terminationMessagePath, err)
+			}
+			logger.Fatalf("Failed to verify the signature of %q: %v", *revision, err)
+		}
+		logger.Infof("Verified the signature of %q by %q (%s)", *revision, sig.Signer, sig.Key)
+	}
+
+	commit, err := resolveHead()
+	if err != nil {
+		logger.Fatalf("Failed to resolve HEAD: %v", err)
+	}
+	msg := "commit=" + commit + "\n"
+	if sig != nil {
+		msg += "signer=" + sig.Signer + "\nsigningKey=" + sig.Key + "\n"
+	}
+	if err := ioutil.WriteFile(*terminationMessagePath, []byte(msg), 0644); err != nil {
+		logger.Errorf("Failed to write resolved commit to %q: %v", *terminationMessagePath, err)
+	}
+
+	logger.Infof("Successfully cloned %q @ %q (%s) in path %q", *url, *revision, commit, dir)
+}
+
+// enableSparseCheckout limits the paths that are checked out in the
+// repository in the current directory to those matching patterns.
+func enableSparseCheckout(patterns []string) error {
+	if err := exec.Command("git", "config", "core.sparseCheckout", "true").Run(); err != nil {
+		return err
+	}
+	info := filepath.Join(".git", "info")
+	if err := os.MkdirAll(info, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(info, "sparse-checkout"), []byte(strings.Join(patterns, "\n")+"\n"), 0644)
+}
+
+// resolveHead returns the SHA of the commit checked out in the current
+// directory.
+func resolveHead() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/knative/build/pkg/httpfetch"
+	"github.com/knative/pkg/logging"
+)
+
+var (
+	url        = flag.String("url", "", "The http or https URL of the archive or file to fetch.")
+	sha256     = flag.String("sha256", "", "If set, the hex-encoded SHA-256 checksum the fetched content must have.")
+	format     = flag.String("format", string(httpfetch.File), "How the fetched content is unpacked (tar, tar.gz, zip or file).")
+	destDir    = flag.String("dest_dir", "", "The directory into which the content is unpacked.")
+	headersDir = flag.String("headers_dir", "", "If set, a directory whose files name the headers of the request, and contain their values.")
+
+	terminationMessagePath = flag.String("terminationMessagePath", "/dev/termination-log",
+		"Path of the file to which the reason the fetch failed is written")
+)
+
+// readHeaders returns the headers in dir, one per file.
+func readHeaders(dir string) (http.Header, error) {
+	header := http.Header{}
+	if dir == "" {
+		return header, nil
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		// Secret volumes hold their keys as symlinks to hidden files.
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		header.Set(f.Name(), strings.TrimSpace(string(b)))
+	}
+	return header, nil
+}
+
+func main() {
+	flag.Parse()
+	logger, _ := logging.NewLogger("", "http-fetcher")
+	defer logger.Sync()
+
+	header, err := readHeaders(*headersDir)
+	if err != nil {
+		logger.Fatalf("Failed to read headers from %q: %v", *headersDir, err)
+	}
+
+	if err := (httpfetch.Fetcher{
+		URL:    *url,
+		SHA256: *sha256,
+		Format: httpfetch.Format(*format),
+		Dest:   *destDir,
+		Header: header,
+	}).Fetch(); err != nil {
+		if err := ioutil.WriteFile(*terminationMessagePath, []byte(err.Error()), 0644); err != nil {
+			logger.Errorf("Failed to write termination message to %q: %v", *terminationMessagePath, err)
+		}
+		logger.Fatalf("Failed to fetch %q: %v", *url, err)
+	}
+
+	logger.Infof("Successfully fetched %q into %q", *url, *destDir)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/knative/build/pkg/credentials/dockercreds"
+	"github.com/knative/build/pkg/imagefetch"
+	"github.com/knative/pkg/logging"
+)
+
+var (
+	image   = flag.String("image", "", "The reference to the image to fetch, by tag or digest.")
+	path    = flag.String("path", "", "If set, the path of the directory or file within the image to fetch.")
+	destDir = flag.String("dest_dir", "", "The directory into which the image's files are extracted.")
+
+	terminationMessagePath = flag.String("terminationMessagePath", "/dev/termination-log",
+		"Path of the file to which the resolved digest is written, as a line of the form digest=<digest>")
+)
+
+func main() {
+	flag.Parse()
+	logger, _ := logging.NewLogger("", "image-fetcher")
+	defer logger.Sync()
+
+	ref, err := imagefetch.ParseReference(*image)
+	if err != nil {
+		logger.Fatalf("Invalid image reference: %v", err)
+	}
+
+	// Use the registry credentials written by creds-init.
+	config := dockercreds.ConfigPath()
+	digest, err := imagefetch.Fetcher{
+		Reference: ref,
+		Path:      *path,
+		Dest:      *destDir,
+		Keychain: func(registry string) (string, string, bool, error) {
+			return dockercreds.Lookup(config, registry)
+		},
+	}.Fetch()
+	if err != nil {
+		logger.Fatalf("Failed to fetch %q: %v", *image, err)
+	}
+	if err := ioutil.WriteFile(*terminationMessagePath, []byte("digest="+digest+"\n"), 0644); err != nil {
+		logger.Errorf("Failed to write resolved digest to %q: %v", *terminationMessagePath, err)
+	}
+
+	logger.Infof("Successfully fetched %q (%s) into %q", *image, digest, *destDir)
+}
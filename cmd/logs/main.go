@@ -18,25 +18,61 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/knative/build/pkg/logs"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
-var namespace = flag.String("n", "default", "The namespace scope for this CLI request")
+// stepsFlag implements flag.Value for the repeatable -step flag.
+type stepsFlag []string
+
+func (s *stepsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stepsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+var (
+	namespace  = flag.String("n", "default", "The namespace scope for this CLI request")
+	timestamps = flag.Bool("timestamps", false, "Prefix each line with the time it was logged")
+	since      = flag.Duration("since", 0, "Only print lines logged within this duration, such as 5m")
+	noColor    = flag.Bool("no-color", false, "Print the logs without colours")
+	output     = flag.String("output", "text", "The output format: text, or json for one object per line with the step, timestamp and line")
+	allSteps   = flag.Bool("all-steps", false, "Keep printing the logs of the remaining steps after a step fails; only steps run by the entrypoint, as in builds with step policies or sidecars, run after a failure")
+	steps      stepsFlag
+)
 
 func main() {
+	flag.Var(&steps, "step", "Only print the logs of this step; may be repeated")
 	flag.Parse()
 	if len(flag.Args()) != 1 {
-		log.Fatalf("Usage: %s [-n NAMESPACE] BUILD-NAME\n", os.Args[0])
+		log.Fatalf("Usage: %s [-n NAMESPACE] [FLAGS] BUILD-NAME\n", os.Args[0])
+	}
+
+	opts := logs.Options{
+		Timestamps: *timestamps,
+		Since:      *since,
+		Steps:      steps,
+		NoColor:    *noColor,
+		AllSteps:   *allSteps,
+	}
+	switch *output {
+	case "text":
+	case "json":
+		opts.JSON = true
+	default:
+		log.Fatalln(fmt.Errorf("unknown output format %q, want text or json", *output))
 	}
 
 	buildName := flag.Args()[0]
 	ctx := context.Background()
 
-	if err := logs.Tail(ctx, os.Stdout, buildName, *namespace); err != nil {
+	if err := logs.Tail(ctx, os.Stdout, buildName, *namespace, opts); err != nil {
 		log.Fatalln(err)
 	}
 }
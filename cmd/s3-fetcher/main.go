@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/knative/build/pkg/credentials/s3creds"
+	"github.com/knative/build/pkg/s3fetch"
+	"github.com/knative/pkg/logging"
+)
+
+var (
+	bucket   = flag.String("bucket", "", "The bucket that holds the source.")
+	key      = flag.String("key", "", "The key of the archive or manifest object to fetch.")
+	prefix   = flag.String("prefix", "", "If set instead of key, the prefix of the objects to fetch.")
+	srcType  = flag.String("type", string(s3fetch.Archive), "The style of source to fetch from key (Archive or Manifest).")
+	endpoint = flag.String("endpoint", "", "The URL of the S3-compatible service; if empty, Amazon S3 is used.")
+	region   = flag.String("region", s3fetch.DefaultRegion, "The region of the bucket.")
+	destDir  = flag.String("dest_dir", "", "The directory into which the source is fetched.")
+
+	terminationMessagePath = flag.String("terminationMessagePath", "/dev/termination-log",
+		"Path of the file to which the reason the fetch failed is written")
+)
+
+func main() {
+	flag.Parse()
+	logger, _ := logging.NewLogger("", "s3-fetcher")
+	defer logger.Sync()
+
+	f := s3fetch.Fetcher{
+		Endpoint: *endpoint,
+		Region:   *region,
+		Bucket:   *bucket,
+		Key:      *key,
+		Prefix:   *prefix,
+		Type:     s3fetch.Type(*srcType),
+		Dest:     *destDir,
+	}
+	// Use the S3 credentials written by creds-init, if any apply.
+	creds, ok, err := s3creds.Lookup(s3creds.ConfigPath(), *endpoint)
+	if err != nil {
+		logger.Fatalf("Failed to read S3 credentials: %v", err)
+	} else if ok {
+		f.Credentials = &creds
+	}
+
+	if err := f.Fetch(); err != nil {
+		if err := ioutil.WriteFile(*terminationMessagePath, []byte(err.Error()), 0644); err != nil {
+			logger.Errorf("Failed to write termination message to %q: %v", *terminationMessagePath, err)
+		}
+		logger.Fatalf("Failed to fetch s3://%s/%s%s: %v", *bucket, *key, *prefix, err)
+	}
+
+	logger.Infof("Successfully fetched s3://%s/%s%s into %q", *bucket, *key, *prefix, *destDir)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldValue returns the value of the build's field selected by fieldPath,
+// as used by a BuildFieldSelector; fields of unset structs are empty. The
+// supported paths are metadata.name, metadata.namespace, metadata.uid,
+// metadata.labels['<key>'], metadata.annotations['<key>'],
+// spec.serviceAccountName, spec.source.git.url and spec.source.git.revision.
+func (b *Build) FieldValue(fieldPath string) (string, error) {
+	if path, key, ok := splitMapPath(fieldPath); ok {
+		switch path {
+		case "metadata.labels":
+			return b.Labels[key], nil
+		case "metadata.annotations":
+			return b.Annotations[key], nil
+		}
+	}
+
+	switch fieldPath {
+	case "metadata.name":
+		return b.Name, nil
+	case "metadata.namespace":
+		return b.Namespace, nil
+	case "metadata.uid":
+		return string(b.UID), nil
+	case "spec.serviceAccountName":
+		return b.Spec.ServiceAccountName, nil
+	case "spec.source.git.url":
+		if s := b.Spec.Source; s != nil && s.Git != nil {
+			return s.Git.Url, nil
+		}
+		return "", nil
+	case "spec.source.git.revision":
+		if s := b.Spec.Source; s != nil && s.Git != nil {
+			return s.Git.Revision, nil
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported field path %q", fieldPath)
+}
+
+// splitMapPath splits a path of the form path['key'].
+func splitMapPath(fieldPath string) (path, key string, ok bool) {
+	i := strings.Index(fieldPath, "['")
+	if i < 0 || !strings.HasSuffix(fieldPath, "']") || len(fieldPath) < i+len("['']") {
+		return "", "", false
+	}
+	return fieldPath[:i], fieldPath[i+len("['") : len(fieldPath)-len("']")], true
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFieldValue(t *testing.T) {
+	b := &Build{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "build",
+			Namespace:   "ns",
+			UID:         "1234",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"example.com/team": "infra"},
+		},
+		Spec: BuildSpec{
+			ServiceAccountName: "builder",
+			Source: &SourceSpec{
+				Git: &GitSourceSpec{
+					Url:      "https://github.com/knative/build",
+					Revision: "master",
+				},
+			},
+		},
+	}
+	for _, c := range []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "metadata.name", want: "build"},
+		{path: "metadata.namespace", want: "ns"},
+		{path: "metadata.uid", want: "1234"},
+		{path: "metadata.labels['app']", want: "web"},
+		{path: "metadata.labels['missing']", want: ""},
+		{path: "metadata.annotations['example.com/team']", want: "infra"},
+		{path: "spec.serviceAccountName", want: "builder"},
+		{path: "spec.source.git.url", want: "https://github.com/knative/build"},
+		{path: "spec.source.git.revision", want: "master"},
+		{path: "spec.steps", wantErr: true},
+		{path: "metadata.labels['app'", wantErr: true},
+		{path: "status.startTime", wantErr: true},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			got, err := b.FieldValue(c.path)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("FieldValue(%q) = %v, wantErr %t", c.path, err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("FieldValue(%q) = %q, want %q", c.path, got, c.want)
+			}
+		})
+	}
+
+	if got, err := (&Build{}).FieldValue("spec.source.git.revision"); err != nil || got != "" {
+		t.Errorf("FieldValue() without source = %q, %v; want empty", got, err)
+	}
+}
@@ -33,6 +33,50 @@ type Template interface {
 	TemplateSpec() BuildTemplateSpec
 }
 
+// BaseTemplate references a template that another template extends.
+type BaseTemplate struct {
+	// Name is the name of the base template. A BuildTemplate must be in
+	// the same namespace as the template that extends it.
+	Name string `json:"name"`
+
+	// Kind is the kind of the base template: BuildTemplate (the default)
+	// or ClusterBuildTemplate. ClusterBuildTemplates may only extend
+	// ClusterBuildTemplates.
+	// +optional
+	Kind TemplateKind `json:"kind,omitempty"`
+
+	// Position is where the base template's steps run relative to the
+	// extending template's own steps: Before (the default) or After.
+	// +optional
+	Position BasePosition `json:"position,omitempty"`
+}
+
+// BasePosition is where a base template's steps run.
+type BasePosition string
+
+const (
+	// BaseBefore runs the base template's steps before the extending
+	// template's own steps.
+	BaseBefore BasePosition = "Before"
+	// BaseAfter runs the base template's steps after the extending
+	// template's own steps.
+	BaseAfter BasePosition = "After"
+)
+
+// ResultSpec declares a result that a template's steps report.
+//
+// Steps report a result by writing a line of the form "name=value" to their
+// termination message, /dev/termination-log; the declared results of steps
+// that succeed are surfaced in the Build's status. A Build whose steps
+// succeed without reporting a declared result fails.
+type ResultSpec struct {
+	// Name is the unique name of the result.
+	Name string `json:"name"`
+
+	// Description is a human-readable explanation of the result.
+	Description string `json:"description,omitempty"`
+}
+
 // +genclient
 // +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -65,6 +109,15 @@ type BuildTemplateSpec struct {
 	// Parameters defines the parameters that can be populated in a template.
 	Parameters []ParameterSpec `json:"parameters,omitempty"`
 
+	// Bases, if specified, lists templates whose steps are run before or
+	// after this template's own steps, and whose volumes, parameters and
+	// results are merged into this template's. Where the templates declare
+	// a volume, parameter or result with the same name, this template's
+	// declaration is used. A template reached through more than one base
+	// is merged once, where it is first reached.
+	// +optional
+	Bases []BaseTemplate `json:"bases,omitempty"`
+
 	// Steps are the steps of the build; each step is run sequentially with the
 	// source mounted into /workspace.
 	Steps []corev1.Container `json:"steps"`
@@ -72,6 +125,21 @@ type BuildTemplateSpec struct {
 	// Volumes is a collection of volumes that are available to mount into the
 	// steps of the build.
 	Volumes []corev1.Volume `json:"volumes"`
+
+	// StepTimeouts, if specified, lists the time after which each step,
+	// in order, times out; an unset or zero entry means the step is only
+	// bounded by the build's Timeout.
+	// +optional
+	StepTimeouts []metav1.Duration `json:"stepTimeouts,omitempty"`
+
+	// StepPolicies, if specified, control whether named steps run after an
+	// earlier step failed.
+	// +optional
+	StepPolicies []StepPolicy `json:"stepPolicies,omitempty"`
+
+	// Results declares the results that the template's steps report.
+	// +optional
+	Results []ResultSpec `json:"results,omitempty"`
 }
 
 // ParameterSpec defines the possible parameters that can be populated in a
@@ -86,8 +154,46 @@ type ParameterSpec struct {
 	// Default, if specified, defines the default value that should be applied if
 	// the build does not specify the value for this parameter.
 	Default *string `json:"default,omitempty"`
+
+	// Type is the type of the parameter's value: string, int, bool or
+	// array. Defaults to string.
+	// +optional
+	Type ParameterType `json:"type,omitempty"`
+
+	// Enum, if specified, lists the values the parameter may take; for
+	// array parameters, it lists the values each element may take.
+	// +optional
+	Enum []string `json:"enum,omitempty"`
+
+	// Pattern, if specified, is a regular expression that the parameter's
+	// value, or each element of an array parameter, must match in full.
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+
+	// DefaultValues, if specified, defines the default values of an array
+	// parameter, which are applied if the build does not specify them.
+	// +optional
+	DefaultValues []string `json:"defaultValues,omitempty"`
 }
 
+// ParameterType is the type of a template parameter's value.
+type ParameterType string
+
+const (
+	// ParameterTypeString is a parameter whose value is any string.
+	ParameterTypeString ParameterType = "string"
+	// ParameterTypeInt is a parameter whose value is an integer.
+	ParameterTypeInt ParameterType = "int"
+	// ParameterTypeBool is a parameter whose value is a boolean, such as
+	// "true" or "false".
+	ParameterTypeBool ParameterType = "bool"
+	// ParameterTypeArray is a parameter whose value is a list of strings.
+	// Where a step's args or command consist of just the parameter's
+	// placeholder, it expands into one entry for each of its values;
+	// elsewhere it is replaced by the values separated by spaces.
+	ParameterTypeArray ParameterType = "array"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // BuildTemplateList is a list of BuildTemplate resources.
@@ -18,12 +18,20 @@ package v1alpha1
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/knative/pkg/apis"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
+// resultNameRegexp matches the names of results that steps may report.
+var resultNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*$`)
+
 // Validate build template
 func (b *BuildTemplate) Validate(ctx context.Context) *apis.FieldError {
 	return validateObjectMetadata(b.GetObjectMeta()).ViaField("metadata").Also(b.Spec.Validate(ctx).ViaField("spec"))
@@ -40,6 +48,32 @@ func (b *BuildTemplateSpec) Validate(ctx context.Context) *apis.FieldError {
 	if err := validateParameters(b.Parameters); err != nil {
 		return err
 	}
+	if len(b.StepTimeouts) > len(b.Steps) {
+		return apis.ErrInvalidValue("more step timeouts than steps", "stepTimeouts")
+	}
+	if err := validateStepTimeouts(b.StepTimeouts); err != nil {
+		return err
+	}
+	if err := validateResults(b.Results); err != nil {
+		return err
+	}
+	if err := validateBases(b.Bases); err != nil {
+		return err
+	}
+	// Policies may apply to the steps of base templates, which are only
+	// known once the template is flattened.
+	steps := b.Steps
+	if len(b.Bases) > 0 {
+		steps = nil
+	}
+	if err := ValidateStepPolicies(b.StepPolicies, steps); err != nil {
+		return err
+	}
+	if len(b.StepPolicies) > 0 {
+		if err := validateStepCommands(b.Steps); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -76,6 +110,27 @@ func validateSteps(steps []corev1.Container) *apis.FieldError {
 	return nil
 }
 
+// validateStepCommands checks that each step specifies its command, as
+// steps run as regular containers are started by an injected entrypoint
+// that can't use their images' entrypoints.
+func validateStepCommands(steps []corev1.Container) *apis.FieldError {
+	for i, s := range steps {
+		if len(s.Command) == 0 {
+			return apis.ErrMissingField(fmt.Sprintf("steps[%d].command", i))
+		}
+	}
+	return nil
+}
+
+func validateStepTimeouts(timeouts []metav1.Duration) *apis.FieldError {
+	for _, t := range timeouts {
+		if t.Duration > maxTimeout || t.Duration < 0 {
+			return apis.ErrOutOfBoundsValue(t.Duration.String(), "0", "24", "stepTimeouts")
+		}
+	}
+	return nil
+}
+
 func validateParameters(params []ParameterSpec) *apis.FieldError {
 	// Template must not duplicate parameter names.
 	seen := sets.NewString()
@@ -84,6 +139,172 @@ func validateParameters(params []ParameterSpec) *apis.FieldError {
 			return apis.ErrInvalidKeyName("ParamName", "b.spec.params")
 		}
 		seen.Insert(p.Name)
+
+		switch p.Type {
+		case "", ParameterTypeString, ParameterTypeInt, ParameterTypeBool:
+			if p.DefaultValues != nil {
+				return apis.ErrDisallowedFields("parameters.defaultValues")
+			}
+		case ParameterTypeArray:
+			if p.Default != nil {
+				return apis.ErrDisallowedFields("parameters.default")
+			}
+		default:
+			return apis.ErrInvalidValue(string(p.Type), "parameters.type")
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return apis.ErrInvalidValue(p.Pattern, "parameters.pattern")
+			}
+		}
+		// Enum values are checked against the type and pattern only, as
+		// checking them against the enum itself is trivial.
+		typed := p
+		typed.Enum = nil
+		for _, e := range p.Enum {
+			if err := typed.validateValue(e); err != nil {
+				return apis.ErrInvalidValue(e, "parameters.enum")
+			}
+		}
+		if p.Default != nil {
+			if err := p.validateValue(*p.Default); err != nil {
+				return apis.ErrInvalidValue(*p.Default, "parameters.default")
+			}
+		}
+		for _, v := range p.DefaultValues {
+			if err := p.validateValue(v); err != nil {
+				return apis.ErrInvalidValue(v, "parameters.defaultValues")
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateArgument checks that the argument conforms to the parameter's
+// type, enum and pattern.
+func (p *ParameterSpec) ValidateArgument(a ArgumentSpec) error {
+	if p.Type == ParameterTypeArray {
+		if a.ValueFrom != nil {
+			return fmt.Errorf("array parameters can't take their values from a ConfigMap or Secret")
+		}
+		if a.Value != "" {
+			return fmt.Errorf("expected an array of values, got %q", a.Value)
+		}
+		for _, v := range a.Values {
+			if err := p.validateValue(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(a.Values) > 0 {
+		return fmt.Errorf("expected a single value, got an array")
+	}
+	return p.validateValue(a.Value)
+}
+
+// validateValue checks that a single value, or an element of an array,
+// conforms to the parameter's type, enum and pattern.
+func (p *ParameterSpec) validateValue(v string) error {
+	switch p.Type {
+	case ParameterTypeInt:
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an int", v)
+		}
+	case ParameterTypeBool:
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("%q is not a bool", v)
+		}
+	}
+	if len(p.Enum) > 0 && !sets.NewString(p.Enum...).Has(v) {
+		return fmt.Errorf("%q is not one of %s", v, strings.Join(p.Enum, ", "))
+	}
+	if p.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + p.Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", p.Pattern, err)
+		}
+		if !re.MatchString(v) {
+			return fmt.Errorf("%q does not match pattern %q", v, p.Pattern)
+		}
+	}
+	return nil
+}
+
+func validateResults(results []ResultSpec) *apis.FieldError {
+	// Template must not duplicate result names.
+	seen := sets.NewString()
+	for _, r := range results {
+		if !resultNameRegexp.MatchString(r.Name) {
+			return apis.ErrInvalidValue(r.Name, "results.name")
+		}
+		if seen.Has(r.Name) {
+			return apis.ErrMultipleOneOf("results.name")
+		}
+		seen.Insert(r.Name)
+	}
+	return nil
+}
+
+// ValidateStepPolicies validates the policies of the given steps. If steps
+// is nil, as for a build that uses a template, the steps named by the
+// policies aren't checked; they are once the template is applied.
+func ValidateStepPolicies(policies []StepPolicy, steps []corev1.Container) *apis.FieldError {
+	index := map[string]int{}
+	for i, s := range steps {
+		if s.Name != "" {
+			index[s.Name] = i
+		}
+	}
+	seen := sets.NewString()
+	for _, p := range policies {
+		if seen.Has(p.Step) {
+			return apis.ErrMultipleOneOf("stepPolicies.step")
+		}
+		seen.Insert(p.Step)
+		i, ok := index[p.Step]
+		if steps != nil && !ok {
+			return apis.ErrInvalidValue(p.Step, "stepPolicies.step")
+		}
+
+		switch p.RunPolicy {
+		case "", RunOnSuccess, RunAlways:
+		default:
+			return apis.ErrInvalidValue(string(p.RunPolicy), "stepPolicies.runPolicy")
+		}
+		if p.When == nil {
+			continue
+		}
+		if p.RunPolicy != "" {
+			return apis.ErrMultipleOneOf("stepPolicies.runPolicy", "stepPolicies.when")
+		}
+		if j, ok := index[p.When.Step]; steps != nil && (!ok || j >= i) {
+			return apis.ErrInvalidValue(p.When.Step, "stepPolicies.when.step")
+		}
+		switch p.When.Outcome {
+		case StepSucceeded, StepFailed:
+		default:
+			return apis.ErrInvalidValue(string(p.When.Outcome), "stepPolicies.when.outcome")
+		}
+	}
+	return nil
+}
+
+func validateBases(bases []BaseTemplate) *apis.FieldError {
+	for _, b := range bases {
+		if b.Name == "" {
+			return apis.ErrMissingField("bases.name")
+		}
+		switch b.Kind {
+		case "", BuildTemplateKind, ClusterBuildTemplateKind:
+		default:
+			return apis.ErrInvalidValue(string(b.Kind), "bases.kind")
+		}
+		switch b.Position {
+		case "", BaseBefore, BaseAfter:
+		default:
+			return apis.ErrInvalidValue(string(b.Position), "bases.position")
+		}
 	}
 	return nil
 }
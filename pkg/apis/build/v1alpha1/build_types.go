@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -68,11 +69,29 @@ type BuildSpec struct {
 	// +optional
 	Steps []corev1.Container `json:"steps,omitempty"`
 
+	// Sidecars are containers that run alongside all of the build's steps,
+	// such as a docker daemon or a test database. Builds with sidecars run
+	// their steps as regular containers, ordered by an injected entrypoint,
+	// so each step must specify its Command.
+	// +optional
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+
 	// Volumes is a collection of volumes that are available to mount into the
 	// steps of the build.
 	// +optional
 	Volumes []corev1.Volume `json:"volumes,omitempty"`
 
+	// Caches, if specified, are directories that persist between builds,
+	// such as dependency caches, and are mounted into each of the build's
+	// steps.
+	// +optional
+	Caches []CacheSpec `json:"caches,omitempty"`
+
+	// Artifacts, if specified, are files in the workspace that are uploaded
+	// once the build's steps have finished.
+	// +optional
+	Artifacts *ArtifactsSpec `json:"artifacts,omitempty"`
+
 	// The name of the service account as which to run this build.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
@@ -99,6 +118,52 @@ type BuildSpec struct {
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// StepTimeouts, if specified, lists the time after which each step,
+	// in order, times out; an unset or zero entry means the step is only
+	// bounded by the build's Timeout. If the build uses a template, these
+	// apply to the template's steps, overriding the template's StepTimeouts.
+	// +optional
+	StepTimeouts []metav1.Duration `json:"stepTimeouts,omitempty"`
+
+	// StepPolicies, if specified, control whether named steps run after an
+	// earlier step failed; by default a step runs only if no earlier step
+	// failed. Builds with step policies run their steps as regular
+	// containers, so each step must specify its Command. If the build uses
+	// a template, these apply to the template's steps, overriding the
+	// template's StepPolicies.
+	// +optional
+	StepPolicies []StepPolicy `json:"stepPolicies,omitempty"`
+
+	// Retries is the number of times a build whose steps fail is re-run
+	// before it is marked as failed. Cancelled and timed out builds are not
+	// retried. Defaults to 0.
+	// +optional
+	Retries int `json:"retries,omitempty"`
+
+	// Results declares the results that the build's steps report. Only
+	// declared results are recorded in the build's status, and a build
+	// whose steps succeed without reporting each of them fails. If the
+	// build uses a template, the template's results are declared too.
+	// +optional
+	Results []ResultSpec `json:"results,omitempty"`
+
+	// RetryBackoff is the delay before the first retry of a failed build;
+	// the delay doubles for each subsequent retry. Defaults to no delay.
+	// +optional
+	RetryBackoff *metav1.Duration `json:"retryBackoff,omitempty"`
+
+	// Builder, if specified, selects the implementation that executes the
+	// build. If nothing is specified, the controller's default builder is
+	// used.
+	// +optional
+	Builder BuildProvider `json:"builder,omitempty"`
+
+	// Notify, if specified, is the URI of a sink to which CloudEvents are
+	// sent as the build's state changes. If nothing is specified, the
+	// controller's default sink is used, if it has one.
+	// +optional
+	Notify string `json:"notify,omitempty"`
+
 	// Used for cancelling a job (and maybe more later on)
 	// +optional
 	Status BuildSpecStatus
@@ -153,7 +218,35 @@ type ArgumentSpec struct {
 	Name string `json:"name"`
 	// Value is the value of the argument.
 	Value string `json:"value"`
-	// TODO(jasonhall): ValueFrom?
+	// Values are the values of an array argument.
+	// +optional
+	Values []string `json:"values,omitempty"`
+	// ValueFrom, if specified, is the source of the argument's value.
+	// +optional
+	ValueFrom *ArgumentValueSource `json:"valueFrom,omitempty"`
+}
+
+// ArgumentValueSource is the source of an argument's value. Exactly one of
+// its fields must be set.
+type ArgumentValueSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the build's namespace.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects a key of a Secret in the build's namespace. The
+	// value is passed to the steps in an environment variable, so its
+	// placeholder may only be used in their commands, args and environment.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// FieldRef selects a field of the build.
+	// +optional
+	FieldRef *BuildFieldSelector `json:"fieldRef,omitempty"`
+}
+
+// BuildFieldSelector selects a field of a build, such as metadata.name,
+// metadata.labels['key'] or spec.source.git.revision.
+type BuildFieldSelector struct {
+	// FieldPath is the path of the field to select.
+	FieldPath string `json:"fieldPath"`
 }
 
 // SourceSpec defines the input to the Build
@@ -166,6 +259,19 @@ type SourceSpec struct {
 	// +optional
 	GCS *GCSSourceSpec `json:"gcs,omitempty"`
 
+	// HTTP represents source in an archive or file fetched over HTTP(S).
+	// +optional
+	HTTP *HTTPSourceSpec `json:"http,omitempty"`
+
+	// Image represents source in the filesystem of a container image.
+	// +optional
+	Image *ImageSourceSpec `json:"image,omitempty"`
+
+	// S3 represents source in S3-compatible object storage, such as
+	// Amazon S3 or MinIO.
+	// +optional
+	S3 *S3SourceSpec `json:"s3,omitempty"`
+
 	// Custom indicates that source should be retrieved using a custom
 	// process defined in a container invocation.
 	// +optional
@@ -202,8 +308,122 @@ type GitSourceSpec struct {
 	// https://git-scm.com/docs/gitrevisions#_specifying_revisions for more
 	// information.
 	Revision string `json:"revision"`
+
+	// Depth is the number of commits of history to fetch, or 0 to fetch
+	// the full history, as needed by commands such as `git describe`.
+	// Defaults to 1.
+	// +optional
+	Depth *int32 `json:"depth,omitempty"`
+
+	// SparseCheckout, if specified, lists the paths, in the pattern syntax
+	// of .gitignore files, that are checked out; other paths of the
+	// repository are not written to the workspace.
+	// +optional
+	SparseCheckout []string `json:"sparseCheckout,omitempty"`
+
+	// Submodules controls how the repository's submodules are checked out.
+	// If true, they are checked out recursively, and the source fails if
+	// any of them can't be. If false, they are not fetched. By default they
+	// are fetched along with the revision, and failures to do so are
+	// ignored.
+	// +optional
+	Submodules *bool `json:"submodules,omitempty"`
+
+	// LFS controls whether files tracked by Git LFS are fetched and checked
+	// out, rather than left as pointer files. Defaults to false.
+	// +optional
+	LFS bool `json:"lfs,omitempty"`
+
+	// Verify, if specified, requires the revision to be signed by one of a
+	// set of trusted keys. The build fails with reason UnverifiedRevision
+	// if it isn't.
+	// +optional
+	Verify *GitVerifySpec `json:"verify,omitempty"`
+
+	// CommitStatus, if specified, posts a pending status for the commit
+	// that is checked out when it is resolved, and a success or failure
+	// status when the build finishes. The API token is taken from the Git
+	// credentials of the build's ServiceAccount. If Revision isn't a commit
+	// SHA, no status is posted for a build that finishes, such as by timing
+	// out, before the source is fetched, as its commit isn't known.
+	// +optional
+	CommitStatus *CommitStatusSpec `json:"commitStatus,omitempty"`
 }
 
+// CommitStatusSpec describes where and how commit statuses are posted.
+type CommitStatusSpec struct {
+	// Provider is the flavor of the API that statuses are posted to.
+	// Defaults to GitHub.
+	// +optional
+	Provider CommitStatusProvider `json:"provider,omitempty"`
+
+	// APIURL is the base URL of the provider's API. Defaults to
+	// https://api.github.com for GitHub and https://gitlab.com/api/v4 for
+	// GitLab. It must be served from the host of the repository's URL, or
+	// be https://api.github.com for repositories on github.com. Statuses
+	// are posted with the API token of the first secret of the build's
+	// ServiceAccount that is annotated for the API's host, or for
+	// github.com for GitHub's API.
+	// +optional
+	APIURL string `json:"apiURL,omitempty"`
+
+	// Context distinguishes the build's statuses from those posted by
+	// other systems. Defaults to "knative-build".
+	// +optional
+	Context string `json:"context,omitempty"`
+
+	// TargetURL is a Go text/template for the link attached to each status.
+	// It may refer to {{.Namespace}}, {{.Name}}, {{.Commit}} and
+	// {{.PodName}}.
+	// +optional
+	TargetURL string `json:"targetURL,omitempty"`
+}
+
+// CommitStatusProvider defines a flavor of commit status API.
+type CommitStatusProvider string
+
+const (
+	// GitHubCommitStatus posts statuses with the GitHub API.
+	GitHubCommitStatus CommitStatusProvider = "GitHub"
+
+	// GitLabCommitStatus posts statuses with the GitLab API.
+	GitLabCommitStatus CommitStatusProvider = "GitLab"
+)
+
+// GitVerifySpec is a policy for verifying the signature of a Git revision.
+// When the revision names an annotated tag, the tag's signature is
+// verified; otherwise that of the commit that is checked out.
+//
+// For GPG signatures, each value of the ConfigMap or Secret is an
+// ASCII-armored public key. For SSH signatures, each value holds lines in
+// the allowed signers format of ssh-keygen(1).
+type GitVerifySpec struct {
+	// Type is the kind of signature that is required.
+	Type GitSignatureType `json:"type"`
+
+	// TrustedKeysConfigMap is the name of a ConfigMap in the build's
+	// namespace that holds the trusted keys. Exactly one of
+	// TrustedKeysConfigMap and TrustedKeysSecret must be specified.
+	// +optional
+	TrustedKeysConfigMap string `json:"trustedKeysConfigMap,omitempty"`
+
+	// TrustedKeysSecret is the name of a Secret in the build's namespace
+	// that holds the trusted keys.
+	// +optional
+	TrustedKeysSecret string `json:"trustedKeysSecret,omitempty"`
+}
+
+// GitSignatureType defines a kind of signature of a Git revision.
+type GitSignatureType string
+
+const (
+	// GitSignatureGPG indicates an OpenPGP signature made with GPG.
+	GitSignatureGPG GitSignatureType = "GPG"
+
+	// GitSignatureSSH indicates a signature made with an SSH key.
+	GitSignatureSSH GitSignatureType = "SSH"
+)
+
 // GCSSourceSpec describes source input to the Build in the form of an archive,
 // or a source manifest describing files to fetch.
 type GCSSourceSpec struct {
@@ -214,6 +434,157 @@ type GCSSourceSpec struct {
 	Location string `json:"location,omitempty"`
 }
 
+// HTTPSourceSpec describes source input to the Build in the form of an
+// archive or a single file fetched over HTTP(S).
+type HTTPSourceSpec struct {
+	// URL is the http or https URL of the archive or file to fetch.
+	URL string `json:"url"`
+
+	// SHA256, if specified, is the hex-encoded SHA-256 checksum that the
+	// fetched content must have; the build fails if it doesn't.
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Format declares how the fetched content is unpacked into the
+	// workspace. Defaults to File.
+	// +optional
+	Format HTTPSourceFormat `json:"format,omitempty"`
+
+	// HeadersSecret, if specified, is the name of a Secret in the build's
+	// namespace whose keys and values are sent as headers of the request,
+	// such as Authorization.
+	// +optional
+	HeadersSecret string `json:"headersSecret,omitempty"`
+}
+
+// ImageSourceSpec describes source input to the Build in the form of the
+// filesystem of a container image, such as a bundle pushed by an earlier
+// build. Images are pulled with the registry credentials of the build's
+// service account.
+type ImageSourceSpec struct {
+	// Reference is the reference to the image, by tag or digest.
+	Reference string `json:"reference"`
+
+	// Path, if specified, is the path of the directory or file within the
+	// image to fetch; by default the image's whole filesystem is fetched.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// S3SourceSpec describes source input to the Build in S3-compatible object
+// storage: an archive, a source manifest describing files to fetch, or the
+// objects under a prefix. Requests are signed with the S3 credentials of
+// the build's service account.
+type S3SourceSpec struct {
+	// Type declares the style of source to fetch from Key. Defaults to
+	// Archive.
+	// +optional
+	Type S3SourceType `json:"type,omitempty"`
+
+	// Bucket is the name of the bucket that holds the source.
+	Bucket string `json:"bucket"`
+
+	// Key is the key of the archive or manifest object. Archives may be
+	// tar, gzipped tar or zip files, according to the key's extension.
+	// Exactly one of Key and Prefix must be specified.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Prefix selects the objects to fetch, which are written to the
+	// workspace at their keys relative to the prefix.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint is the URL of the S3-compatible service, such as
+	// https://minio.example.com:9000. Defaults to Amazon S3.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region is the region of the bucket. Defaults to us-east-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// ArtifactsSpec describes the files that a build uploads after its steps.
+type ArtifactsSpec struct {
+	// Paths are globs, relative to the workspace, of the files to upload.
+	// Directories that match are uploaded with all of their files, which
+	// keep their paths relative to the workspace.
+	Paths []string `json:"paths"`
+
+	// Destination is where the files are uploaded.
+	Destination ArtifactDestination `json:"destination"`
+
+	// Always uploads the files even if one of the build's steps failed.
+	// Builds that always upload their artifacts run their steps as regular
+	// containers, so each step must specify its Command.
+	// +optional
+	Always bool `json:"always,omitempty"`
+}
+
+// ArtifactDestination is where a build's artifacts are uploaded; exactly
+// one of its fields must be specified.
+type ArtifactDestination struct {
+	// S3 uploads the artifacts to a bucket of Amazon S3 or of an
+	// S3-compatible service, such as Google Cloud Storage through its XML
+	// API. Requests are signed with the S3 credentials of the build's
+	// service account.
+	// +optional
+	S3 *S3ArtifactDestination `json:"s3,omitempty"`
+
+	// PersistentVolumeClaim copies the artifacts to a directory of a
+	// PersistentVolumeClaim.
+	// +optional
+	PersistentVolumeClaim *PVCArtifactDestination `json:"persistentVolumeClaim,omitempty"`
+}
+
+// S3ArtifactDestination uploads artifacts to S3-compatible object storage.
+type S3ArtifactDestination struct {
+	// Bucket is the name of the bucket to which the artifacts are uploaded.
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the paths of the artifacts to form their
+	// keys.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint is the URL of the S3-compatible service, such as
+	// https://storage.googleapis.com. Defaults to Amazon S3.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region is the region of the bucket. Defaults to us-east-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// PVCArtifactDestination copies artifacts to a PersistentVolumeClaim.
+type PVCArtifactDestination struct {
+	// ClaimName is the name of the PersistentVolumeClaim in the build's
+	// namespace.
+	ClaimName string `json:"claimName"`
+
+	// Path is the directory of the claim, relative to its root, to which
+	// the artifacts are copied.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// HTTPSourceFormat defines how HTTP source is unpacked.
+type HTTPSourceFormat string
+
+const (
+	// HTTPTar indicates that the source is a tar archive.
+	HTTPTar HTTPSourceFormat = "tar"
+	// HTTPTarGz indicates that the source is a gzipped tar archive.
+	HTTPTarGz HTTPSourceFormat = "tar.gz"
+	// HTTPZip indicates that the source is a zip archive.
+	HTTPZip HTTPSourceFormat = "zip"
+	// HTTPFile indicates that the source is a single file, which is
+	// written to the workspace under the last element of the URL's path.
+	HTTPFile HTTPSourceFormat = "file"
+)
+
 // GCSSourceType defines a type of GCS source fetch.
 type GCSSourceType string
 
@@ -226,6 +597,102 @@ const (
 	GCSManifest GCSSourceType = "Manifest"
 )
 
+// S3SourceType defines a type of S3 source fetch.
+type S3SourceType string
+
+const (
+	// S3Archive indicates that source should be fetched from an archive
+	// object.
+	S3Archive S3SourceType = "Archive"
+
+	// S3Manifest indicates that source should be fetched using a manifest
+	// object, in the same format as GCSManifest, whose files are S3 objects.
+	S3Manifest S3SourceType = "Manifest"
+)
+
+// CacheSpec describes a directory whose contents are kept between builds.
+//
+// Each cache is stored in a PersistentVolumeClaim that the controller
+// provisions in the build's namespace, and which is reused by later builds
+// with the same cache name and key. Only one build at a time may write to
+// a cache; concurrent builds that share it mount it read-only, so the
+// claim is requested as ReadWriteMany unless the controller is configured
+// otherwise.
+type CacheSpec struct {
+	// Name is the name of the cache, which must be a DNS label.
+	Name string `json:"name"`
+
+	// MountPath is the path at which the cache is mounted into each step.
+	MountPath string `json:"mountPath"`
+
+	// Key, if specified, selects which copy of the cache to use, so that
+	// builds only share a cache when their keys match. Template arguments
+	// are substituted into the key. The cache is chosen before the build's
+	// sources are fetched, so the key can't be derived from their contents;
+	// to key a cache by, say, a lock file, whoever creates the build must
+	// compute its checksum and pass it as an argument.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Size is the storage requested for the cache when it is first
+	// provisioned. Defaults to the controller's configured cache size.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// StorageClassName is the storage class of the cache's claim. Defaults
+	// to the cluster's default storage class.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// StepPolicy controls whether a step runs, based on the outcomes of the
+// steps before it.
+type StepPolicy struct {
+	// Step is the name of the step to which the policy applies.
+	Step string `json:"step"`
+
+	// RunPolicy controls whether the step runs after an earlier step
+	// failed; it defaults to OnSuccess.
+	// +optional
+	RunPolicy StepRunPolicy `json:"runPolicy,omitempty"`
+
+	// When, if specified, runs the step only if the named earlier step had
+	// the given outcome.
+	// +optional
+	When *StepCondition `json:"when,omitempty"`
+}
+
+// StepRunPolicy controls whether a step runs after an earlier step failed.
+type StepRunPolicy string
+
+const (
+	// RunOnSuccess runs the step only if no earlier step failed.
+	RunOnSuccess StepRunPolicy = "OnSuccess"
+	// RunAlways runs the step whatever the outcomes of earlier steps, for
+	// example to clean up or to send notifications.
+	RunAlways StepRunPolicy = "Always"
+)
+
+// StepCondition is a condition on the outcome of an earlier step.
+type StepCondition struct {
+	// Step is the name of the earlier step.
+	Step string `json:"step"`
+
+	// Outcome is the outcome of the earlier step for which the condition
+	// holds: Succeeded or Failed.
+	Outcome StepOutcome `json:"outcome"`
+}
+
+// StepOutcome is the outcome of a step.
+type StepOutcome string
+
+const (
+	// StepSucceeded indicates that the step succeeded.
+	StepSucceeded StepOutcome = "Succeeded"
+	// StepFailed indicates that the step failed.
+	StepFailed StepOutcome = "Failed"
+)
+
 // BuildProvider defines a build execution implementation.
 type BuildProvider string
 
@@ -266,6 +733,124 @@ type BuildStatus struct {
 	// StepsCompleted lists the name of build steps completed.
 	// +optional
 	StepsCompleted []string `json:"stepsCompleted",omitempty`
+
+	// StepsSkipped lists the name of build steps that didn't run because
+	// of their step policies.
+	// +optional
+	StepsSkipped []string `json:"stepsSkipped,omitempty"`
+
+	// RetriesStatus records each failed attempt of a build that has been
+	// retried, oldest first.
+	// +optional
+	RetriesStatus []AttemptStatus `json:"retriesStatus,omitempty"`
+
+	// Results lists the results reported by the build's steps, such as the
+	// digests of the images they pushed.
+	// +optional
+	Results []BuildResult `json:"results,omitempty"`
+
+	// SourcesStatus records what was fetched for each of the build's Git
+	// and image sources, matched to them by name.
+	// +optional
+	SourcesStatus []SourceStatus `json:"sourcesStatus,omitempty"`
+
+	// Caches records the claim backing each of the build's caches.
+	// +optional
+	Caches []CacheStatus `json:"caches,omitempty"`
+
+	// Artifacts records the files that the build uploaded.
+	// +optional
+	Artifacts []ArtifactStatus `json:"artifacts,omitempty"`
+
+	// ArtifactsOmitted is the number of uploaded files that are too many to
+	// be recorded in Artifacts.
+	// +optional
+	ArtifactsOmitted int32 `json:"artifactsOmitted,omitempty"`
+
+	// NotificationFailures records the most recent CloudEvents about the
+	// build that couldn't be delivered to its sink, oldest first.
+	// +optional
+	NotificationFailures []NotificationFailure `json:"notificationFailures,omitempty"`
+}
+
+// NotificationFailure records a CloudEvent about a build that couldn't be
+// delivered.
+type NotificationFailure struct {
+	// EventType is the type of the CloudEvent.
+	EventType string `json:"eventType"`
+
+	// Sink is the URI to which the CloudEvent was sent.
+	Sink string `json:"sink"`
+
+	// Time is when delivery was given up.
+	Time metav1.Time `json:"time"`
+
+	// Message describes the last error delivering the CloudEvent.
+	Message string `json:"message"`
+}
+
+// ArtifactStatus records a file that a build uploaded.
+type ArtifactStatus struct {
+	// Path is the path of the file relative to the workspace.
+	Path string `json:"path"`
+
+	// URI is where the file was uploaded, such as s3://bucket/key, or
+	// pvc://claim/path for PersistentVolumeClaims.
+	URI string `json:"uri"`
+
+	// SHA256 is the hex-encoded SHA-256 checksum of the file.
+	SHA256 string `json:"sha256"`
+}
+
+// CacheStatus records the claim that backs one of a build's caches.
+type CacheStatus struct {
+	// Name is the name of the cache.
+	Name string `json:"name"`
+
+	// ClaimName is the name of the PersistentVolumeClaim holding the cache.
+	ClaimName string `json:"claimName"`
+
+	// ReadOnly is true if another build was writing to the cache when this
+	// build started, so the cache is mounted read-only.
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// SourceStatus records what was fetched for one of a build's sources.
+type SourceStatus struct {
+	// Name is the name of the source, or empty if the source is unnamed.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Commit is the SHA of the Git commit that the source's revision
+	// resolved to.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// Digest is the digest of the image that the source's reference
+	// resolved to.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// Signer is the identity that signed the source's revision, when its
+	// signature was verified: the user ID of a GPG key, or the principal
+	// of an SSH key.
+	// +optional
+	Signer string `json:"signer,omitempty"`
+
+	// SigningKey is the fingerprint of the key that signed the source's
+	// revision, when its signature was verified.
+	// +optional
+	SigningKey string `json:"signingKey,omitempty"`
+}
+
+// BuildResult is a named value reported by one of a build's steps.
+type BuildResult struct {
+	// Name is the name of the result.
+	Name string `json:"name"`
+
+	// Value is the value of the result.
+	Value string `json:"value"`
 }
 
 // Check that BuildStatus may have its conditions managed.
@@ -279,6 +864,35 @@ type ClusterSpec struct {
 	PodName string `json:"podName"`
 }
 
+// AttemptStatus records the outcome of a failed attempt to execute a build.
+type AttemptStatus struct {
+	// PodName is the name of the pod that executed the attempt, if the
+	// builder is Cluster.
+	// +optional
+	PodName string `json:"podName,omitempty"`
+
+	// Operation is the name of the GCB API Operation that executed the
+	// attempt, if the builder is Google.
+	// +optional
+	Operation string `json:"operation,omitempty"`
+
+	// StartTime is the time the attempt started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the attempt failed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// StepStates describes the state of each build step container.
+	// +optional
+	StepStates []corev1.ContainerState `json:"stepStates,omitempty"`
+
+	// Message describes why the attempt failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // GoogleSpec provides information about the GCB build, if applicable.
 type GoogleSpec struct {
 	// Operation is the unique name of the GCB API Operation for the build.
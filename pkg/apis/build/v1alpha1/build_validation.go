@@ -18,8 +18,17 @@ package v1alpha1
 
 import (
 	"context"
+	"encoding/hex"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
 	"github.com/knative/pkg/apis"
 )
 
@@ -36,6 +45,47 @@ func (bs *BuildSpec) Validate(ctx context.Context) *apis.FieldError {
 	if bs.Template != nil && len(bs.Steps) > 0 {
 		return apis.ErrMultipleOneOf("template", "steps")
 	}
+	if err := bs.validateBuilder(); err != nil {
+		return err
+	}
+	if err := bs.validateRetries(); err != nil {
+		return err
+	}
+	if err := bs.validateCaches(); err != nil {
+		return err
+	}
+	if err := validateResults(bs.Results); err != nil {
+		return err
+	}
+	if err := bs.Artifacts.validate(); err != nil {
+		return err.ViaField("artifacts")
+	}
+	if bs.Notify != "" {
+		if u, err := url.Parse(bs.Notify); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apis.ErrInvalidValue(bs.Notify, "notify")
+		}
+	}
+	if bs.Template == nil && len(bs.StepTimeouts) > len(bs.Steps) {
+		return apis.ErrInvalidValue("more step timeouts than steps", "stepTimeouts")
+	}
+	if err := validateStepTimeouts(bs.StepTimeouts); err != nil {
+		return err
+	}
+	if err := validateSteps(bs.Sidecars); err != nil {
+		return err.ViaField("sidecars")
+	}
+	// A build that uses a template has no steps of its own, so its step
+	// policies are checked against the template's steps once it's applied.
+	if err := ValidateStepPolicies(bs.StepPolicies, bs.Steps); err != nil {
+		return err
+	}
+	// Builds with sidecars, step policies or artifacts that are always
+	// uploaded run their steps as regular containers.
+	if len(bs.Sidecars) > 0 || len(bs.StepPolicies) > 0 || (bs.Artifacts != nil && bs.Artifacts.Always) {
+		if err := validateStepCommands(bs.Steps); err != nil {
+			return err
+		}
+	}
 
 	// If a build specifies a template, all the template's parameters without
 	// defaults must be satisfied by the build's parameters.
@@ -63,11 +113,297 @@ func (b *TemplateInstantiationSpec) Validate(ctx context.Context) *apis.FieldErr
 		switch b.Kind {
 		case ClusterBuildTemplateKind,
 			BuildTemplateKind:
-			return nil
 		default:
 			return apis.ErrInvalidValue(string(b.Kind), "kind")
 		}
 	}
+	for _, a := range b.Arguments {
+		if a.Value != "" && len(a.Values) > 0 {
+			return apis.ErrMultipleOneOf("arguments.value", "arguments.values")
+		}
+		if a.ValueFrom == nil {
+			continue
+		}
+		if a.Value != "" || len(a.Values) > 0 {
+			return apis.ErrMultipleOneOf("arguments.value", "arguments.values", "arguments.valueFrom")
+		}
+		if err := a.ValueFrom.validate(); err != nil {
+			return err.ViaField("arguments.valueFrom")
+		}
+	}
+	return nil
+}
+
+func (vs *ArgumentValueSource) validate() *apis.FieldError {
+	var set []string
+	if ref := vs.ConfigMapKeyRef; ref != nil {
+		set = append(set, "configMapKeyRef")
+		if ref.Name == "" || ref.Key == "" {
+			return apis.ErrMissingField("configMapKeyRef.name", "configMapKeyRef.key")
+		}
+	}
+	if ref := vs.SecretKeyRef; ref != nil {
+		set = append(set, "secretKeyRef")
+		if ref.Name == "" || ref.Key == "" {
+			return apis.ErrMissingField("secretKeyRef.name", "secretKeyRef.key")
+		}
+	}
+	if ref := vs.FieldRef; ref != nil {
+		set = append(set, "fieldRef")
+		if _, err := (&Build{}).FieldValue(ref.FieldPath); err != nil {
+			return apis.ErrInvalidValue(ref.FieldPath, "fieldRef.fieldPath")
+		}
+	}
+	switch len(set) {
+	case 0:
+		return apis.ErrMissingOneOf("configMapKeyRef", "secretKeyRef", "fieldRef")
+	case 1:
+		return nil
+	default:
+		return apis.ErrMultipleOneOf(set...)
+	}
+}
+
+// maxTimeout is the longest that a build or any of its steps may run.
+const maxTimeout = 24 * time.Hour
+
+// Validate builder
+func (bs *BuildSpec) validateBuilder() *apis.FieldError {
+	switch bs.Builder {
+	case "", ClusterBuildProvider, GoogleBuildProvider:
+		return nil
+	default:
+		return apis.ErrInvalidValue(string(bs.Builder), "builder")
+	}
+}
+
+// Validate build caches
+func (bs *BuildSpec) validateCaches() *apis.FieldError {
+	names := sets.NewString()
+	paths := sets.NewString()
+	for _, c := range bs.Caches {
+		if errs := validation.IsDNS1123Label(c.Name); len(errs) > 0 {
+			return apis.ErrInvalidValue(c.Name, "caches.name")
+		}
+		if names.Has(c.Name) {
+			return apis.ErrMultipleOneOf("caches.name")
+		}
+		names.Insert(c.Name)
+
+		if !filepath.IsAbs(c.MountPath) {
+			return apis.ErrInvalidValue(c.MountPath, "caches.mountPath")
+		}
+		path := filepath.Clean(c.MountPath)
+		// The workspace and the builder's own directories are managed by
+		// the build.
+		if path == "/workspace" || path == "/builder" || strings.HasPrefix(path, "/builder/") {
+			return apis.ErrInvalidValue(c.MountPath, "caches.mountPath")
+		}
+		if paths.Has(path) {
+			return apis.ErrMultipleOneOf("caches.mountPath")
+		}
+		paths.Insert(path)
+
+		if c.Size != nil && c.Size.Sign() <= 0 {
+			return apis.ErrInvalidValue(c.Size.String(), "caches.size")
+		}
+	}
+	return nil
+}
+
+func (g *GitSourceSpec) validate() *apis.FieldError {
+	if g.Depth != nil && *g.Depth < 0 {
+		return apis.ErrInvalidValue(strconv.Itoa(int(*g.Depth)), "depth")
+	}
+	for i, p := range g.SparseCheckout {
+		if strings.TrimSpace(p) == "" {
+			return apis.ErrInvalidArrayValue(p, "sparseCheckout", i)
+		}
+	}
+	if g.Verify != nil {
+		if err := g.Verify.validate(); err != nil {
+			return err.ViaField("verify")
+		}
+	}
+	if g.CommitStatus != nil {
+		if err := g.CommitStatus.validate(g.Url); err != nil {
+			return err.ViaField("commitStatus")
+		}
+	}
+	return nil
+}
+
+func (c *CommitStatusSpec) validate(repoURL string) *apis.FieldError {
+	switch c.Provider {
+	case "", GitHubCommitStatus, GitLabCommitStatus:
+	default:
+		return apis.ErrInvalidValue(string(c.Provider), "provider")
+	}
+	if c.APIURL != "" {
+		u, err := url.Parse(c.APIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apis.ErrInvalidValue(c.APIURL, "apiURL")
+		}
+		// Statuses are posted with the Git credentials for the API's host,
+		// so the API must be that of the repository's host, lest the
+		// credentials be sent elsewhere. GitHub serves its API from
+		// api.github.com.
+		host := gitHost(repoURL)
+		if u.Hostname() != host && !(host == "github.com" && u.Hostname() == "api.github.com") {
+			return apis.ErrInvalidValue(c.APIURL, "apiURL")
+		}
+	}
+	if _, err := template.New("targetURL").Parse(c.TargetURL); err != nil {
+		return apis.ErrInvalidValue(c.TargetURL, "targetURL")
+	}
+	return nil
+}
+
+// gitHost returns the host name of a Git URL, which may also be an scp-like
+// address such as git@github.com:owner/repo.
+func gitHost(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if i := strings.Index(s, "@"); i >= 0 {
+		s = s[i+1:]
+	}
+	if i := strings.IndexAny(s, ":/"); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+func (v *GitVerifySpec) validate() *apis.FieldError {
+	switch v.Type {
+	case GitSignatureGPG, GitSignatureSSH:
+	case "":
+		return apis.ErrMissingField("type")
+	default:
+		return apis.ErrInvalidValue(string(v.Type), "type")
+	}
+	switch {
+	case v.TrustedKeysConfigMap == "" && v.TrustedKeysSecret == "":
+		return apis.ErrMissingOneOf("trustedKeysConfigMap", "trustedKeysSecret")
+	case v.TrustedKeysConfigMap != "" && v.TrustedKeysSecret != "":
+		return apis.ErrMultipleOneOf("trustedKeysConfigMap", "trustedKeysSecret")
+	}
+	return nil
+}
+
+func (h *HTTPSourceSpec) validate() *apis.FieldError {
+	if h.URL == "" {
+		return apis.ErrMissingField("url")
+	}
+	if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apis.ErrInvalidValue(h.URL, "url")
+	}
+	if h.SHA256 != "" {
+		if b, err := hex.DecodeString(h.SHA256); err != nil || len(b) != 32 {
+			return apis.ErrInvalidValue(h.SHA256, "sha256")
+		}
+	}
+	switch h.Format {
+	case "", HTTPTar, HTTPTarGz, HTTPZip, HTTPFile:
+	default:
+		return apis.ErrInvalidValue(string(h.Format), "format")
+	}
+	return nil
+}
+
+func (i *ImageSourceSpec) validate() *apis.FieldError {
+	if i.Reference == "" {
+		return apis.ErrMissingField("reference")
+	}
+	for _, elem := range strings.Split(i.Path, "/") {
+		if elem == ".." {
+			return apis.ErrInvalidValue(i.Path, "path")
+		}
+	}
+	return nil
+}
+
+func (s *S3SourceSpec) validate() *apis.FieldError {
+	if s.Bucket == "" {
+		return apis.ErrMissingField("bucket")
+	}
+	switch {
+	case s.Key == "" && s.Prefix == "":
+		return apis.ErrMissingOneOf("key", "prefix")
+	case s.Key != "" && s.Prefix != "":
+		return apis.ErrMultipleOneOf("key", "prefix")
+	}
+	switch s.Type {
+	case "":
+	case S3Archive, S3Manifest:
+		if s.Prefix != "" {
+			return apis.ErrDisallowedFields("type")
+		}
+	default:
+		return apis.ErrInvalidValue(string(s.Type), "type")
+	}
+	if s.Endpoint != "" {
+		if u, err := url.Parse(s.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apis.ErrInvalidValue(s.Endpoint, "endpoint")
+		}
+	}
+	return nil
+}
+
+func (a *ArtifactsSpec) validate() *apis.FieldError {
+	if a == nil {
+		return nil
+	}
+	if len(a.Paths) == 0 {
+		return apis.ErrMissingField("paths")
+	}
+	for i, p := range a.Paths {
+		if _, err := filepath.Match(p, ""); err != nil || !isWorkspacePath(p) {
+			return apis.ErrInvalidArrayValue(p, "paths", i)
+		}
+	}
+
+	d := a.Destination
+	switch {
+	case d.S3 == nil && d.PersistentVolumeClaim == nil:
+		return apis.ErrMissingOneOf("destination.s3", "destination.persistentVolumeClaim")
+	case d.S3 != nil && d.PersistentVolumeClaim != nil:
+		return apis.ErrMultipleOneOf("destination.s3", "destination.persistentVolumeClaim")
+	case d.S3 != nil:
+		if d.S3.Bucket == "" {
+			return apis.ErrMissingField("destination.s3.bucket")
+		}
+		if d.S3.Endpoint != "" {
+			if u, err := url.Parse(d.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return apis.ErrInvalidValue(d.S3.Endpoint, "destination.s3.endpoint")
+			}
+		}
+	default:
+		if d.PersistentVolumeClaim.ClaimName == "" {
+			return apis.ErrMissingField("destination.persistentVolumeClaim.claimName")
+		}
+		if p := d.PersistentVolumeClaim.Path; p != "" && !isWorkspacePath(p) {
+			return apis.ErrInvalidValue(p, "destination.persistentVolumeClaim.path")
+		}
+	}
+	return nil
+}
+
+// isWorkspacePath returns true if p is a non-empty relative path that
+// doesn't refer outside of its root.
+func isWorkspacePath(p string) bool {
+	p = filepath.Clean(p)
+	return p != "." && !filepath.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// Validate build retries
+func (bs *BuildSpec) validateRetries() *apis.FieldError {
+	if bs.Retries < 0 {
+		return apis.ErrInvalidValue(strconv.Itoa(bs.Retries), "retries")
+	}
+	if bs.RetryBackoff != nil && bs.RetryBackoff.Duration < 0 {
+		return apis.ErrInvalidValue(bs.RetryBackoff.Duration.String(), "retryBackoff")
+	}
 	return nil
 }
 
@@ -76,8 +412,6 @@ func (bs *BuildSpec) validateTimeout() *apis.FieldError {
 	if bs.Timeout == nil {
 		return nil
 	}
-	maxTimeout := time.Duration(24 * time.Hour)
-
 	if bs.Timeout.Duration > maxTimeout || bs.Timeout.Duration < 0 {
 		return apis.ErrOutOfBoundsValue(bs.Timeout.Duration.String(), "0", "24", "timeout")
 	}
@@ -97,6 +431,26 @@ func (bs BuildSpec) validateSources() *apis.FieldError {
 	if len(bs.Sources) > 0 && bs.Source != nil {
 		return apis.ErrMultipleOneOf("source", "sources")
 	}
+	if bs.Source != nil && bs.Source.Git != nil {
+		if err := bs.Source.Git.validate(); err != nil {
+			return err.ViaField("source.git")
+		}
+	}
+	if bs.Source != nil && bs.Source.HTTP != nil {
+		if err := bs.Source.HTTP.validate(); err != nil {
+			return err.ViaField("source.http")
+		}
+	}
+	if bs.Source != nil && bs.Source.Image != nil {
+		if err := bs.Source.Image.validate(); err != nil {
+			return err.ViaField("source.image")
+		}
+	}
+	if bs.Source != nil && bs.Source.S3 != nil {
+		if err := bs.Source.S3.validate(); err != nil {
+			return err.ViaField("source.s3")
+		}
+	}
 	for _, source := range bs.Sources {
 		// Check all source have unique names
 		if _, ok := names[source.Name]; ok {
@@ -110,6 +464,26 @@ func (bs BuildSpec) validateSources() *apis.FieldError {
 			subPathExists = true
 		}
 		names[source.Name] = ""
+		if source.Git != nil {
+			if err := source.Git.validate(); err != nil {
+				return err.ViaField("sources.git")
+			}
+		}
+		if source.HTTP != nil {
+			if err := source.HTTP.validate(); err != nil {
+				return err.ViaField("sources.http")
+			}
+		}
+		if source.Image != nil {
+			if err := source.Image.validate(); err != nil {
+				return err.ViaField("sources.image")
+			}
+		}
+		if source.S3 != nil {
+			if err := source.S3.validate(); err != nil {
+				return err.ViaField("sources.s3")
+			}
+		}
 
 		if source.TargetPath == "" {
 			if source.Custom != nil {
@@ -28,6 +28,7 @@ import (
 )
 
 func TestValidateBuild(t *testing.T) {
+	negativeDepth := int32(-1)
 	for _, c := range []struct {
 		name  string
 		build *Build
@@ -471,6 +472,856 @@ func TestValidateBuild(t *testing.T) {
 			},
 		},
 		want: apis.ErrMissingField("spec.template.name"),
+	}, {
+		name: "Unknown builder",
+		build: &Build{
+			Spec: BuildSpec{
+				Builder: "Banana",
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("Banana", "spec.builder"),
+	}, {
+		name: "Duplicate results",
+		build: &Build{
+			Spec: BuildSpec{
+				Results: []ResultSpec{{Name: "digest"}, {Name: "digest"}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMultipleOneOf("spec.results.name"),
+	}, {
+		name: "Negative retries",
+		build: &Build{
+			Spec: BuildSpec{
+				Retries: -1,
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("-1", "spec.retries"),
+	}, {
+		name: "Negative retry backoff",
+		build: &Build{
+			Spec: BuildSpec{
+				Retries:      1,
+				RetryBackoff: &metav1.Duration{Duration: -time.Second},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("-1s", "spec.retryBackoff"),
+	}, {
+		name: "HTTP source",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					HTTP: &HTTPSourceSpec{
+						URL:    "https://example.com/src.tar.gz",
+						SHA256: "6ca13d52ca70c883e0f0bb101e425a89e8624de51db2d2392593af6a84118090",
+						Format: HTTPTarGz,
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "HTTP source with unsupported scheme",
+		build: &Build{
+			Spec: BuildSpec{
+				Sources: []SourceSpec{{
+					Name: "src",
+					HTTP: &HTTPSourceSpec{
+						URL: "ftp://example.com/src.tar",
+					},
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("ftp://example.com/src.tar", "spec.sources.http.url"),
+	}, {
+		name: "HTTP source with invalid checksum",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					HTTP: &HTTPSourceSpec{
+						URL:    "https://example.com/src.tar",
+						SHA256: "abc",
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("abc", "spec.source.http.sha256"),
+	}, {
+		name: "HTTP source with unknown format",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					HTTP: &HTTPSourceSpec{
+						URL:    "https://example.com/src.rar",
+						Format: "rar",
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("rar", "spec.source.http.format"),
+	}, {
+		name: "Git source with negative depth",
+		build: &Build{
+			Spec: BuildSpec{
+				Sources: []SourceSpec{{
+					Name: "repo",
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "master",
+						Depth:    &negativeDepth,
+					},
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("-1", "spec.sources.git.depth"),
+	}, {
+		name: "Git source with empty sparse checkout path",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:            "https://github.com/my/repo",
+						Revision:       "master",
+						SparseCheckout: []string{"services/api/", " "},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidArrayValue(" ", "spec.source.git.sparseCheckout", 1),
+	}, {
+		name: "Git source verified against a ConfigMap",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "v1.0.0",
+						Verify: &GitVerifySpec{
+							Type:                 GitSignatureSSH,
+							TrustedKeysConfigMap: "allowed-signers",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "Git source verified without a signature type",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "v1.0.0",
+						Verify: &GitVerifySpec{
+							TrustedKeysSecret: "release-keys",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMissingField("spec.source.git.verify.type"),
+	}, {
+		name: "Git source verified against a ConfigMap and a Secret",
+		build: &Build{
+			Spec: BuildSpec{
+				Sources: []SourceSpec{{
+					Name: "app",
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "v1.0.0",
+						Verify: &GitVerifySpec{
+							Type:                 GitSignatureGPG,
+							TrustedKeysConfigMap: "release-keys",
+							TrustedKeysSecret:    "release-keys",
+						},
+					},
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMultipleOneOf("spec.sources.git.verify.trustedKeysConfigMap", "spec.sources.git.verify.trustedKeysSecret"),
+	}, {
+		name: "Git source with commit status",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://gitlab.example.com/my/repo",
+						Revision: "master",
+						CommitStatus: &CommitStatusSpec{
+							Provider:  GitLabCommitStatus,
+							APIURL:    "https://gitlab.example.com/api/v4",
+							TargetURL: "https://ci.example.com/{{.Namespace}}/{{.Name}}",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "Commit status with an unknown provider",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "master",
+						CommitStatus: &CommitStatusSpec{
+							Provider: "Gitea",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("Gitea", "spec.source.git.commitStatus.provider"),
+	}, {
+		name: "Commit status with a relative API URL",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "master",
+						CommitStatus: &CommitStatusSpec{
+							APIURL: "api/v3",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("api/v3", "spec.source.git.commitStatus.apiURL"),
+	}, {
+		name: "Commit status to GitHub's API",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "git@github.com:my/repo",
+						Revision: "master",
+						CommitStatus: &CommitStatusSpec{
+							APIURL: "https://api.github.com",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "Commit status to an API on another host",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "master",
+						CommitStatus: &CommitStatusSpec{
+							APIURL: "https://evil.example.com/api/v3",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("https://evil.example.com/api/v3", "spec.source.git.commitStatus.apiURL"),
+	}, {
+		name: "Commit status with a malformed target URL template",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Git: &GitSourceSpec{
+						Url:      "https://github.com/my/repo",
+						Revision: "master",
+						CommitStatus: &CommitStatusSpec{
+							TargetURL: "https://ci.example.com/{{.Name",
+						},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("https://ci.example.com/{{.Name", "spec.source.git.commitStatus.targetURL"),
+	}, {
+		name: "Image source",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					Image: &ImageSourceSpec{
+						Reference: "gcr.io/foo/bundle:latest",
+						Path:      "/app",
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "Image source without reference",
+		build: &Build{
+			Spec: BuildSpec{
+				Sources: []SourceSpec{{
+					Name:  "bundle",
+					Image: &ImageSourceSpec{},
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMissingField("spec.sources.image.reference"),
+	}, {
+		name: "S3 source",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					S3: &S3SourceSpec{
+						Type:     S3Manifest,
+						Bucket:   "src",
+						Key:      "manifest.json",
+						Endpoint: "https://minio.example.com:9000",
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "S3 source without key or prefix",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					S3: &S3SourceSpec{Bucket: "src"},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMissingOneOf("spec.source.s3.key", "spec.source.s3.prefix"),
+	}, {
+		name: "S3 source with type and prefix",
+		build: &Build{
+			Spec: BuildSpec{
+				Sources: []SourceSpec{{
+					Name: "assets",
+					S3: &S3SourceSpec{
+						Type:   S3Archive,
+						Bucket: "src",
+						Prefix: "images/",
+					},
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrDisallowedFields("spec.sources.s3.type"),
+	}, {
+		name: "S3 source with invalid endpoint",
+		build: &Build{
+			Spec: BuildSpec{
+				Source: &SourceSpec{
+					S3: &S3SourceSpec{
+						Bucket:   "src",
+						Key:      "app.tar.gz",
+						Endpoint: "minio.example.com",
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("minio.example.com", "spec.source.s3.endpoint"),
+	}, {
+		name: "Caches",
+		build: &Build{
+			Spec: BuildSpec{
+				Caches: []CacheSpec{{
+					Name:      "go-mod",
+					MountPath: "/go/pkg/mod",
+					Key:       "abc123",
+				}, {
+					Name:      "npm",
+					MountPath: "/root/.npm",
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+	}, {
+		name: "Invalid cache name",
+		build: &Build{
+			Spec: BuildSpec{
+				Caches: []CacheSpec{{
+					Name:      "Go_Mod",
+					MountPath: "/go/pkg/mod",
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("Go_Mod", "spec.caches.name"),
+	}, {
+		name: "Duplicate cache names",
+		build: &Build{
+			Spec: BuildSpec{
+				Caches: []CacheSpec{{
+					Name:      "deps",
+					MountPath: "/go/pkg/mod",
+				}, {
+					Name:      "deps",
+					MountPath: "/root/.npm",
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMultipleOneOf("spec.caches.name"),
+	}, {
+		name: "Cache mounted over workspace",
+		build: &Build{
+			Spec: BuildSpec{
+				Caches: []CacheSpec{{
+					Name:      "deps",
+					MountPath: "/workspace/",
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("/workspace/", "spec.caches.mountPath"),
+	}, {
+		name: "Relative cache mount path",
+		build: &Build{
+			Spec: BuildSpec{
+				Caches: []CacheSpec{{
+					Name:      "deps",
+					MountPath: "deps",
+				}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("deps", "spec.caches.mountPath"),
+	}, {
+		name: "Artifacts uploaded to S3",
+		build: &Build{
+			Spec: BuildSpec{
+				Artifacts: &ArtifactsSpec{
+					Paths: []string{"bin/*", "report.xml"},
+					Destination: ArtifactDestination{
+						S3: &S3ArtifactDestination{Bucket: "builds", Endpoint: "https://storage.googleapis.com"},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: nil,
+	}, {
+		name: "Artifacts without destination",
+		build: &Build{
+			Spec: BuildSpec{
+				Artifacts: &ArtifactsSpec{
+					Paths: []string{"bin/*"},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrMissingOneOf("spec.artifacts.destination.s3", "spec.artifacts.destination.persistentVolumeClaim"),
+	}, {
+		name: "Artifact path outside of workspace",
+		build: &Build{
+			Spec: BuildSpec{
+				Artifacts: &ArtifactsSpec{
+					Paths: []string{"bin/*", "../etc/passwd"},
+					Destination: ArtifactDestination{
+						S3: &S3ArtifactDestination{Bucket: "builds"},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidArrayValue("../etc/passwd", "spec.artifacts.paths", 1),
+	}, {
+		name: "Malformed artifact glob",
+		build: &Build{
+			Spec: BuildSpec{
+				Artifacts: &ArtifactsSpec{
+					Paths: []string{"bin/[a"},
+					Destination: ArtifactDestination{
+						S3: &S3ArtifactDestination{Bucket: "builds"},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidArrayValue("bin/[a", "spec.artifacts.paths", 0),
+	}, {
+		name: "Absolute artifact claim path",
+		build: &Build{
+			Spec: BuildSpec{
+				Artifacts: &ArtifactsSpec{
+					Paths: []string{"bin"},
+					Destination: ArtifactDestination{
+						PersistentVolumeClaim: &PVCArtifactDestination{ClaimName: "artifacts", Path: "/builds"},
+					},
+				},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("/builds", "spec.artifacts.destination.persistentVolumeClaim.path"),
+	}, {
+		name: "Notify sink",
+		build: &Build{
+			Spec: BuildSpec{
+				Notify: "http://deployer.default.svc.cluster.local/builds",
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: nil,
+	}, {
+		name: "Relative notify sink",
+		build: &Build{
+			Spec: BuildSpec{
+				Notify: "/builds",
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("/builds", "spec.notify"),
+	}, {
+		name: "Step timeout greater than maximum",
+		build: &Build{
+			Spec: BuildSpec{
+				StepTimeouts: []metav1.Duration{{Duration: 48 * time.Hour}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrOutOfBoundsValue("48h0m0s", "0", "24", "spec.stepTimeouts"),
+	}, {
+		name: "More step timeouts than steps",
+		build: &Build{
+			Spec: BuildSpec{
+				StepTimeouts: []metav1.Duration{{Duration: time.Minute}, {Duration: time.Minute}},
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("more step timeouts than steps", "spec.stepTimeouts"),
+	}, {
+		name: "Sidecar without image",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:  "foo",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				Sidecars: []corev1.Container{{
+					Name: "docker",
+				}},
+			},
+		},
+		want: apis.ErrMissingField("spec.sidecars.Image"),
+	}, {
+		name: "Step policy for missing step",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:  "build",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}, {
+					Name:  "notify",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				StepPolicies: []StepPolicy{{
+					Step:      "cleanup",
+					RunPolicy: RunAlways,
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("cleanup", "spec.stepPolicies.step"),
+	}, {
+		name: "Step policy with unknown run policy",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:  "build",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}, {
+					Name:  "notify",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				StepPolicies: []StepPolicy{{
+					Step:      "notify",
+					RunPolicy: "Sometimes",
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("Sometimes", "spec.stepPolicies.runPolicy"),
+	}, {
+		name: "Step policy conditional on later step",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:  "build",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}, {
+					Name:  "notify",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				StepPolicies: []StepPolicy{{
+					Step: "build",
+					When: &StepCondition{Step: "notify", Outcome: StepFailed},
+				}},
+			},
+		},
+		want: apis.ErrInvalidValue("notify", "spec.stepPolicies.when.step"),
+	}, {
+		name: "Step policy with run policy and condition",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:  "build",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}, {
+					Name:  "notify",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				StepPolicies: []StepPolicy{{
+					Step:      "notify",
+					RunPolicy: RunAlways,
+					When:      &StepCondition{Step: "build", Outcome: StepFailed},
+				}},
+			},
+		},
+		want: apis.ErrMultipleOneOf("spec.stepPolicies.runPolicy", "spec.stepPolicies.when"),
+	}, {
+		name: "Argument with value and values",
+		build: &Build{
+			Spec: BuildSpec{
+				Template: &TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []ArgumentSpec{{
+						Name:   "tags",
+						Value:  "latest",
+						Values: []string{"v1"},
+					}},
+				},
+			},
+		},
+		want: apis.ErrMultipleOneOf("spec.template.arguments.value", "spec.template.arguments.values"),
+	}, {
+		name: "Argument with value and valueFrom",
+		build: &Build{
+			Spec: BuildSpec{
+				Template: &TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []ArgumentSpec{{
+						Name:  "rev",
+						Value: "master",
+						ValueFrom: &ArgumentValueSource{
+							FieldRef: &BuildFieldSelector{FieldPath: "spec.source.git.revision"},
+						},
+					}},
+				},
+			},
+		},
+		want: apis.ErrMultipleOneOf("spec.template.arguments.value", "spec.template.arguments.values", "spec.template.arguments.valueFrom"),
+	}, {
+		name: "Argument valueFrom unsupported field",
+		build: &Build{
+			Spec: BuildSpec{
+				Template: &TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []ArgumentSpec{{
+						Name: "rev",
+						ValueFrom: &ArgumentValueSource{
+							FieldRef: &BuildFieldSelector{FieldPath: "spec.steps"},
+						},
+					}},
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("spec.steps", "spec.template.arguments.valueFrom.fieldRef.fieldPath"),
+	}, {
+		name: "Argument valueFrom without source",
+		build: &Build{
+			Spec: BuildSpec{
+				Template: &TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []ArgumentSpec{{
+						Name:      "rev",
+						ValueFrom: &ArgumentValueSource{},
+					}},
+				},
+			},
+		},
+		want: apis.ErrMissingOneOf("spec.template.arguments.valueFrom.configMapKeyRef", "spec.template.arguments.valueFrom.secretKeyRef", "spec.template.arguments.valueFrom.fieldRef"),
+	}, {
+		name: "Valid step policy",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:    "build",
+					Image:   "gcr.io/foo-bar/baz:latest",
+					Command: []string{"make"},
+				}, {
+					Name:    "notify",
+					Image:   "gcr.io/foo-bar/baz:latest",
+					Command: []string{"notify"},
+				}},
+				StepPolicies: []StepPolicy{{
+					Step: "notify",
+					When: &StepCondition{Step: "build", Outcome: StepFailed},
+				}},
+			},
+		},
+	}, {
+		name: "Step policy of step without command",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:    "build",
+					Image:   "gcr.io/foo-bar/baz:latest",
+					Command: []string{"make"},
+				}, {
+					Name:  "notify",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				StepPolicies: []StepPolicy{{
+					Step:      "notify",
+					RunPolicy: RunAlways,
+				}},
+			},
+		},
+		want: apis.ErrMissingField("spec.steps[1].command"),
+	}, {
+		name: "Sidecars with step without command",
+		build: &Build{
+			Spec: BuildSpec{
+				Steps: []corev1.Container{{
+					Name:  "test",
+					Image: "gcr.io/foo-bar/baz:latest",
+				}},
+				Sidecars: []corev1.Container{{
+					Name:  "db",
+					Image: "postgres",
+				}},
+			},
+		},
+		want: apis.ErrMissingField("spec.steps[0].command"),
 	}} {
 		name := c.name
 		t.Run(name, func(t *testing.T) {
@@ -24,5 +24,18 @@ import (
 
 // Validate ClusterBuildTemplate
 func (b *ClusterBuildTemplate) Validate(ctx context.Context) *apis.FieldError {
-	return validateObjectMetadata(b.GetObjectMeta()).ViaField("metadata").Also(b.Spec.Validate(ctx).ViaField("spec"))
+	return validateObjectMetadata(b.GetObjectMeta()).ViaField("metadata").
+		Also(b.Spec.Validate(ctx).ViaField("spec")).
+		Also(validateClusterBases(b.Spec.Bases).ViaField("spec"))
+}
+
+// validateClusterBases checks that a ClusterBuildTemplate only extends
+// ClusterBuildTemplates, since BuildTemplates are namespaced.
+func validateClusterBases(bases []BaseTemplate) *apis.FieldError {
+	for _, b := range bases {
+		if b.Kind != ClusterBuildTemplateKind {
+			return apis.ErrInvalidValue(string(b.Kind), "bases.kind")
+		}
+	}
+	return nil
 }
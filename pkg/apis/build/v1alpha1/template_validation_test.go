@@ -3,13 +3,17 @@ package v1alpha1
 import (
 	"context"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Validate both cluster build template and build template
 func TestValidateClusterBuildTemplate(t *testing.T) {
 	hasDefault := "has-default"
+	three := "3"
+	notAnInt := "three"
 	for _, c := range []struct {
 		desc   string
 		tmpl   BuildTemplateSpec
@@ -85,6 +89,192 @@ func TestValidateClusterBuildTemplate(t *testing.T) {
 			}},
 		},
 		reason: "NestedPlaceholder",
+	}, {
+		desc: "Step timeouts",
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}, {
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			StepTimeouts: []metav1.Duration{{}, {Duration: time.Minute}},
+		},
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			StepTimeouts: []metav1.Duration{{Duration: time.Minute}, {Duration: time.Minute}},
+		},
+		reason: "TooManyStepTimeouts",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			StepTimeouts: []metav1.Duration{{Duration: -time.Minute}},
+		},
+		reason: "NegativeStepTimeout",
+	}, {
+		desc: "Results",
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Results: []ResultSpec{{
+				Name:        "IMAGE_DIGEST",
+				Description: "The digest of the pushed image",
+			}, {
+				Name: "commit.sha",
+			}},
+		},
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Results: []ResultSpec{{Name: "digest"}, {Name: "digest"}},
+		},
+		reason: "DuplicateResultName",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Results: []ResultSpec{{Name: "image digest"}},
+		},
+		reason: "InvalidResultName",
+	}, {
+		desc: "Typed parameters",
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Parameters: []ParameterSpec{{
+				Name:    "replicas",
+				Type:    ParameterTypeInt,
+				Default: &three,
+			}, {
+				Name: "verbose",
+				Type: ParameterTypeBool,
+			}, {
+				Name:          "tags",
+				Type:          ParameterTypeArray,
+				Enum:          []string{"latest", "stable"},
+				DefaultValues: []string{"latest"},
+			}},
+		},
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Parameters: []ParameterSpec{{
+				Name: "foo",
+				Type: "float",
+			}},
+		},
+		reason: "UnknownParameterType",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Parameters: []ParameterSpec{{
+				Name:    "foo",
+				Type:    ParameterTypeInt,
+				Default: &notAnInt,
+			}},
+		},
+		reason: "DefaultNotAnInt",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Parameters: []ParameterSpec{{
+				Name:    "foo",
+				Pattern: "[a-z",
+			}},
+		},
+		reason: "InvalidPattern",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Parameters: []ParameterSpec{{
+				Name: "foo",
+				Type: ParameterTypeBool,
+				Enum: []string{"true", "maybe"},
+			}},
+		},
+		reason: "EnumValueNotABool",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			Parameters: []ParameterSpec{{
+				Name:    "foo",
+				Type:    ParameterTypeArray,
+				Default: &three,
+			}},
+		},
+		reason: "ArrayWithDefault",
+	}, {
+		desc: "Bases",
+		tmpl: BuildTemplateSpec{
+			Bases: []BaseTemplate{{
+				Name:     "setup",
+				Kind:     ClusterBuildTemplateKind,
+				Position: BaseAfter,
+			}},
+			Steps: []corev1.Container{{
+				Image:   "gcr.io/foo-bar/baz:latest",
+				Command: []string{"build"},
+			}},
+			StepPolicies: []StepPolicy{{
+				Step:      "from-base",
+				RunPolicy: RunAlways,
+			}},
+		},
+	}, {
+		tmpl: BuildTemplateSpec{
+			Bases: []BaseTemplate{{
+				Kind: ClusterBuildTemplateKind,
+			}},
+		},
+		reason: "MissingBaseName",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Bases: []BaseTemplate{{
+				Name: "setup",
+				Kind: "Build",
+			}},
+		},
+		reason: "InvalidBaseKind",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Bases: []BaseTemplate{{
+				Name:     "setup",
+				Kind:     ClusterBuildTemplateKind,
+				Position: "During",
+			}},
+		},
+		reason: "InvalidBasePosition",
+	}, {
+		tmpl: BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Name:  "build",
+				Image: "gcr.io/foo-bar/baz:latest",
+			}},
+			StepPolicies: []StepPolicy{{
+				Step:      "build",
+				RunPolicy: RunAlways,
+			}},
+		},
+		reason: "StepWithoutCommand",
 	}} {
 		name := c.desc
 		if c.reason != "" {
@@ -109,3 +299,15 @@ func TestValidateClusterBuildTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateClusterBuildTemplateBases(t *testing.T) {
+	spec := BuildTemplateSpec{
+		Bases: []BaseTemplate{{Name: "setup"}},
+	}
+	if err := (&BuildTemplate{Spec: spec}).Validate(context.Background()); err != nil {
+		t.Errorf("BuildTemplate extending a BuildTemplate: got %v, want success", err)
+	}
+	if err := (&ClusterBuildTemplate{Spec: spec}).Validate(context.Background()); err == nil {
+		t.Error("ClusterBuildTemplate extending a BuildTemplate: got success, want error")
+	}
+}
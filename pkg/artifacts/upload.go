@@ -0,0 +1,272 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package artifacts uploads the files that a build declares as its
+// artifacts once its steps have finished.
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/knative/build/pkg/credentials/s3creds"
+	"github.com/knative/build/pkg/s3fetch"
+)
+
+// Artifact is a file that was uploaded.
+type Artifact struct {
+	// Path is the path of the file relative to the workspace.
+	Path string `json:"path"`
+	// URI is where the file was uploaded.
+	URI string `json:"uri"`
+	// SHA256 is the hex-encoded SHA-256 checksum of the file.
+	SHA256 string `json:"sha256"`
+}
+
+// Destination is where artifacts are uploaded.
+type Destination interface {
+	// Put uploads the file, whose path relative to the workspace is rel,
+	// and returns the URI of the upload.
+	Put(rel string, f *os.File, size int64, sha256Sum string) (string, error)
+}
+
+// Match returns the paths, relative to the workspace, of the files that the
+// globs match, in order. Directories that match contribute all of their
+// files.
+func Match(workspace string, globs []string) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	add := func(p string) error {
+		rel, err := filepath.Rel(workspace, p)
+		if err != nil {
+			return err
+		}
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%q is outside of the workspace", p)
+		}
+		if !seen[rel] {
+			seen[rel] = true
+			paths = append(paths, rel)
+		}
+		return nil
+	}
+
+	for _, g := range globs {
+		matches, err := filepath.Glob(filepath.Join(workspace, g))
+		if err != nil {
+			return nil, fmt.Errorf("glob %q: %v", g, err)
+		}
+		for _, m := range matches {
+			if err := filepath.Walk(m, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				// Only regular files are uploaded.
+				if !info.Mode().IsRegular() {
+					return nil
+				}
+				return add(p)
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Upload uploads the files that the globs match in the workspace to the
+// destination.
+func Upload(workspace string, globs []string, dest Destination) ([]Artifact, error) {
+	paths, err := Match(workspace, globs)
+	if err != nil {
+		return nil, err
+	}
+	var uploaded []Artifact
+	for _, rel := range paths {
+		a, err := upload(workspace, rel, dest)
+		if err != nil {
+			return uploaded, fmt.Errorf("uploading %s: %v", rel, err)
+		}
+		uploaded = append(uploaded, *a)
+	}
+	return uploaded, nil
+}
+
+func upload(workspace, rel string, dest Destination) (*Artifact, error) {
+	f, err := os.Open(filepath.Join(workspace, rel))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	uri, err := dest.Put(filepath.ToSlash(rel), f, size, sum)
+	if err != nil {
+		return nil, err
+	}
+	return &Artifact{Path: rel, URI: uri, SHA256: sum}, nil
+}
+
+// S3 uploads artifacts to a bucket of S3-compatible object storage, at the
+// keys formed by prefixing their paths with Prefix.
+type S3 struct {
+	// Endpoint is the URL of the service. Defaults to Amazon S3 in Region.
+	Endpoint string
+	Region   string
+	Bucket   string
+	Prefix   string
+	// Credentials, if set, are used to sign requests; otherwise they are
+	// sent anonymously.
+	Credentials *s3creds.Credentials
+	Client      *http.Client
+}
+
+var _ Destination = (*S3)(nil)
+
+// s3Error is the body of an error response.
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// Put implements Destination.
+func (s *S3) Put(rel string, f *os.File, size int64, sha256Sum string) (string, error) {
+	key := s.Prefix + rel
+	if s.Prefix != "" && !strings.HasSuffix(s.Prefix, "/") {
+		key = s.Prefix + "/" + rel
+	}
+	region := s.Region
+	if region == "" {
+		region = s3fetch.DefaultRegion
+	}
+	u, err := s3fetch.ObjectURL(s.Endpoint, region, s.Bucket, key)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPut, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	// The checksum is signed in place of the body, which is then streamed.
+	req.Header.Set("X-Amz-Content-Sha256", sha256Sum)
+	if err := s3fetch.Sign(req, nil, region, s.Credentials); err != nil {
+		return "", err
+	}
+	req.Body, req.ContentLength = ioutil.NopCloser(f), size
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		e := s3Error{}
+		if err := xml.NewDecoder(resp.Body).Decode(&e); err != nil || e.Code == "" {
+			return "", fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return "", fmt.Errorf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, key), nil
+}
+
+// Volume copies artifacts to a directory of a PersistentVolumeClaim, which
+// is mounted at Dir.
+type Volume struct {
+	// Dir is where the claim's directory is mounted.
+	Dir string
+	// ClaimName and Path name the directory in the URIs of the artifacts.
+	ClaimName string
+	Path      string
+}
+
+var _ Destination = (*Volume)(nil)
+
+// Put implements Destination.
+func (v *Volume) Put(rel string, f *os.File, size int64, sha256Sum string) (string, error) {
+	p := filepath.Join(v.Dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	out, err := os.Create(p)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, f); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pvc://%s/%s", v.ClaimName, path.Join(v.Path, rel)), nil
+}
+
+// status is the termination message of the uploader.
+type status struct {
+	Artifacts []Artifact `json:"artifacts"`
+	Omitted   int        `json:"omitted,omitempty"`
+}
+
+// FormatStatus formats the uploaded artifacts as JSON, keeping at most max
+// bytes; the artifacts that don't fit are left out and counted, and their
+// number is returned.
+func FormatStatus(uploaded []Artifact, max int) (string, int) {
+	var formatted string
+	omitted := len(uploaded)
+	for n := 0; n <= len(uploaded); n++ {
+		b, err := json.Marshal(status{Artifacts: uploaded[:n], Omitted: len(uploaded) - n})
+		if err != nil || len(b) > max {
+			break
+		}
+		formatted, omitted = string(b), len(uploaded)-n
+	}
+	return formatted, omitted
+}
+
+// ParseStatus parses the artifacts formatted by FormatStatus, and the number
+// of artifacts that were left out. Malformed statuses have no artifacts.
+func ParseStatus(formatted string) ([]Artifact, int) {
+	var s status
+	if err := json.Unmarshal([]byte(formatted), &s); err != nil {
+		return nil, 0
+	}
+	return s.Artifacts, s.Omitted
+}
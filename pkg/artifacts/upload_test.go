@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/knative/build/pkg/credentials/s3creds"
+	"github.com/knative/build/pkg/s3fetch"
+)
+
+var testCredentials = &s3creds.Credentials{
+	AccessKeyID:     "minio",
+	SecretAccessKey: "minio123",
+}
+
+// testS3 stores objects like an S3-compatible service that requires signed
+// requests.
+type testS3 struct {
+	t *testing.T
+	// objects are keyed by bucket/key.
+	objects map[string]string
+}
+
+func (s *testS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.t.Fatalf("ReadAll() = %v", err)
+	}
+	sum := sha256.Sum256(body)
+	if r.Method != http.MethodPut || !s.signed(r) || r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`)
+		return
+	}
+	s.objects[strings.TrimPrefix(r.URL.Path, "/")] = string(body)
+}
+
+// signed returns true if the request was signed with testCredentials.
+func (s *testS3) signed(r *http.Request) bool {
+	signTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		s.t.Fatalf("NewRequest() = %v", err)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", r.Header.Get("X-Amz-Content-Sha256"))
+	signer := v4.NewSigner(credentials.NewStaticCredentials(testCredentials.AccessKeyID, testCredentials.SecretAccessKey, ""),
+		func(s *v4.Signer) { s.DisableURIPathEscaping = true })
+	if _, err := signer.Sign(req, nil, "s3", s3fetch.DefaultRegion, signTime); err != nil {
+		s.t.Fatalf("Sign() = %v", err)
+	}
+	return req.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func workspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("MkdirAll() = %v", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() = %v", err)
+		}
+	}
+	return dir
+}
+
+func TestMatch(t *testing.T) {
+	dir := workspace(t, map[string]string{
+		"bin/app":             "app",
+		"bin/tool":            "tool",
+		"reports/unit.xml":    "unit",
+		"reports/e2e/e2e.xml": "e2e",
+		"src/main.go":         "package main",
+		"coverage/cover.out":  "cover",
+		"coverage/cover.html": "html",
+		"README.md":           "readme",
+	})
+	defer os.RemoveAll(dir)
+
+	got, err := Match(dir, []string{"bin/*", "reports", "coverage/*.out", "bin/app", "missing/*"})
+	if err != nil {
+		t.Fatalf("Match() = %v", err)
+	}
+	want := []string{"bin/app", "bin/tool", "coverage/cover.out", "reports/e2e/e2e.xml", "reports/unit.xml"}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Match() diff -want, +got: %v", d)
+	}
+
+	if _, err := Match(dir, []string{"bin/[a"}); err == nil {
+		t.Error("Match() with a malformed glob succeeded, want error")
+	}
+}
+
+func TestUploadS3(t *testing.T) {
+	dir := workspace(t, map[string]string{
+		"bin/app":        "app",
+		"out/a b+c.txt":  "escaped",
+		"out/ignore.log": "log",
+	})
+	defer os.RemoveAll(dir)
+	s3 := &testS3{t: t, objects: map[string]string{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	got, err := Upload(dir, []string{"bin/*", "out/*.txt"}, &S3{
+		Endpoint:    server.URL,
+		Bucket:      "builds",
+		Prefix:      "my-build",
+		Credentials: testCredentials,
+	})
+	if err != nil {
+		t.Fatalf("Upload() = %v", err)
+	}
+	want := []Artifact{
+		{Path: "bin/app", URI: "s3://builds/my-build/bin/app", SHA256: sha256Hex("app")},
+		{Path: "out/a b+c.txt", URI: "s3://builds/my-build/out/a b+c.txt", SHA256: sha256Hex("escaped")},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Upload() diff -want, +got: %v", d)
+	}
+	if d := cmp.Diff(map[string]string{
+		"builds/my-build/bin/app":       "app",
+		"builds/my-build/out/a b+c.txt": "escaped",
+	}, s3.objects); d != "" {
+		t.Errorf("Uploaded objects diff -want, +got: %v", d)
+	}
+
+	// Anonymous uploads are refused.
+	if _, err := Upload(dir, []string{"bin/*"}, &S3{Endpoint: server.URL, Bucket: "builds"}); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Upload() without credentials = %v, want AccessDenied", err)
+	}
+}
+
+func TestUploadVolume(t *testing.T) {
+	dir := workspace(t, map[string]string{
+		"reports/unit.xml":    "unit",
+		"reports/e2e/e2e.xml": "e2e",
+	})
+	defer os.RemoveAll(dir)
+	dest, err := ioutil.TempDir("", "claim")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dest)
+
+	got, err := Upload(dir, []string{"reports"}, &Volume{Dir: dest, ClaimName: "test-reports", Path: "build-1"})
+	if err != nil {
+		t.Fatalf("Upload() = %v", err)
+	}
+	want := []Artifact{
+		{Path: "reports/e2e/e2e.xml", URI: "pvc://test-reports/build-1/reports/e2e/e2e.xml", SHA256: sha256Hex("e2e")},
+		{Path: "reports/unit.xml", URI: "pvc://test-reports/build-1/reports/unit.xml", SHA256: sha256Hex("unit")},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Upload() diff -want, +got: %v", d)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dest, "reports", "e2e", "e2e.xml"))
+	if err != nil || string(b) != "e2e" {
+		t.Errorf("Copied file = %q, %v, want %q", b, err, "e2e")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	uploaded := []Artifact{
+		{Path: "bin/app", URI: "s3://builds/bin/app", SHA256: sha256Hex("app")},
+		{Path: "bin/tool", URI: "s3://builds/bin/tool", SHA256: sha256Hex("tool")},
+		{Path: "bin/other", URI: "s3://builds/bin/other", SHA256: sha256Hex("other")},
+	}
+	status, omitted := FormatStatus(uploaded, 350)
+	if omitted != 1 {
+		t.Errorf("FormatStatus() omitted %d artifacts, want 1", omitted)
+	}
+	if len(status) > 350 {
+		t.Errorf("FormatStatus() = %d bytes, want at most 350", len(status))
+	}
+	got, gotOmitted := ParseStatus(status)
+	if d := cmp.Diff(uploaded[:2], got); d != "" {
+		t.Errorf("ParseStatus() diff -want, +got: %v", d)
+	}
+	if gotOmitted != 1 {
+		t.Errorf("ParseStatus() omitted %d artifacts, want 1", gotOmitted)
+	}
+
+	if got, omitted := ParseStatus("not an artifact\n"); got != nil || omitted != 0 {
+		t.Errorf("ParseStatus(malformed) = %v, %d; want nil, 0", got, omitted)
+	}
+}
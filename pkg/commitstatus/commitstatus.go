@@ -0,0 +1,268 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package commitstatus posts the statuses of builds for Git commits to
+// GitHub and GitLab style APIs.
+package commitstatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+)
+
+// State is the state of a commit status.
+type State string
+
+// The states of a commit status, named as in the GitHub API.
+const (
+	Pending State = "pending"
+	Success State = "success"
+	Failure State = "failure"
+	Error   State = "error"
+)
+
+// The API URLs used for providers that don't specify one.
+const (
+	DefaultGitHubAPIURL = "https://api.github.com"
+	DefaultGitLabAPIURL = "https://gitlab.com/api/v4"
+)
+
+// CredentialsURL returns the URL for whose host the Git credentials that
+// authenticate to the provider's API at apiURL, or its default API, are
+// annotated: the API's own, or github.com for GitHub's API.
+func CredentialsURL(provider v1alpha1.CommitStatusProvider, apiURL string) string {
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+		if provider == v1alpha1.GitLabCommitStatus {
+			apiURL = DefaultGitLabAPIURL
+		}
+	}
+	if u, err := url.Parse(apiURL); err == nil && u.Hostname() == "api.github.com" {
+		return "https://github.com"
+	}
+	return apiURL
+}
+
+// maxDescription is the longest description GitHub accepts.
+const maxDescription = 140
+
+// Status is a status to post for a commit.
+type Status struct {
+	State       State
+	Context     string
+	Description string
+	TargetURL   string
+}
+
+// Post posts the status for the commit of the repository at repoURL, using
+// the API of the given provider at apiURL, or that provider's default API
+// if apiURL is empty.
+func Post(client *http.Client, provider v1alpha1.CommitStatusProvider, apiURL, token, repoURL, commit string, status Status) error {
+	repo, err := repoPath(repoURL)
+	if err != nil {
+		return err
+	}
+	if len(status.Description) > maxDescription {
+		status.Description = status.Description[:maxDescription-3] + "..."
+	}
+
+	var endpoint string
+	var body interface{}
+	switch provider {
+	case "", v1alpha1.GitHubCommitStatus:
+		if apiURL == "" {
+			apiURL = DefaultGitHubAPIURL
+		}
+		endpoint = fmt.Sprintf("%s/repos/%s/statuses/%s", strings.TrimSuffix(apiURL, "/"), repo, commit)
+		body = map[string]string{
+			"state":       string(status.State),
+			"context":     status.Context,
+			"description": status.Description,
+			"target_url":  status.TargetURL,
+		}
+	case v1alpha1.GitLabCommitStatus:
+		if apiURL == "" {
+			apiURL = DefaultGitLabAPIURL
+		}
+		endpoint = fmt.Sprintf("%s/projects/%s/statuses/%s", strings.TrimSuffix(apiURL, "/"), url.PathEscape(repo), commit)
+		body = map[string]string{
+			"state":       gitLabState(status.State),
+			"name":        status.Context,
+			"description": status.Description,
+			"target_url":  status.TargetURL,
+		}
+	default:
+		return fmt.Errorf("unknown commit status provider %q", provider)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("posting %s status for commit %s: unexpected status %s: %s", status.State, commit, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Update is a status to post for the commit of one of a build's Git
+// sources.
+type Update struct {
+	// Build is the build whose status is posted.
+	Build    *v1alpha1.Build
+	Provider v1alpha1.CommitStatusProvider
+	APIURL   string
+	RepoURL  string
+	Commit   string
+	Status   Status
+}
+
+// Sender posts updates. Updates for the same build are posted in the order
+// they were sent, and failed posts are retried with exponential backoff.
+type Sender struct {
+	// Client posts the updates.
+	Client *http.Client
+	// Token returns the API token with which the update is posted. It's
+	// called before each attempt.
+	Token func(Update) (string, error)
+	// Attempts is how many times posting an update is attempted.
+	Attempts int
+	// Backoff is the delay before the first retry; the delay doubles with
+	// each retry.
+	Backoff time.Duration
+	// Failed is called with the updates that couldn't be posted, and the
+	// last error posting each.
+	Failed func(Update, error)
+
+	mu     sync.Mutex
+	queues map[string][]Update
+}
+
+// Send queues the update for posting after the earlier updates with the
+// same key, which identifies the build.
+func (s *Sender) Send(key string, u Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queues == nil {
+		s.queues = map[string][]Update{}
+	}
+	q, running := s.queues[key]
+	s.queues[key] = append(q, u)
+	if !running {
+		go s.drain(key)
+	}
+}
+
+// drain posts the updates queued with the key until there are none left.
+func (s *Sender) drain(key string) {
+	for {
+		s.mu.Lock()
+		q := s.queues[key]
+		if len(q) == 0 {
+			delete(s.queues, key)
+			s.mu.Unlock()
+			return
+		}
+		u := q[0]
+		s.mu.Unlock()
+
+		if err := s.deliver(u); err != nil && s.Failed != nil {
+			s.Failed(u, err)
+		}
+
+		s.mu.Lock()
+		s.queues[key] = s.queues[key][1:]
+		s.mu.Unlock()
+	}
+}
+
+// deliver posts the update until the API accepts it or it runs out of
+// attempts.
+func (s *Sender) deliver(u Update) error {
+	var err error
+	for attempt := 0; attempt < s.Attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.Backoff << uint(attempt-1))
+		}
+		if err = s.post(u); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *Sender) post(u Update) error {
+	token, err := s.Token(u)
+	if err != nil {
+		return err
+	}
+	return Post(s.Client, u.Provider, u.APIURL, token, u.RepoURL, u.Commit, u.Status)
+}
+
+// gitLabState returns the GitLab name of the state.
+func gitLabState(s State) string {
+	switch s {
+	case Pending:
+		return "running"
+	case Success:
+		return "success"
+	case Error:
+		return "canceled"
+	default:
+		return "failed"
+	}
+}
+
+// repoPath returns the path of the repository at a Git URL, without a
+// trailing .git, such as owner/repo. The URL may also be an scp-like
+// address such as git@github.com:owner/repo.git.
+func repoPath(repoURL string) (string, error) {
+	path := repoURL
+	if u, err := url.Parse(repoURL); err == nil && u.Host != "" {
+		path = u.Path
+	} else if i := strings.Index(repoURL, ":"); i >= 0 {
+		path = repoURL[i+1:]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if !strings.Contains(path, "/") {
+		return "", fmt.Errorf("no repository path in Git URL %q", repoURL)
+	}
+	return path, nil
+}
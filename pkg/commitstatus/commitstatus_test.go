@@ -0,0 +1,237 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commitstatus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+)
+
+// request is a status request received by a fake API.
+type request struct {
+	Path, Authorization string
+	Body                map[string]string
+}
+
+func TestPost(t *testing.T) {
+	status := Status{
+		State:       Pending,
+		Context:     "knative-build",
+		Description: "Build started",
+		TargetURL:   "https://ci.example.com/default/my-build",
+	}
+	for _, c := range []struct {
+		desc     string
+		provider v1alpha1.CommitStatusProvider
+		apiPath  string
+		repoURL  string
+		status   Status
+		want     request
+	}{{
+		desc:    "GitHub",
+		repoURL: "https://github.com/owner/repo.git",
+		status:  status,
+		want: request{
+			Path:          "/repos/owner/repo/statuses/abc123",
+			Authorization: "Bearer secret-token",
+			Body: map[string]string{
+				"state":       "pending",
+				"context":     "knative-build",
+				"description": "Build started",
+				"target_url":  "https://ci.example.com/default/my-build",
+			},
+		},
+	}, {
+		desc:     "GitHub Enterprise with an scp-like URL",
+		provider: v1alpha1.GitHubCommitStatus,
+		apiPath:  "/api/v3/",
+		repoURL:  "git@github.example.com:owner/repo.git",
+		status:   Status{State: Error, Context: "ci", Description: strings.Repeat("x", 200)},
+		want: request{
+			Path:          "/api/v3/repos/owner/repo/statuses/abc123",
+			Authorization: "Bearer secret-token",
+			Body: map[string]string{
+				"state":       "error",
+				"context":     "ci",
+				"description": strings.Repeat("x", 137) + "...",
+				"target_url":  "",
+			},
+		},
+	}, {
+		desc:     "GitLab subgroup",
+		provider: v1alpha1.GitLabCommitStatus,
+		apiPath:  "/api/v4",
+		repoURL:  "https://gitlab.com/group/sub/repo",
+		status:   status,
+		want: request{
+			Path:          "/api/v4/projects/group%2Fsub%2Frepo/statuses/abc123",
+			Authorization: "Bearer secret-token",
+			Body: map[string]string{
+				"state":       "running",
+				"name":        "knative-build",
+				"description": "Build started",
+				"target_url":  "https://ci.example.com/default/my-build",
+			},
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			var got request
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.Path = r.URL.EscapedPath()
+				got.Authorization = r.Header.Get("Authorization")
+				if err := json.NewDecoder(r.Body).Decode(&got.Body); err != nil {
+					t.Errorf("Decoding request: %v", err)
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer ts.Close()
+
+			if err := Post(ts.Client(), c.provider, ts.URL+c.apiPath, "secret-token", c.repoURL, "abc123", c.status); err != nil {
+				t.Fatalf("Post() = %v", err)
+			}
+			if d := cmp.Diff(c.want, got); d != "" {
+				t.Errorf("Diff request (-want, +got): %s", d)
+			}
+		})
+	}
+}
+
+func TestPostError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad credentials", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	err := Post(ts.Client(), "", ts.URL, "bad-token", "https://github.com/owner/repo", "abc123", Status{State: Success})
+	if err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("Post() = %v, want error with the response", err)
+	}
+	if err := Post(ts.Client(), "", ts.URL, "token", "https://github.com/repo", "abc123", Status{State: Success}); err == nil {
+		t.Error("Post() with no repository path succeeded, want error")
+	}
+}
+
+func TestCredentialsURL(t *testing.T) {
+	for _, c := range []struct {
+		provider v1alpha1.CommitStatusProvider
+		apiURL   string
+		want     string
+	}{
+		{"", "", "https://github.com"},
+		{v1alpha1.GitHubCommitStatus, "https://api.github.com/", "https://github.com"},
+		{v1alpha1.GitHubCommitStatus, "https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3"},
+		{v1alpha1.GitLabCommitStatus, "", DefaultGitLabAPIURL},
+	} {
+		if got := CredentialsURL(c.provider, c.apiURL); got != c.want {
+			t.Errorf("CredentialsURL(%q, %q) = %q, want %q", c.provider, c.apiURL, got, c.want)
+		}
+	}
+}
+
+func TestSendInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var states []string
+	failures := 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decoding request: %v", err)
+		}
+		states = append(states, body["state"])
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+	received := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, states...)
+	}
+
+	var tokens int
+	sender := &Sender{
+		Client: ts.Client(),
+		Token: func(Update) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			tokens++
+			return "secret-token", nil
+		},
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+		Failed: func(u Update, err error) {
+			t.Errorf("Failed to post %s status: %v", u.Status.State, err)
+		},
+	}
+	update := func(state State) Update {
+		return Update{APIURL: ts.URL, RepoURL: "https://github.com/owner/repo", Commit: "abc123", Status: Status{State: state}}
+	}
+	sender.Send("default/ordered", update(Pending))
+	sender.Send("default/ordered", update(Success))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(received()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if d := cmp.Diff([]string{"pending", "success"}, received()); d != "" {
+		t.Errorf("Unexpected states (-want, +got): %s", d)
+	}
+	// The token is fetched again for the retried post.
+	mu.Lock()
+	defer mu.Unlock()
+	if tokens != 3 {
+		t.Errorf("Token was fetched %d times, want 3", tokens)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	failed := make(chan error, 1)
+	sender := &Sender{
+		Token: func(Update) (string, error) {
+			return "", errors.New("no token")
+		},
+		Attempts: 2,
+		Backoff:  time.Millisecond,
+		Failed: func(u Update, err error) {
+			failed <- err
+		},
+	}
+	sender.Send("default/failing", Update{RepoURL: "https://github.com/owner/repo", Commit: "abc123", Status: Status{State: Pending}})
+	select {
+	case err := <-failed:
+		if err.Error() != "no token" {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the post to fail")
+	}
+}
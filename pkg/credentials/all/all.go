@@ -0,0 +1,31 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package all registers each of the credential builders. It is imported
+// for its side effects by creds-init and by the controller, so that both
+// agree on the builders and their flags.
+package all
+
+import (
+	// Register the credential builders.
+	_ "github.com/knative/build/pkg/credentials/dockercreds"
+	_ "github.com/knative/build/pkg/credentials/gitcreds"
+	_ "github.com/knative/build/pkg/credentials/mavencreds"
+	_ "github.com/knative/build/pkg/credentials/netrccreds"
+	_ "github.com/knative/build/pkg/credentials/npmcreds"
+	_ "github.com/knative/build/pkg/credentials/pypicreds"
+	_ "github.com/knative/build/pkg/credentials/s3creds"
+)
@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BasicAuth is the username and password of a basic-auth Secret.
+type BasicAuth struct {
+	Secret   string
+	Username string
+	Password string
+}
+
+// ReadBasicAuth reads the username and password of the basic-auth Secret
+// from its volume.
+func ReadBasicAuth(secret string) (*BasicAuth, error) {
+	secretPath := VolumeName(secret)
+
+	ub, err := ioutil.ReadFile(filepath.Join(secretPath, corev1.BasicAuthUsernameKey))
+	if err != nil {
+		return nil, err
+	}
+	pb, err := ioutil.ReadFile(filepath.Join(secretPath, corev1.BasicAuthPasswordKey))
+	if err != nil {
+		return nil, err
+	}
+	return &BasicAuth{
+		Secret:   secret,
+		Username: string(ub),
+		Password: string(pb),
+	}, nil
+}
+
+// CheckControlCharacters returns an error if the username or password
+// contains control characters, such as newlines, which would let them add
+// settings to the file they are written to.
+func (b *BasicAuth) CheckControlCharacters(file string) error {
+	if strings.IndexFunc(b.Username+b.Password, unicode.IsControl) >= 0 {
+		return fmt.Errorf("credentials of secret %v contain control characters, which %s doesn't support", b.Secret, file)
+	}
+	return nil
+}
+
+// BasicAuthFlag is a flag.Value whose values are secret=target pairs,
+// naming a basic-auth Secret and the target, such as the URL of a
+// registry, that its credentials apply to.
+type BasicAuthFlag struct {
+	Entries map[string]BasicAuth
+}
+
+// NewBasicAuthFlag returns a BasicAuthFlag without entries.
+func NewBasicAuthFlag() *BasicAuthFlag {
+	return &BasicAuthFlag{Entries: make(map[string]BasicAuth)}
+}
+
+func (f *BasicAuthFlag) String() string {
+	if f == nil {
+		// According to flag.Value this can happen.
+		return ""
+	}
+	var pairs []string
+	for _, target := range f.Targets() {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", f.Entries[target].Secret, target))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *BasicAuthFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("Expect entries of the form secret=target, got: %v", value)
+	}
+	secret, target := parts[0], parts[1]
+
+	if _, ok := f.Entries[target]; ok {
+		return fmt.Errorf("Multiple entries for: %v", target)
+	}
+	e, err := ReadBasicAuth(secret)
+	if err != nil {
+		return err
+	}
+	f.Entries[target] = *e
+	return nil
+}
+
+// Targets returns the targets of the flag's entries, in order.
+func (f *BasicAuthFlag) Targets() []string {
+	var targets []string
+	for k := range f.Entries {
+		targets = append(targets, k)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// MatchingBasicAuth implements the MatchingAnnotations method of Builder
+// for builders of basic-auth credentials: it returns the flags, named
+// flagName, that pass a basic-auth Secret to a BasicAuthFlag for each of
+// the Secret's annotations with annotationPrefix.
+func MatchingBasicAuth(secret *corev1.Secret, annotationPrefix, flagName string) []string {
+	var flags []string
+	if secret.Type != corev1.SecretTypeBasicAuth {
+		return flags
+	}
+	for _, v := range SortAnnotations(secret.Annotations, annotationPrefix) {
+		flags = append(flags, fmt.Sprintf("-%s=%s=%s", flagName, secret.Name, v))
+	}
+	return flags
+}
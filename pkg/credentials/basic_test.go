@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBasicAuthFlag(t *testing.T) {
+	VolumePath, _ = ioutil.TempDir("", "")
+	dir := VolumeName("foo")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatalf("os.MkdirAll(%s) = %v", dir, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, corev1.BasicAuthUsernameKey), []byte("bar"), 0777); err != nil {
+		t.Fatalf("ioutil.WriteFile(username) = %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, corev1.BasicAuthPasswordKey), []byte("baz"), 0777); err != nil {
+		t.Fatalf("ioutil.WriteFile(password) = %v", err)
+	}
+
+	f := NewBasicAuthFlag()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(f, "basic", "")
+	if err := fs.Parse([]string{"-basic=foo=https://b.example.com/?a=b", "-basic=foo=a.example.com"}); err != nil {
+		t.Fatalf("flag.CommandLine.Parse() = %v", err)
+	}
+	want := map[string]BasicAuth{
+		"a.example.com":              {Secret: "foo", Username: "bar", Password: "baz"},
+		"https://b.example.com/?a=b": {Secret: "foo", Username: "bar", Password: "baz"},
+	}
+	if d := cmp.Diff(want, f.Entries); d != "" {
+		t.Errorf("Entries diff -want, +got: %v", d)
+	}
+	if got, want := f.String(), "foo=a.example.com,foo=https://b.example.com/?a=b"; got != want {
+		t.Errorf("String() = %v, wanted %v", got, want)
+	}
+
+	for _, value := range []string{"foo=a.example.com", "foo", "missing=c.example.com"} {
+		if err := f.Set(value); err == nil {
+			t.Errorf("Set(%q) = nil, wanted error", value)
+		}
+	}
+}
+
+func TestMatchingBasicAuth(t *testing.T) {
+	for _, c := range []struct {
+		secret *corev1.Secret
+		want   []string
+	}{{
+		secret: &corev1.Secret{
+			Type: corev1.SecretTypeBasicAuth,
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "foo",
+				Annotations: map[string]string{
+					"build.knative.dev/npm-1": "https://npm.example.com",
+					"build.knative.dev/npm-0": "https://registry.npmjs.org",
+					"build.knative.dev/git-0": "https://github.com",
+				},
+			},
+		},
+		want: []string{"-basic-npm=foo=https://npm.example.com", "-basic-npm=foo=https://registry.npmjs.org"},
+	}, {
+		secret: &corev1.Secret{
+			Type: corev1.SecretTypeSSHAuth,
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "ssh",
+				Annotations: map[string]string{
+					"build.knative.dev/npm-0": "https://registry.npmjs.org",
+				},
+			},
+		},
+	}} {
+		got := MatchingBasicAuth(c.secret, "build.knative.dev/npm-", "basic-npm")
+		if d := cmp.Diff(c.want, got); d != "" {
+			t.Errorf("MatchingBasicAuth(%s) diff -want, +got: %v", c.secret.Name, d)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package credentialstest provides fixtures for testing credential
+// builders.
+package credentialstest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+// Setup points credentials.VolumePath and HOME at a new temporary
+// directory, into which Secrets are written and from which builders'
+// files are read.
+func Setup(t *testing.T) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials.VolumePath = dir
+	os.Setenv("HOME", dir)
+}
+
+// WriteSecret writes the data of the named Secret to its volume.
+func WriteSecret(t *testing.T, name string, data map[string]string) {
+	t.Helper()
+	dir := credentials.VolumeName(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatalf("os.MkdirAll(%s) = %v", dir, err)
+	}
+	for k, v := range data {
+		if err := ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0777); err != nil {
+			t.Fatalf("ioutil.WriteFile(%s) = %v", k, err)
+		}
+	}
+}
+
+// WriteBasicAuth writes the username and password of the named basic-auth
+// Secret to its volume.
+func WriteBasicAuth(t *testing.T, name, username, password string) {
+	t.Helper()
+	WriteSecret(t, name, map[string]string{
+		corev1.BasicAuthUsernameKey: username,
+		corev1.BasicAuthPasswordKey: password,
+	})
+}
+
+// Write registers the builder's flags with flags, parses args and returns
+// the error of the builder's Write.
+func Write(t *testing.T, flags func(*flag.FlagSet), b credentials.Builder, args ...string) error {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("flag.CommandLine.Parse() = %v", err)
+	}
+	return b.Write()
+}
+
+// CheckFile checks the content of the file at the path relative to HOME.
+func CheckFile(t *testing.T, name, want string) {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(os.Getenv("HOME"), name))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(%s) = %v", name, err)
+	}
+	if string(b) != want {
+		t.Errorf("got: %v, wanted: %v", string(b), want)
+	}
+}
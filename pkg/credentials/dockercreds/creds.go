@@ -46,6 +46,7 @@ func flags(fs *flag.FlagSet) {
 
 func init() {
 	flags(flag.CommandLine)
+	credentials.Register("docker", NewBuilder())
 }
 
 // As the flag is read, this status is populated.
@@ -213,3 +214,56 @@ func authsFromDockerConfig(secret string) (map[string]entry, error) {
 	}
 	return m, nil
 }
+
+// ConfigPath returns the path of the Docker config file that the builder
+// writes.
+func ConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".docker", "config.json")
+}
+
+// Lookup returns the username and password for the registry host in the
+// Docker config file at path, as written by the builder. It returns ok
+// false if the file doesn't exist or has no entry for the registry.
+func Lookup(path, registry string) (username, password string, ok bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", false, nil
+	} else if err != nil {
+		return "", "", false, err
+	}
+	cf := configFile{}
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return "", "", false, err
+	}
+	for k, e := range cf.Auth {
+		if registryHost(k) != registryHost(registry) {
+			continue
+		}
+		if e.Username != "" || e.Password != "" {
+			return e.Username, e.Password, true, nil
+		}
+		b, err := base64.StdEncoding.DecodeString(e.Auth)
+		if err != nil {
+			return "", "", false, err
+		}
+		parts := strings.SplitN(string(b), ":", 2)
+		if len(parts) != 2 {
+			return "", "", false, fmt.Errorf("malformed auth for %q", k)
+		}
+		return parts[0], parts[1], true, nil
+	}
+	return "", "", false, nil
+}
+
+// registryHost returns the host of a registry named by a config file key,
+// which may be a URL such as https://index.docker.io/v1/.
+func registryHost(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	if i := strings.Index(key, "/"); i >= 0 {
+		key = key[:i]
+	}
+	if key == "docker.io" || key == "registry-1.docker.io" {
+		return "index.docker.io"
+	}
+	return key
+}
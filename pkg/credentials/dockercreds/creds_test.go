@@ -276,3 +276,37 @@ func TestMultipleFlagHandling(t *testing.T) {
 		t.Errorf("got: %v, wanted: %v", string(b), expected)
 	}
 }
+
+func TestLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"YmFyOmJheg=="},"https://us.gcr.io":{"username":"foo","password":"qux","auth":"Zm9vOnF1eA=="}}}`), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile(config.json) = %v", err)
+	}
+
+	for _, c := range []struct {
+		registry           string
+		username, password string
+		ok                 bool
+	}{
+		{"index.docker.io", "bar", "baz", true},
+		{"us.gcr.io", "foo", "qux", true},
+		{"gcr.io", "", "", false},
+	} {
+		username, password, ok, err := Lookup(path, c.registry)
+		if err != nil {
+			t.Errorf("Lookup(%q) = %v", c.registry, err)
+		}
+		if username != c.username || password != c.password || ok != c.ok {
+			t.Errorf("Lookup(%q) = %q, %q, %t, want %q, %q, %t", c.registry, username, password, ok, c.username, c.password, c.ok)
+		}
+	}
+
+	if _, _, ok, err := Lookup(filepath.Join(dir, "missing.json"), "gcr.io"); ok || err != nil {
+		t.Errorf("Lookup() of missing file = %t, %v, want false, nil", ok, err)
+	}
+}
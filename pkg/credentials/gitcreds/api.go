@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitcreds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+// tokenExpiryMargin is how long before they expire cached installation
+// tokens are minted again, so that they don't expire while in use.
+const tokenExpiryMargin = 5 * time.Minute
+
+// TokenCache holds the tokens minted for GitHub App Secrets until shortly
+// before they expire, so that a token isn't minted for each API request.
+// The zero value is an empty cache.
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// installationToken returns a token for the installation of the GitHub App,
+// minting one unless an unexpired token is cached.
+func (c *TokenCache) installationToken(client *http.Client, apiURL, appID, installationID string, privateKey []byte, now time.Time) (string, error) {
+	if c == nil {
+		token, _, err := installationToken(client, apiURL, appID, installationID, privateKey, now)
+		return token, err
+	}
+	// The key covers the App's private key, so that tokens aren't reused
+	// once a Secret is changed.
+	sum := sha256.Sum256([]byte(strings.Join([]string{apiURL, appID, installationID, string(privateKey)}, "\x00")))
+	key := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[key]; ok && now.Before(t.expires.Add(-tokenExpiryMargin)) {
+		return t.token, nil
+	}
+	token, expires, err := installationToken(client, apiURL, appID, installationID, privateKey, now)
+	if err != nil {
+		return "", err
+	}
+	if c.tokens == nil {
+		c.tokens = map[string]cachedToken{}
+	}
+	for k, t := range c.tokens {
+		if !now.Before(t.expires) {
+			delete(c.tokens, k)
+		}
+	}
+	c.tokens[key] = cachedToken{token: token, expires: expires}
+	return token, nil
+}
+
+// APIToken returns the token with which the secret authenticates to the
+// API of the Git host of hostURL, if the secret is annotated for that host:
+// the token of token Secrets, the password of basic auth Secrets, or a
+// token minted for GitHub App Secrets. Minted tokens are cached in tokens,
+// unless it is nil.
+func APIToken(client *http.Client, tokens *TokenCache, secret *corev1.Secret, hostURL string) (string, bool, error) {
+	host := urlHost(hostURL)
+	var matches bool
+	for _, v := range credentials.SortAnnotations(secret.Annotations, annotationPrefix) {
+		if urlHost(v) == host {
+			matches = true
+			break
+		}
+	}
+	if !matches {
+		return "", false, nil
+	}
+
+	read := func(key string) string {
+		return strings.TrimSpace(string(secret.Data[key]))
+	}
+	switch secret.Type {
+	case corev1.SecretTypeBasicAuth:
+		return read(corev1.BasicAuthPasswordKey), true, nil
+	case TokenSecretType:
+		return read(TokenKey), true, nil
+	case GitHubAppSecretType:
+		apiURL := read(GitHubAppAPIURLKey)
+		if apiURL == "" {
+			apiURL = defaultGitHubAPIURL
+		}
+		token, err := tokens.installationToken(client, apiURL, read(GitHubAppIDKey), read(GitHubAppInstallationIDKey), secret.Data[GitHubAppPrivateKeyKey], time.Now())
+		if err != nil {
+			return "", false, fmt.Errorf("getting token of secret %v: %v", secret.Name, err)
+		}
+		return token, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// urlHost returns the host name of a Git URL, which may also be an
+// scp-like address such as git@github.com:owner/repo, or a bare host.
+func urlHost(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if i := strings.Index(s, "@"); i >= 0 {
+		s = s[i+1:]
+	}
+	if i := strings.IndexAny(s, ":/"); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
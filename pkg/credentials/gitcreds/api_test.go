@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitcreds
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPIToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	ts := fakeGitHub(t, key, "42", "1234", "v1.installation")
+	defer ts.Close()
+
+	secret := func(typ corev1.SecretType, host string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "creds",
+				Annotations: map[string]string{"build.knative.dev/git-0": host},
+			},
+			Type: typ,
+			Data: map[string][]byte{},
+		}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+
+	for _, c := range []struct {
+		desc    string
+		secret  *corev1.Secret
+		repo    string
+		want    string
+		wantOK  bool
+		wantErr bool
+	}{{
+		desc:   "basic auth",
+		secret: secret(corev1.SecretTypeBasicAuth, "https://github.com", map[string]string{"username": "me", "password": "pat"}),
+		repo:   "https://github.com/knative/build.git",
+		want:   "pat",
+		wantOK: true,
+	}, {
+		desc:   "token for scp-like repository",
+		secret: secret(TokenSecretType, "https://gitlab.example.com:8443", map[string]string{TokenKey: "glpat\n"}),
+		repo:   "git@gitlab.example.com:group/sub/repo.git",
+		want:   "glpat",
+		wantOK: true,
+	}, {
+		desc: "GitHub App",
+		secret: secret(GitHubAppSecretType, "https://github.com", map[string]string{
+			GitHubAppIDKey:             "42",
+			GitHubAppInstallationIDKey: "1234",
+			GitHubAppPrivateKeyKey:     string(pemKey),
+			GitHubAppAPIURLKey:         ts.URL,
+		}),
+		repo:   "https://github.com/knative/build",
+		want:   "v1.installation",
+		wantOK: true,
+	}, {
+		desc: "GitHub App that can't mint tokens",
+		secret: secret(GitHubAppSecretType, "https://github.com", map[string]string{
+			GitHubAppIDKey:             "43",
+			GitHubAppInstallationIDKey: "1234",
+			GitHubAppPrivateKeyKey:     string(pemKey),
+			GitHubAppAPIURLKey:         ts.URL,
+		}),
+		repo:    "https://github.com/knative/build",
+		wantErr: true,
+	}, {
+		desc:   "other host",
+		secret: secret(TokenSecretType, "https://gitlab.com", map[string]string{TokenKey: "glpat"}),
+		repo:   "https://github.com/knative/build",
+	}, {
+		desc:   "SSH",
+		secret: secret(corev1.SecretTypeSSHAuth, "github.com", map[string]string{corev1.SSHAuthPrivateKey: "key"}),
+		repo:   "git@github.com:knative/build.git",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, ok, err := APIToken(http.DefaultClient, nil, c.secret, c.repo)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("APIToken() = %v, wanted error %t", err, c.wantErr)
+			}
+			if got != c.want || ok != c.wantOK {
+				t.Errorf("APIToken() = %q, %t, want %q, %t", got, ok, c.want, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestAPITokenCache(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	github := fakeGitHub(t, key, "42", "1234", "v1.installation")
+	defer github.Close()
+	var mints int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&mints, 1)
+		github.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Annotations: map[string]string{"build.knative.dev/git-0": "https://github.com"},
+		},
+		Type: GitHubAppSecretType,
+		Data: map[string][]byte{
+			GitHubAppIDKey:             []byte("42"),
+			GitHubAppInstallationIDKey: []byte("1234"),
+			GitHubAppPrivateKeyKey:     pemKey,
+			GitHubAppAPIURLKey:         []byte(ts.URL),
+		},
+	}
+	tokens := &TokenCache{}
+	for i := 0; i < 3; i++ {
+		if got, _, err := APIToken(http.DefaultClient, tokens, secret, "https://github.com/knative/build"); err != nil || got != "v1.installation" {
+			t.Fatalf("APIToken() = %q, %v, want v1.installation", got, err)
+		}
+	}
+	if got := atomic.LoadInt32(&mints); got != 1 {
+		t.Errorf("Minted %d tokens, want 1", got)
+	}
+
+	// Tokens are minted again shortly before they expire.
+	for k, cached := range tokens.tokens {
+		cached.expires = time.Now().Add(tokenExpiryMargin - time.Minute)
+		tokens.tokens[k] = cached
+	}
+	if _, _, err := APIToken(http.DefaultClient, tokens, secret, "https://github.com/knative/build"); err != nil {
+		t.Fatalf("APIToken() = %v", err)
+	}
+	if got := atomic.LoadInt32(&mints); got != 2 {
+		t.Errorf("Minted %d tokens, want 2", got)
+	}
+}
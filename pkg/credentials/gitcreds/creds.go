@@ -19,6 +19,7 @@ package gitcreds
 import (
 	"flag"
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -29,11 +30,17 @@ const (
 	annotationPrefix = "build.knative.dev/git-"
 	basicAuthFlag    = "basic-git"
 	sshFlag          = "ssh-git"
+	tokenFlag        = "token-git"
+	gitHubAppFlag    = "github-app-git"
 )
 
 var (
-	basicConfig basicGitConfig
-	sshConfig   sshGitConfig
+	basicConfig     basicGitConfig
+	sshConfig       sshGitConfig
+	tokenConfig     tokenGitConfig
+	gitHubAppConfig tokenGitConfig
+	hostKeyPolicy   hostKeyPolicies
+	hostKeyPins     hostKeyFingerprints
 )
 
 func flags(fs *flag.FlagSet) {
@@ -42,10 +49,23 @@ func flags(fs *flag.FlagSet) {
 
 	sshConfig = sshGitConfig{entries: make(map[string]sshEntry)}
 	fs.Var(&sshConfig, sshFlag, "List of secret=url pairs.")
+
+	tokenConfig = tokenGitConfig{entries: make(map[string]tokenEntry)}
+	fs.Var(&tokenConfig, tokenFlag, "List of secret=url pairs.")
+
+	gitHubAppConfig = tokenGitConfig{entries: make(map[string]tokenEntry), githubApp: true}
+	fs.Var(&gitHubAppConfig, gitHubAppFlag, "List of secret=url pairs.")
+
+	hostKeyPolicy = hostKeyPolicies{def: HostKeyPolicyTOFU, secrets: make(map[string]HostKeyPolicy)}
+	fs.Var(&hostKeyPolicy, hostKeyPolicyFlag, "The SSH host key policy (strict, tofu or pinned), or a secret=policy pair overriding it.")
+
+	hostKeyPins = make(hostKeyFingerprints)
+	fs.Var(hostKeyPins, hostKeyFingerprintsFlag, "List of secret=fingerprints pairs of pinned SSH host keys.")
 }
 
 func init() {
 	flags(flag.CommandLine)
+	credentials.Register("git", NewBuilder())
 }
 
 type gitConfigBuilder struct{}
@@ -65,20 +85,52 @@ func (*gitConfigBuilder) MatchingAnnotations(secret *corev1.Secret) []string {
 
 	case corev1.SecretTypeSSHAuth:
 		flagName = sshFlag
+		flags = hostKeyFlags(secret)
+
+	case TokenSecretType:
+		flagName = tokenFlag
+
+	case GitHubAppSecretType:
+		flagName = gitHubAppFlag
 
 	default:
 		return flags
 	}
 
-	for _, v := range credentials.SortAnnotations(secret.Annotations, annotationPrefix) {
+	annotations := credentials.SortAnnotations(secret.Annotations, annotationPrefix)
+	if len(annotations) == 0 {
+		return nil
+	}
+	for _, v := range annotations {
 		flags = append(flags, fmt.Sprintf("-%s=%s=%s", flagName, secret.Name, v))
 	}
 	return flags
 }
 
+// hostKeyFlags returns the flags that pass the host key policy of the SSH
+// secret to the credential helper, whose default policy is TOFU.
+func hostKeyFlags(secret *corev1.Secret) []string {
+	policy, fps, err := SecretHostKeyPolicy(secret)
+	if err != nil || policy == HostKeyPolicyTOFU {
+		// Invalid policies are rejected when the build is validated.
+		return nil
+	}
+	flags := []string{fmt.Sprintf("-%s=%s=%s", hostKeyPolicyFlag, secret.Name, policy)}
+	if len(fps) > 0 {
+		flags = append(flags, fmt.Sprintf("-%s=%s=%s", hostKeyFingerprintsFlag, secret.Name, strings.Join(fps, ",")))
+	}
+	return flags
+}
+
 func (*gitConfigBuilder) Write() error {
 	if err := basicConfig.Write(); err != nil {
 		return err
 	}
+	if err := tokenConfig.Write(); err != nil {
+		return err
+	}
+	if err := gitHubAppConfig.Write(); err != nil {
+		return err
+	}
 	return sshConfig.Write()
 }
@@ -457,6 +457,28 @@ func TestMatchingAnnotations(t *testing.T) {
 			},
 		},
 		wantFlag: []string{fmt.Sprintf("-%s=ssh=keys1", sshFlag), fmt.Sprintf("-%s=ssh=keys2", sshFlag), fmt.Sprintf("-%s=ssh=keys3", sshFlag)},
+	}, {
+		secret: &corev1.Secret{
+			Type: TokenSecretType,
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "token",
+				Annotations: map[string]string{
+					fmt.Sprintf("%s.testkeys", annotationPrefix): "https://gitlab.com",
+				},
+			},
+		},
+		wantFlag: []string{fmt.Sprintf("-%s=token=https://gitlab.com", tokenFlag)},
+	}, {
+		secret: &corev1.Secret{
+			Type: GitHubAppSecretType,
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app",
+				Annotations: map[string]string{
+					fmt.Sprintf("%s.testkeys", annotationPrefix): "https://github.com",
+				},
+			},
+		},
+		wantFlag: []string{fmt.Sprintf("-%s=app=https://github.com", gitHubAppFlag)},
 	}}
 
 	nb := NewBuilder()
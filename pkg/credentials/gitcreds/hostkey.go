@@ -0,0 +1,241 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitcreds
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+// HostKeyPolicy determines how the host keys of SSH Git servers are trusted.
+type HostKeyPolicy string
+
+const (
+	// HostKeyPolicyStrict trusts only the host keys in the Secret's
+	// known_hosts, which must be present.
+	HostKeyPolicyStrict HostKeyPolicy = "strict"
+	// HostKeyPolicyTOFU trusts the host keys in the Secret's known_hosts,
+	// or, if it has none, whichever keys the server presents when the
+	// build starts.
+	HostKeyPolicyTOFU HostKeyPolicy = "tofu"
+	// HostKeyPolicyPinned trusts only the host keys whose fingerprints
+	// are listed in the Secret's HostKeyFingerprintsAnnotation.
+	HostKeyPolicyPinned HostKeyPolicy = "pinned"
+)
+
+const (
+	// HostKeyPolicyAnnotation overrides the controller's host key policy
+	// for an SSH Secret.
+	HostKeyPolicyAnnotation = "build.knative.dev/ssh-host-key-policy"
+	// HostKeyFingerprintsAnnotation lists the comma-separated SHA256
+	// fingerprints of the host keys that are trusted under the pinned
+	// policy, as printed by ssh-keygen -l.
+	HostKeyFingerprintsAnnotation = "build.knative.dev/ssh-host-key-fingerprints"
+
+	hostKeyPolicyFlag       = "ssh-host-key-policy"
+	hostKeyFingerprintsFlag = "ssh-host-key-fingerprints"
+
+	fingerprintPrefix = "SHA256:"
+)
+
+func parseHostKeyPolicy(s string) (HostKeyPolicy, error) {
+	switch p := HostKeyPolicy(strings.ToLower(s)); p {
+	case HostKeyPolicyStrict, HostKeyPolicyTOFU, HostKeyPolicyPinned:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown SSH host key policy %q, want one of %q, %q or %q", s, HostKeyPolicyStrict, HostKeyPolicyTOFU, HostKeyPolicyPinned)
+}
+
+// hostKeyPolicies holds the default host key policy, and those of
+// individual Secrets.
+// hostKeyPolicies implements flag.Value
+type hostKeyPolicies struct {
+	def     HostKeyPolicy
+	secrets map[string]HostKeyPolicy
+}
+
+func (hp *hostKeyPolicies) String() string {
+	if hp == nil {
+		// According to flag.Value this can happen.
+		return ""
+	}
+	var secrets []string
+	for k, v := range hp.secrets {
+		secrets = append(secrets, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(secrets)
+	return strings.Join(append([]string{string(hp.def)}, secrets...), ",")
+}
+
+// Set takes either a policy, which becomes the default, or a secret=policy
+// pair.
+func (hp *hostKeyPolicies) Set(value string) error {
+	parts := strings.Split(value, "=")
+	switch len(parts) {
+	case 1:
+		p, err := parseHostKeyPolicy(parts[0])
+		if err != nil {
+			return err
+		}
+		hp.def = p
+	case 2:
+		p, err := parseHostKeyPolicy(parts[1])
+		if err != nil {
+			return err
+		}
+		hp.secrets[parts[0]] = p
+	default:
+		return fmt.Errorf("expect entries of the form policy or secret=policy, got: %v", value)
+	}
+	return nil
+}
+
+// For returns the policy for the secret.
+func (hp *hostKeyPolicies) For(secret string) HostKeyPolicy {
+	if p, ok := hp.secrets[secret]; ok {
+		return p
+	}
+	return hp.def
+}
+
+// hostKeyFingerprints holds the pinned host key fingerprints of Secrets.
+// hostKeyFingerprints implements flag.Value
+type hostKeyFingerprints map[string][]string
+
+func (hf hostKeyFingerprints) String() string {
+	var values []string
+	for k, v := range hf {
+		values = append(values, fmt.Sprintf("%s=%s", k, strings.Join(v, ",")))
+	}
+	sort.Strings(values)
+	return strings.Join(values, ";")
+}
+
+// Set takes secret=fingerprint[,fingerprint...].
+func (hf hostKeyFingerprints) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expect entries of the form secret=fingerprints, got: %v", value)
+	}
+	fps, err := parseFingerprints(parts[1])
+	if err != nil {
+		return err
+	}
+	hf[parts[0]] = append(hf[parts[0]], fps...)
+	return nil
+}
+
+func parseFingerprints(s string) ([]string, error) {
+	var fps []string
+	for _, fp := range strings.Split(s, ",") {
+		fp = strings.TrimSpace(fp)
+		if fp == "" {
+			continue
+		}
+		if !strings.HasPrefix(fp, fingerprintPrefix) {
+			return nil, fmt.Errorf("host key fingerprint %q is not a %s fingerprint", fp, strings.TrimSuffix(fingerprintPrefix, ":"))
+		}
+		fps = append(fps, fp)
+	}
+	if len(fps) == 0 {
+		return nil, fmt.Errorf("no host key fingerprints in %q", s)
+	}
+	return fps, nil
+}
+
+// pinnedKnownHosts returns the lines of knownHosts whose keys have one of
+// the fingerprints.
+func pinnedKnownHosts(knownHosts string, fingerprints []string) (string, error) {
+	pinned := map[string]bool{}
+	for _, fp := range fingerprints {
+		pinned[fp] = true
+	}
+	var lines []string
+	for _, line := range strings.Split(knownHosts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		// Skip markers, such as @cert-authority.
+		if strings.HasPrefix(fields[0], "@") {
+			fields = fields[1:]
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(blob)
+		if pinned[fingerprintPrefix+base64.RawStdEncoding.EncodeToString(sum[:])] {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("none of the host keys match the pinned fingerprints %s", strings.Join(fingerprints, ", "))
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// SecretHostKeyPolicy returns the host key policy of the SSH Secret, which
+// is that of its HostKeyPolicyAnnotation, or else the controller's, along
+// with its pinned fingerprints.
+func SecretHostKeyPolicy(secret *corev1.Secret) (HostKeyPolicy, []string, error) {
+	policy := hostKeyPolicy.def
+	if v, ok := secret.Annotations[HostKeyPolicyAnnotation]; ok {
+		p, err := parseHostKeyPolicy(v)
+		if err != nil {
+			return "", nil, err
+		}
+		policy = p
+	}
+	if policy != HostKeyPolicyPinned {
+		return policy, nil, nil
+	}
+	fps, err := parseFingerprints(secret.Annotations[HostKeyFingerprintsAnnotation])
+	if err != nil {
+		return "", nil, fmt.Errorf("annotation %q: %v", HostKeyFingerprintsAnnotation, err)
+	}
+	return policy, fps, nil
+}
+
+// ValidateSSHSecret checks that the SSH Secret, if it is used for Git,
+// satisfies its host key policy.
+func ValidateSSHSecret(secret *corev1.Secret) error {
+	if secret.Type != corev1.SecretTypeSSHAuth {
+		return nil
+	}
+	if len(credentials.SortAnnotations(secret.Annotations, annotationPrefix)) == 0 {
+		return nil
+	}
+	policy, _, err := SecretHostKeyPolicy(secret)
+	if err != nil {
+		return err
+	}
+	if _, ok := secret.Data[sshKnownHosts]; !ok && policy == HostKeyPolicyStrict {
+		return fmt.Errorf("the %s host key policy requires the Secret to have a %q key", policy, sshKnownHosts)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitcreds
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	githubKey     = "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIPoVauAnyBuD0S7uH9Ytf6mxwJAaw1+8HIKADXsgyIEM"
+	githubKeyFP   = "SHA256:dPTTUL0JbzrrrWEzqQDwO2Gug5PfUdB4SORtDYiD/50"
+	impostorKey   = "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMXWnvFGYkYb/lQfSln7AOleXgUFsNa2bO5Yb2HUk1DA"
+	impostorKeyFP = "SHA256:+8LOQKFdNm96kymGKA1Klie6RSwHBcLwUuWfNIjpwws"
+)
+
+func TestSSHHostKeyPolicy(t *testing.T) {
+	for _, c := range []struct {
+		desc           string
+		args           []string
+		knownHosts     string
+		wantKnownHosts string
+		wantErr        bool
+	}{{
+		desc:           "strict",
+		args:           []string{"-ssh-host-key-policy=strict"},
+		knownHosts:     githubKey,
+		wantKnownHosts: githubKey,
+	}, {
+		desc:    "strict without known_hosts",
+		args:    []string{"-ssh-host-key-policy=strict"},
+		wantErr: true,
+	}, {
+		desc:    "strict secret without known_hosts",
+		args:    []string{"-ssh-host-key-policy=foo=strict"},
+		wantErr: true,
+	}, {
+		desc:           "secret overrides default",
+		args:           []string{"-ssh-host-key-policy=strict", "-ssh-host-key-policy=foo=tofu"},
+		knownHosts:     githubKey,
+		wantKnownHosts: githubKey,
+	}, {
+		desc:           "pinned",
+		args:           []string{"-ssh-host-key-policy=foo=pinned", "-ssh-host-key-fingerprints=foo=" + githubKeyFP},
+		knownHosts:     "# github.com:22 SSH-2.0-babeld\n" + impostorKey + "\n" + githubKey + "\n",
+		wantKnownHosts: githubKey + "\n",
+	}, {
+		desc:       "pinned mismatch",
+		args:       []string{"-ssh-host-key-policy=foo=pinned", "-ssh-host-key-fingerprints=foo=" + githubKeyFP},
+		knownHosts: impostorKey,
+		wantErr:    true,
+	}, {
+		desc:       "pinned without fingerprints",
+		args:       []string{"-ssh-host-key-policy=pinned"},
+		knownHosts: githubKey,
+		wantErr:    true,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			credentials.VolumePath, _ = ioutil.TempDir("", "")
+			data := map[string]string{corev1.SSHAuthPrivateKey: "bar"}
+			if c.knownHosts != "" {
+				data[sshKnownHosts] = c.knownHosts
+			}
+			writeSecret(t, "foo", data)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags(fs)
+			// The policy applies even when given after the secret.
+			if err := fs.Parse(append([]string{"-ssh-git=foo=github.com"}, c.args...)); err != nil {
+				t.Fatalf("flag.CommandLine.Parse() = %v", err)
+			}
+
+			os.Setenv("HOME", credentials.VolumePath)
+			err := NewBuilder().Write()
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Fatalf("Write() = %v, wanted error %t", err, c.wantErr)
+			}
+			if c.wantErr {
+				return
+			}
+
+			b, err := ioutil.ReadFile(filepath.Join(credentials.VolumePath, ".ssh", "known_hosts"))
+			if err != nil {
+				t.Fatalf("ioutil.ReadFile(.ssh/known_hosts) = %v", err)
+			}
+			if d := cmp.Diff(c.wantKnownHosts, string(b)); d != "" {
+				t.Errorf("known_hosts diff -want, +got: %v", d)
+			}
+		})
+	}
+}
+
+func TestHostKeyMalformedValues(t *testing.T) {
+	for _, test := range []string{
+		"lenient",
+		"foo=lenient",
+		"foo=bar=strict",
+	} {
+		hp := hostKeyPolicies{secrets: make(map[string]HostKeyPolicy)}
+		if err := hp.Set(test); err == nil {
+			t.Errorf("hostKeyPolicies.Set(%v); got success, wanted error.", test)
+		}
+	}
+	for _, test := range []string{
+		"foo",
+		"foo=",
+		"foo=MD5:16:27:ac:a5:76:28:2d:36:63:1b:56:4d:eb:df:a6:48",
+	} {
+		hf := make(hostKeyFingerprints)
+		if err := hf.Set(test); err == nil {
+			t.Errorf("hostKeyFingerprints.Set(%v); got success, wanted error.", test)
+		}
+	}
+}
+
+func TestValidateSSHSecret(t *testing.T) {
+	sshSecret := func(annotations map[string]string, data map[string][]byte) *corev1.Secret {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotationPrefix+"0"] = "github.com"
+		return &corev1.Secret{
+			Type:       corev1.SecretTypeSSHAuth,
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh", Annotations: annotations},
+			Data:       data,
+		}
+	}
+	knownHosts := map[string][]byte{sshKnownHosts: []byte(githubKey)}
+
+	for _, c := range []struct {
+		desc      string
+		policy    string
+		secret    *corev1.Secret
+		wantFlags []string
+		wantErr   bool
+	}{{
+		desc:      "tofu without known_hosts",
+		secret:    sshSecret(nil, nil),
+		wantFlags: []string{"-ssh-git=ssh=github.com"},
+	}, {
+		desc:    "strict without known_hosts",
+		policy:  "strict",
+		secret:  sshSecret(nil, nil),
+		wantErr: true,
+	}, {
+		desc:      "strict",
+		policy:    "strict",
+		secret:    sshSecret(nil, knownHosts),
+		wantFlags: []string{"-ssh-host-key-policy=ssh=strict", "-ssh-git=ssh=github.com"},
+	}, {
+		desc:    "strict annotation without known_hosts",
+		secret:  sshSecret(map[string]string{HostKeyPolicyAnnotation: "Strict"}, nil),
+		wantErr: true,
+	}, {
+		desc:      "tofu annotation",
+		policy:    "strict",
+		secret:    sshSecret(map[string]string{HostKeyPolicyAnnotation: "tofu"}, nil),
+		wantFlags: []string{"-ssh-git=ssh=github.com"},
+	}, {
+		desc: "pinned annotation",
+		secret: sshSecret(map[string]string{
+			HostKeyPolicyAnnotation:       "pinned",
+			HostKeyFingerprintsAnnotation: fmt.Sprintf("%s, %s", githubKeyFP, impostorKeyFP),
+		}, nil),
+		wantFlags: []string{
+			"-ssh-host-key-policy=ssh=pinned",
+			fmt.Sprintf("-ssh-host-key-fingerprints=ssh=%s,%s", githubKeyFP, impostorKeyFP),
+			"-ssh-git=ssh=github.com",
+		},
+	}, {
+		desc:    "pinned without fingerprints",
+		policy:  "pinned",
+		secret:  sshSecret(nil, knownHosts),
+		wantErr: true,
+	}, {
+		desc:    "unknown policy",
+		secret:  sshSecret(map[string]string{HostKeyPolicyAnnotation: "lenient"}, nil),
+		wantErr: true,
+	}, {
+		desc:   "not used for git",
+		policy: "strict",
+		secret: &corev1.Secret{
+			Type:       corev1.SecretTypeSSHAuth,
+			ObjectMeta: metav1.ObjectMeta{Name: "ssh"},
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags(fs)
+			if c.policy != "" {
+				if err := fs.Parse([]string{"-ssh-host-key-policy=" + c.policy}); err != nil {
+					t.Fatalf("flag.CommandLine.Parse() = %v", err)
+				}
+			}
+
+			err := ValidateSSHSecret(c.secret)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Fatalf("ValidateSSHSecret() = %v, wanted error %t", err, c.wantErr)
+			}
+			if c.wantErr {
+				return
+			}
+			if d := cmp.Diff(c.wantFlags, NewBuilder().MatchingAnnotations(c.secret)); d != "" {
+				t.Errorf("MatchingAnnotations() diff -want, +got: %v", d)
+			}
+		})
+	}
+}
@@ -66,7 +66,7 @@ func (dc *sshGitConfig) Set(value string) error {
 		return fmt.Errorf("multiple entries for url: %v", url)
 	}
 
-	e, err := newSshEntry(url, secret)
+	e, err := newSshEntry(secret)
 	if err != nil {
 		return err
 	}
@@ -99,13 +99,17 @@ func (dc *sshGitConfig) Write() error {
 		if err := v.Write(sshDir); err != nil {
 			return err
 		}
+		kh, err := v.trustedHosts(k)
+		if err != nil {
+			return err
+		}
 		configEntries = append(configEntries, fmt.Sprintf(`Host %s
     HostName %s
     IdentityFile %s
     Port %s
 `, host, host, v.path(sshDir), port))
 
-		knownHosts = append(knownHosts, v.knownHosts)
+		knownHosts = append(knownHosts, kh)
 	}
 	configPath := filepath.Join(sshDir, "config")
 	configContent := strings.Join(configEntries, "")
@@ -120,6 +124,7 @@ func (dc *sshGitConfig) Write() error {
 type sshEntry struct {
 	secret     string
 	privateKey string
+	// knownHosts is empty if the secret has none.
 	knownHosts string
 }
 
@@ -138,11 +143,39 @@ func sshKeyScan(domain string) ([]byte, error) {
 	return output.Bytes(), nil
 }
 
+// trustedHosts returns the known_hosts of the entry's url under the
+// secret's host key policy.
+func (be *sshEntry) trustedHosts(u string) (string, error) {
+	policy := hostKeyPolicy.For(be.secret)
+	if be.knownHosts == "" {
+		if policy == HostKeyPolicyStrict {
+			return "", fmt.Errorf("secret %s has no %s, which the %s host key policy requires", be.secret, sshKnownHosts, policy)
+		}
+		kh, err := sshKeyScan(u)
+		if err != nil {
+			return "", err
+		}
+		be.knownHosts = string(kh)
+	}
+	if policy != HostKeyPolicyPinned {
+		return be.knownHosts, nil
+	}
+	fps := hostKeyPins[be.secret]
+	if len(fps) == 0 {
+		return "", fmt.Errorf("secret %s has no pinned host key fingerprints, which the %s host key policy requires", be.secret, policy)
+	}
+	kh, err := pinnedKnownHosts(be.knownHosts, fps)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %v", be.secret, err)
+	}
+	return kh, nil
+}
+
 func (be *sshEntry) Write(sshDir string) error {
 	return ioutil.WriteFile(be.path(sshDir), []byte(be.privateKey), 0600)
 }
 
-func newSshEntry(u, secret string) (*sshEntry, error) {
+func newSshEntry(secret string) (*sshEntry, error) {
 	secretPath := credentials.VolumeName(secret)
 
 	pk, err := ioutil.ReadFile(filepath.Join(secretPath, corev1.SSHAuthPrivateKey))
@@ -151,14 +184,14 @@ func newSshEntry(u, secret string) (*sshEntry, error) {
 	}
 	privateKey := string(pk)
 
-	kh, err := ioutil.ReadFile(filepath.Join(secretPath, sshKnownHosts))
-	if err != nil {
-		kh, err = sshKeyScan(u)
-		if err != nil {
-			return nil, err
-		}
+	// Whether to fall back to scanning the host's keys depends on the
+	// secret's host key policy, which might not have been set yet.
+	var knownHosts string
+	if kh, err := ioutil.ReadFile(filepath.Join(secretPath, sshKnownHosts)); err == nil {
+		knownHosts = string(kh)
+	} else if !os.IsNotExist(err) {
+		return nil, err
 	}
-	knownHosts := string(kh)
 
 	return &sshEntry{
 		secret:     secret,
@@ -0,0 +1,241 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitcreds
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	// TokenSecretType is the type of Secrets that hold a token, such as an
+	// OAuth token, which is sent as a bearer token to Git servers.
+	TokenSecretType = "build.knative.dev/git-token"
+	// TokenKey is the key of the token in a token Secret.
+	TokenKey = "token"
+
+	// GitHubAppSecretType is the type of Secrets that hold the private key
+	// of a GitHub App, from which a token is minted for one of the App's
+	// installations.
+	GitHubAppSecretType = "build.knative.dev/github-app"
+	// GitHubAppIDKey is the key of the App's ID in a GitHub App Secret.
+	GitHubAppIDKey = "appID"
+	// GitHubAppInstallationIDKey is the key of the installation's ID in a
+	// GitHub App Secret.
+	GitHubAppInstallationIDKey = "installationID"
+	// GitHubAppPrivateKeyKey is the key of the App's PEM-encoded private
+	// key in a GitHub App Secret.
+	GitHubAppPrivateKeyKey = "privateKey"
+	// GitHubAppAPIURLKey is the key of the optional URL of the GitHub API
+	// in a GitHub App Secret, such as that of a GitHub Enterprise server.
+	GitHubAppAPIURLKey = "apiURL"
+
+	defaultGitHubAPIURL = "https://api.github.com"
+)
+
+// As the flag is read, this status is populated.
+// tokenGitConfig implements flag.Value
+type tokenGitConfig struct {
+	entries map[string]tokenEntry
+	// The order we see things, for iterating over the above.
+	order []string
+	// githubApp is true if the entries are GitHub App Secrets.
+	githubApp bool
+}
+
+func (dc *tokenGitConfig) String() string {
+	if dc == nil {
+		// According to flag.Value this can happen.
+		return ""
+	}
+	var urls []string
+	for _, k := range dc.order {
+		v := dc.entries[k]
+		urls = append(urls, fmt.Sprintf("%s=%s", v.secret, k))
+	}
+	return strings.Join(urls, ",")
+}
+
+func (dc *tokenGitConfig) Set(value string) error {
+	parts := strings.Split(value, "=")
+	if len(parts) != 2 {
+		return fmt.Errorf("Expect entries of the form secret=url, got: %v", value)
+	}
+	secret := parts[0]
+	url := parts[1]
+
+	if _, ok := dc.entries[url]; ok {
+		return fmt.Errorf("Multiple entries for url: %v", url)
+	}
+
+	e, err := newTokenEntry(secret, dc.githubApp)
+	if err != nil {
+		return err
+	}
+	dc.entries[url] = *e
+	dc.order = append(dc.order, url)
+	return nil
+}
+
+// Write appends the configuration that sends the tokens to their URLs as
+// headers to ~/.gitconfig, minting tokens for GitHub App Secrets.
+func (dc *tokenGitConfig) Write() error {
+	if len(dc.order) == 0 {
+		return nil
+	}
+	var blurbs []string
+	for _, k := range dc.order {
+		v := dc.entries[k]
+		header, err := v.header()
+		if err != nil {
+			return fmt.Errorf("getting token of secret %v for %v: %v", v.secret, k, err)
+		}
+		blurbs = append(blurbs, fmt.Sprintf("[http %q]\n	extraHeader = %s\n", k, header))
+	}
+
+	gitConfigPath := filepath.Join(os.Getenv("HOME"), ".gitconfig")
+	f, err := os.OpenFile(gitConfigPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strings.Join(blurbs, "")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type tokenEntry struct {
+	secret string
+	token  string
+	// The remaining fields are set for GitHub App Secrets.
+	appID          string
+	installationID string
+	privateKey     []byte
+	apiURL         string
+}
+
+// header returns the Authorization header that is sent to the entry's URL.
+func (te *tokenEntry) header() (string, error) {
+	if te.privateKey == nil {
+		return "Authorization: Bearer " + te.token, nil
+	}
+	token, _, err := installationToken(http.DefaultClient, te.apiURL, te.appID, te.installationID, te.privateKey, time.Now())
+	if err != nil {
+		return "", err
+	}
+	// GitHub accepts installation tokens as the password of the
+	// x-access-token user.
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return "Authorization: Basic " + auth, nil
+}
+
+func newTokenEntry(secret string, githubApp bool) (*tokenEntry, error) {
+	secretPath := credentials.VolumeName(secret)
+	read := func(key string) (string, error) {
+		b, err := ioutil.ReadFile(filepath.Join(secretPath, key))
+		return strings.TrimSpace(string(b)), err
+	}
+
+	if !githubApp {
+		token, err := read(TokenKey)
+		if err != nil {
+			return nil, err
+		}
+		return &tokenEntry{secret: secret, token: token}, nil
+	}
+
+	e := &tokenEntry{secret: secret, apiURL: defaultGitHubAPIURL}
+	var err error
+	if e.appID, err = read(GitHubAppIDKey); err != nil {
+		return nil, err
+	}
+	if e.installationID, err = read(GitHubAppInstallationIDKey); err != nil {
+		return nil, err
+	}
+	if e.privateKey, err = ioutil.ReadFile(filepath.Join(secretPath, GitHubAppPrivateKeyKey)); err != nil {
+		return nil, err
+	}
+	if apiURL, err := read(GitHubAppAPIURLKey); err == nil && apiURL != "" {
+		e.apiURL = apiURL
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return e, nil
+}
+
+// installationToken mints a token for an installation of a GitHub App,
+// authenticating as the App with a JWT signed by its private key. It
+// returns the token and when it expires, which is after an hour.
+func installationToken(client *http.Client, apiURL, appID, installationID string, privateKey []byte, now time.Time) (string, time.Time, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// Allow for clock drift between us and GitHub, which rejects JWTs
+	// that are valid for more than ten minutes.
+	appJWT, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(9 * time.Minute).Unix(),
+		Issuer:    appID,
+	}).SignedString(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", strings.TrimSuffix(apiURL, "/"), installationID)
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+appJWT)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("minting installation token: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", time.Time{}, err
+	}
+	if token.Token == "" {
+		return "", time.Time{}, fmt.Errorf("minting installation token: response has no token")
+	}
+	if token.ExpiresAt.IsZero() {
+		token.ExpiresAt = now.Add(time.Hour)
+	}
+	return token.Token, token.ExpiresAt, nil
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitcreds
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+func writeSecret(t *testing.T, name string, data map[string]string) {
+	t.Helper()
+	dir := credentials.VolumeName(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatalf("os.MkdirAll(%s) = %v", dir, err)
+	}
+	for k, v := range data {
+		if err := ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0777); err != nil {
+			t.Fatalf("ioutil.WriteFile(%s) = %v", k, err)
+		}
+	}
+}
+
+func TestTokenFlagHandling(t *testing.T) {
+	credentials.VolumePath, _ = ioutil.TempDir("", "")
+	writeSecret(t, "foo", map[string]string{"username": "bar", "password": "baz"})
+	writeSecret(t, "tok", map[string]string{TokenKey: "s3cr3t\n"})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags(fs)
+	err := fs.Parse([]string{
+		"-basic-git=foo=https://github.com",
+		"-token-git=tok=https://gitlab.com",
+	})
+	if err != nil {
+		t.Fatalf("flag.CommandLine.Parse() = %v", err)
+	}
+
+	os.Setenv("HOME", credentials.VolumePath)
+	if err := NewBuilder().Write(); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(credentials.VolumePath, ".gitconfig"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(.gitconfig) = %v", err)
+	}
+
+	expectedGitConfig := `[credential]
+	helper = store
+[credential "https://github.com"]
+	username = bar
+[http "https://gitlab.com"]
+	extraHeader = Authorization: Bearer s3cr3t
+`
+	if string(b) != expectedGitConfig {
+		t.Errorf("got: %v, wanted: %v", string(b), expectedGitConfig)
+	}
+}
+
+func TestTokenFlagHandlingMissingFiles(t *testing.T) {
+	credentials.VolumePath, _ = ioutil.TempDir("", "")
+	writeSecret(t, "not-found", nil)
+	writeSecret(t, "app", map[string]string{GitHubAppIDKey: "1", GitHubAppInstallationIDKey: "2"})
+
+	cfg := tokenGitConfig{entries: make(map[string]tokenEntry)}
+	if err := cfg.Set("not-found=https://github.com"); err == nil {
+		t.Error("Set(); got success, wanted error.")
+	}
+	// GitHub App Secrets need a private key.
+	cfg = tokenGitConfig{entries: make(map[string]tokenEntry), githubApp: true}
+	if err := cfg.Set("app=https://github.com"); err == nil {
+		t.Error("Set(); got success, wanted error.")
+	}
+}
+
+func TestTokenFlagHandlingURLCollision(t *testing.T) {
+	credentials.VolumePath, _ = ioutil.TempDir("", "")
+	writeSecret(t, "foo", map[string]string{TokenKey: "a"})
+	writeSecret(t, "bar", map[string]string{TokenKey: "b"})
+
+	cfg := tokenGitConfig{entries: make(map[string]tokenEntry)}
+	if err := cfg.Set("foo=https://github.com"); err != nil {
+		t.Fatalf("First Set() = %v", err)
+	}
+	if err := cfg.Set("bar=https://github.com"); err == nil {
+		t.Error("Second Set(); got success, wanted error.")
+	}
+}
+
+func TestTokenMalformedValues(t *testing.T) {
+	tests := []string{
+		"bar=baz=blah",
+		"bar",
+	}
+	for _, test := range tests {
+		cfg := tokenGitConfig{}
+		if err := cfg.Set(test); err == nil {
+			t.Errorf("Set(%v); got success, wanted error.", test)
+		}
+	}
+}
+
+// fakeGitHub serves the endpoint that mints installation tokens, checking
+// that requests are authenticated as the App.
+func fakeGitHub(t *testing.T, key *rsa.PrivateKey, appID, installationID, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != fmt.Sprintf("/app/installations/%s/access_tokens", installationID) {
+			http.NotFound(w, r)
+			return
+		}
+		claims := &jwt.StandardClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims, func(tok *jwt.Token) (interface{}, error) {
+			if _, ok := tok.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", tok.Header["alg"])
+			}
+			return &key.PublicKey, nil
+		})
+		if err != nil || claims.Issuer != appID {
+			http.Error(w, fmt.Sprintf("bad credentials: %v", err), http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": %q, "expires_at": %q}`, token, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+}
+
+func TestGitHubAppFlagHandling(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	ts := fakeGitHub(t, key, "42", "1234", "v1.installation")
+	defer ts.Close()
+
+	credentials.VolumePath, _ = ioutil.TempDir("", "")
+	writeSecret(t, "app", map[string]string{
+		GitHubAppIDKey:             "42",
+		GitHubAppInstallationIDKey: "1234",
+		GitHubAppPrivateKeyKey:     string(pemKey),
+		GitHubAppAPIURLKey:         ts.URL,
+	})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags(fs)
+	if err := fs.Parse([]string{"-github-app-git=app=https://github.com"}); err != nil {
+		t.Fatalf("flag.CommandLine.Parse() = %v", err)
+	}
+
+	os.Setenv("HOME", credentials.VolumePath)
+	if err := NewBuilder().Write(); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(credentials.VolumePath, ".gitconfig"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(.gitconfig) = %v", err)
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:v1.installation"))
+	expectedGitConfig := `[credential]
+	helper = store
+[http "https://github.com"]
+	extraHeader = Authorization: Basic ` + auth + "\n"
+	if string(b) != expectedGitConfig {
+		t.Errorf("got: %v, wanted: %v", string(b), expectedGitConfig)
+	}
+}
+
+func TestGitHubAppWrongKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(other)})
+	ts := fakeGitHub(t, key, "42", "1234", "v1.installation")
+	defer ts.Close()
+
+	credentials.VolumePath, _ = ioutil.TempDir("", "")
+	writeSecret(t, "app", map[string]string{
+		GitHubAppIDKey:             "42",
+		GitHubAppInstallationIDKey: "1234",
+		GitHubAppPrivateKeyKey:     string(pemKey),
+		GitHubAppAPIURLKey:         ts.URL + "/",
+	})
+
+	cfg := tokenGitConfig{entries: make(map[string]tokenEntry), githubApp: true}
+	if err := cfg.Set("app=https://github.com"); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	os.Setenv("HOME", credentials.VolumePath)
+	if err := cfg.Write(); err == nil {
+		t.Error("Write(); got success, wanted error.")
+	}
+}
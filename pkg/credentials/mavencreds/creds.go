@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mavencreds
+
+import (
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	annotationPrefix = "build.knative.dev/maven-"
+	basicAuthFlag    = "basic-maven"
+)
+
+var config *credentials.BasicAuthFlag
+
+func flags(fs *flag.FlagSet) {
+	config = credentials.NewBasicAuthFlag()
+	fs.Var(config, basicAuthFlag, "List of secret=server-id pairs.")
+}
+
+func init() {
+	flags(flag.CommandLine)
+	credentials.Register("maven", NewBuilder())
+}
+
+// settings is the subset of Maven's settings.xml that holds credentials.
+type settings struct {
+	XMLName xml.Name `xml:"settings"`
+	Servers []server `xml:"servers>server"`
+}
+
+type server struct {
+	ID       string `xml:"id"`
+	Username string `xml:"username"`
+	Password string `xml:"password"`
+}
+
+type mavenBuilder struct{}
+
+// NewBuilder returns a new builder for Maven repository credentials. The
+// credentials of basic-auth Secrets annotated with build.knative.dev/maven-*
+// are written to ~/.m2/settings.xml for the server IDs of the annotations,
+// which match the IDs of repositories in a project's pom.xml.
+func NewBuilder() credentials.Builder { return &mavenBuilder{} }
+
+// MatchingAnnotations implements credentials.Builder.
+func (*mavenBuilder) MatchingAnnotations(secret *corev1.Secret) []string {
+	return credentials.MatchingBasicAuth(secret, annotationPrefix, basicAuthFlag)
+}
+
+func (*mavenBuilder) Write() error {
+	if len(config.Entries) == 0 {
+		return nil
+	}
+	s := settings{}
+	for _, id := range config.Targets() {
+		e := config.Entries[id]
+		s.Servers = append(s.Servers, server{ID: id, Username: e.Username, Password: e.Password})
+	}
+	content, err := xml.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	m2 := filepath.Join(os.Getenv("HOME"), ".m2")
+	if err := os.MkdirAll(m2, os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(m2, "settings.xml"), append([]byte(xml.Header), append(content, '\n')...), 0600)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mavencreds
+
+import (
+	"testing"
+
+	"github.com/knative/build/pkg/credentials/credentialstest"
+)
+
+func TestFlagHandling(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "foo", "bar", "b<a>z&")
+	credentialstest.WriteBasicAuth(t, "snapshots", "deployer", "secret")
+
+	if err := credentialstest.Write(t, flags, NewBuilder(),
+		"-basic-maven=snapshots=snapshots",
+		"-basic-maven=foo=releases",
+	); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	credentialstest.CheckFile(t, ".m2/settings.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<settings>
+  <servers>
+    <server>
+      <id>releases</id>
+      <username>bar</username>
+      <password>b&lt;a&gt;z&amp;</password>
+    </server>
+    <server>
+      <id>snapshots</id>
+      <username>deployer</username>
+      <password>secret</password>
+    </server>
+  </servers>
+</settings>
+`)
+}
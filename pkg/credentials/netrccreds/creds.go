@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrccreds
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	annotationPrefix = "build.knative.dev/netrc-"
+	basicAuthFlag    = "basic-netrc"
+)
+
+var config *credentials.BasicAuthFlag
+
+func flags(fs *flag.FlagSet) {
+	config = credentials.NewBasicAuthFlag()
+	fs.Var(config, basicAuthFlag, "List of secret=host pairs.")
+}
+
+func init() {
+	flags(flag.CommandLine)
+	credentials.Register("netrc", NewBuilder())
+}
+
+type netrcBuilder struct{}
+
+// NewBuilder returns a new builder for .netrc credentials, which are used
+// by tools such as curl and pip. The credentials of basic-auth Secrets
+// annotated with build.knative.dev/netrc-* are written to ~/.netrc for the
+// hosts of the annotations.
+func NewBuilder() credentials.Builder { return &netrcBuilder{} }
+
+// MatchingAnnotations implements credentials.Builder.
+func (*netrcBuilder) MatchingAnnotations(secret *corev1.Secret) []string {
+	return credentials.MatchingBasicAuth(secret, annotationPrefix, basicAuthFlag)
+}
+
+func (*netrcBuilder) Write() error {
+	if len(config.Entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, host := range config.Targets() {
+		e := config.Entries[host]
+		if strings.ContainsAny(e.Username+e.Password, " \t\n") {
+			return fmt.Errorf("credentials of secret %v contain whitespace, which .netrc doesn't support", e.Secret)
+		}
+		if err := e.CheckControlCharacters(".netrc"); err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "machine %s\nlogin %s\npassword %s\n", machine(host), e.Username, e.Password)
+	}
+	netrc := filepath.Join(os.Getenv("HOME"), ".netrc")
+	return ioutil.WriteFile(netrc, buf.Bytes(), 0600)
+}
+
+// machine returns the host named by an annotation, which may be a URL such
+// as https://example.com/.
+func machine(host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return strings.SplitN(host, "/", 2)[0]
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netrccreds
+
+import (
+	"testing"
+
+	"github.com/knative/build/pkg/credentials/credentialstest"
+)
+
+func TestFlagHandling(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "foo", "bar", "baz")
+	credentialstest.WriteBasicAuth(t, "artifacts", "deployer", "secret")
+
+	if err := credentialstest.Write(t, flags, NewBuilder(),
+		"-basic-netrc=foo=https://example.com/",
+		"-basic-netrc=artifacts=artifacts.example.com",
+	); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	credentialstest.CheckFile(t, ".netrc", `machine artifacts.example.com
+login deployer
+password secret
+machine example.com
+login bar
+password baz
+`)
+}
+
+func TestWhitespace(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "foo", "bar", "b a z")
+
+	if err := credentialstest.Write(t, flags, NewBuilder(), "-basic-netrc=foo=example.com"); err == nil {
+		t.Error("Write() = nil, wanted error")
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmcreds
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	annotationPrefix = "build.knative.dev/npm-"
+	basicAuthFlag    = "basic-npm"
+)
+
+var config *credentials.BasicAuthFlag
+
+func flags(fs *flag.FlagSet) {
+	config = credentials.NewBasicAuthFlag()
+	fs.Var(config, basicAuthFlag, "List of secret=url pairs.")
+}
+
+func init() {
+	flags(flag.CommandLine)
+	credentials.Register("npm", NewBuilder())
+}
+
+type npmrcBuilder struct{}
+
+// NewBuilder returns a new builder for npm registry credentials. The
+// credentials of basic-auth Secrets annotated with build.knative.dev/npm-*
+// are written to ~/.npmrc for the registry URLs of the annotations. A
+// Secret without a username holds an auth token in its password.
+func NewBuilder() credentials.Builder { return &npmrcBuilder{} }
+
+// MatchingAnnotations implements credentials.Builder.
+func (*npmrcBuilder) MatchingAnnotations(secret *corev1.Secret) []string {
+	return credentials.MatchingBasicAuth(secret, annotationPrefix, basicAuthFlag)
+}
+
+func (*npmrcBuilder) Write() error {
+	if len(config.Entries) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, registry := range config.Targets() {
+		prefix, err := registryPrefix(registry)
+		if err != nil {
+			return err
+		}
+		e := config.Entries[registry]
+		if e.Username == "" {
+			lines = append(lines, fmt.Sprintf("%s:_authToken=%s", prefix, e.Password))
+		} else {
+			auth := base64.StdEncoding.EncodeToString([]byte(e.Username + ":" + e.Password))
+			lines = append(lines, fmt.Sprintf("%s:_auth=%s", prefix, auth))
+		}
+		lines = append(lines, fmt.Sprintf("%s:always-auth=true", prefix))
+	}
+	npmrc := filepath.Join(os.Getenv("HOME"), ".npmrc")
+	return ioutil.WriteFile(npmrc, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// registryPrefix returns the scheme-less prefix that npm uses to key the
+// settings of the registry, such as //registry.npmjs.org/.
+func registryPrefix(registry string) (string, error) {
+	if !strings.Contains(registry, "://") {
+		registry = "https://" + registry
+	}
+	u, err := url.Parse(registry)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid npm registry URL %q", registry)
+	}
+	return "//" + u.Host + strings.TrimSuffix(u.Path, "/") + "/", nil
+}
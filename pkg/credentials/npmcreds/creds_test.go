@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmcreds
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/knative/build/pkg/credentials/credentialstest"
+)
+
+func TestFlagHandling(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "foo", "bar", "baz")
+	credentialstest.WriteBasicAuth(t, "token", "", "npm_abc123")
+
+	if err := credentialstest.Write(t, flags, NewBuilder(),
+		"-basic-npm=foo=https://npm.example.com/repository/npm",
+		"-basic-npm=token=https://registry.npmjs.org/",
+	); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	// Note: "_auth" is base64(username + ":" + password)
+	credentialstest.CheckFile(t, ".npmrc", `//npm.example.com/repository/npm/:_auth=YmFyOmJheg==
+//npm.example.com/repository/npm/:always-auth=true
+//registry.npmjs.org/:_authToken=npm_abc123
+//registry.npmjs.org/:always-auth=true
+`)
+}
+
+func TestFlagHandlingURLCollision(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "foo", "bar", "baz")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags(fs)
+	err := fs.Parse([]string{
+		"-basic-npm=foo=https://registry.npmjs.org/",
+		"-basic-npm=foo=https://registry.npmjs.org/",
+	})
+	if err == nil {
+		t.Error("flag.CommandLine.Parse() = nil, wanted error")
+	}
+}
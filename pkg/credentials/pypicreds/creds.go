@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pypicreds
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	annotationPrefix = "build.knative.dev/pypi-"
+	basicAuthFlag    = "basic-pypi"
+)
+
+var config *credentials.BasicAuthFlag
+
+func flags(fs *flag.FlagSet) {
+	config = credentials.NewBasicAuthFlag()
+	fs.Var(config, basicAuthFlag, "List of secret=url pairs.")
+}
+
+func init() {
+	flags(flag.CommandLine)
+	credentials.Register("pypi", NewBuilder())
+}
+
+type pypircBuilder struct{}
+
+// NewBuilder returns a new builder for Python package index credentials.
+// The credentials of basic-auth Secrets annotated with
+// build.knative.dev/pypi-* are written to ~/.pypirc for the repository URLs
+// of the annotations. Each repository is named after its host, except for
+// those of PyPI and TestPyPI, which are named pypi and testpypi as tools
+// such as twine expect.
+func NewBuilder() credentials.Builder { return &pypircBuilder{} }
+
+// MatchingAnnotations implements credentials.Builder.
+func (*pypircBuilder) MatchingAnnotations(secret *corev1.Secret) []string {
+	return credentials.MatchingBasicAuth(secret, annotationPrefix, basicAuthFlag)
+}
+
+func (*pypircBuilder) Write() error {
+	if len(config.Entries) == 0 {
+		return nil
+	}
+	var servers, sections bytes.Buffer
+	names := map[string]string{}
+	for _, repository := range config.Targets() {
+		name, err := repositoryName(repository)
+		if err != nil {
+			return err
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("Repositories %v and %v are both named %v", other, repository, name)
+		}
+		names[name] = repository
+
+		e := config.Entries[repository]
+		if err := e.CheckControlCharacters(".pypirc"); err != nil {
+			return err
+		}
+		fmt.Fprintf(&servers, "    %s\n", name)
+		fmt.Fprintf(&sections, "\n[%s]\nrepository = %s\nusername = %s\npassword = %s\n", name, repository, e.Username, e.Password)
+	}
+	content := "[distutils]\nindex-servers =\n" + servers.String() + sections.String()
+	pypirc := filepath.Join(os.Getenv("HOME"), ".pypirc")
+	return ioutil.WriteFile(pypirc, []byte(content), 0600)
+}
+
+// repositoryName returns the name of the repository's section in .pypirc.
+func repositoryName(repository string) (string, error) {
+	u, err := url.Parse(repository)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid repository URL %q", repository)
+	}
+	switch u.Host {
+	case "upload.pypi.org", "pypi.org":
+		return "pypi", nil
+	case "test.pypi.org":
+		return "testpypi", nil
+	default:
+		return u.Host, nil
+	}
+}
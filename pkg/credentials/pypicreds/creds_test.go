@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pypicreds
+
+import (
+	"testing"
+
+	"github.com/knative/build/pkg/credentials/credentialstest"
+)
+
+func TestFlagHandling(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "pypi", "__token__", "pypi-abc123")
+	credentialstest.WriteBasicAuth(t, "internal", "bar", "baz")
+
+	if err := credentialstest.Write(t, flags, NewBuilder(),
+		"-basic-pypi=pypi=https://upload.pypi.org/legacy/",
+		"-basic-pypi=internal=https://pypi.example.com/simple/",
+	); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	credentialstest.CheckFile(t, ".pypirc", `[distutils]
+index-servers =
+    pypi.example.com
+    pypi
+
+[pypi.example.com]
+repository = https://pypi.example.com/simple/
+username = bar
+password = baz
+
+[pypi]
+repository = https://upload.pypi.org/legacy/
+username = __token__
+password = pypi-abc123
+`)
+}
+
+func TestNameCollision(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteBasicAuth(t, "foo", "bar", "baz")
+
+	if err := credentialstest.Write(t, flags, NewBuilder(),
+		"-basic-pypi=foo=https://pypi.example.com/a/",
+		"-basic-pypi=foo=https://pypi.example.com/b/",
+	); err == nil {
+		t.Error("Write() = nil, wanted error")
+	}
+}
+
+func TestControlCharacters(t *testing.T) {
+	for _, password := range []string{"baz\n[evil]\nrepository = https://evil.example.com", "baz\r", "b\x00az"} {
+		credentialstest.Setup(t)
+		credentialstest.WriteBasicAuth(t, "foo", "bar", password)
+
+		if err := credentialstest.Write(t, flags, NewBuilder(), "-basic-pypi=foo=https://pypi.example.com/"); err == nil {
+			t.Errorf("Write() with password %q = nil, wanted error", password)
+		}
+	}
+}
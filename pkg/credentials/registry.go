@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Builder{}
+)
+
+// Register makes a credential builder available, under a unique name, to
+// creds-init and to the controller that passes it flags for the Secrets of
+// a build's service account. Packages that implement builders call it from
+// their init functions.
+func Register(name string, b Builder) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("credential builder %q is registered twice", name))
+	}
+	registry[name] = b
+}
+
+// Builders returns the registered credential builders, ordered by name.
+func Builders() []Builder {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	builders := make([]Builder, 0, len(names))
+	for _, name := range names {
+		builders = append(builders, registry[name])
+	}
+	return builders
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3creds
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/knative/build/pkg/credentials"
+)
+
+const (
+	// SecretTypeS3 is the type of Secrets that hold credentials for
+	// S3-compatible object storage, such as Amazon S3 or MinIO.
+	SecretTypeS3 corev1.SecretType = "build.knative.dev/s3"
+
+	// AccessKeyIDKey is the key of the access key ID in an S3 Secret.
+	AccessKeyIDKey = "accessKeyID"
+	// SecretAccessKeyKey is the key of the secret access key in an S3 Secret.
+	SecretAccessKeyKey = "secretAccessKey"
+	// SessionTokenKey is the key of the optional session token in an S3
+	// Secret.
+	SessionTokenKey = "sessionToken"
+
+	annotationPrefix = "build.knative.dev/s3-"
+	s3Flag           = "s3"
+)
+
+var config s3Config
+
+func flags(fs *flag.FlagSet) {
+	config = s3Config{Endpoints: make(map[string]Credentials)}
+	fs.Var(&config, s3Flag, "List of secret=endpoint pairs, or secrets used for any endpoint.")
+}
+
+func init() {
+	flags(flag.CommandLine)
+	credentials.Register("s3", NewBuilder())
+}
+
+// Credentials are the keys used to sign requests to an S3 endpoint.
+type Credentials struct {
+	Secret          string `json:"-"`
+	AccessKeyID     string `json:"accessKeyID"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken,omitempty"`
+}
+
+// As the flag is read, this status is populated.
+// s3Config implements flag.Value
+type s3Config struct {
+	Default   *Credentials           `json:"default,omitempty"`
+	Endpoints map[string]Credentials `json:"endpoints,omitempty"`
+}
+
+func (c *s3Config) String() string {
+	if c == nil {
+		// According to flag.Value this can happen.
+		return ""
+	}
+	var entries []string
+	if c.Default != nil {
+		entries = append(entries, c.Default.Secret)
+	}
+	for k, v := range c.Endpoints {
+		entries = append(entries, fmt.Sprintf("%s=%s", v.Secret, k))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (c *s3Config) Set(value string) error {
+	parts := strings.Split(value, "=")
+	if len(parts) > 2 {
+		return fmt.Errorf("Expect entries of the form secret or secret=endpoint, got: %v", value)
+	}
+	creds, err := newCredentials(parts[0])
+	if err != nil {
+		return err
+	}
+	if len(parts) == 1 {
+		if c.Default != nil {
+			return fmt.Errorf("Multiple entries for any endpoint: %v and %v", c.Default.Secret, parts[0])
+		}
+		c.Default = creds
+		return nil
+	}
+
+	endpoint := endpointHost(parts[1])
+	if _, ok := c.Endpoints[endpoint]; ok {
+		return fmt.Errorf("Multiple entries for endpoint: %v", endpoint)
+	}
+	c.Endpoints[endpoint] = *creds
+	return nil
+}
+
+func newCredentials(secret string) (*Credentials, error) {
+	secretPath := credentials.VolumeName(secret)
+
+	id, err := ioutil.ReadFile(filepath.Join(secretPath, AccessKeyIDKey))
+	if err != nil {
+		return nil, err
+	}
+	key, err := ioutil.ReadFile(filepath.Join(secretPath, SecretAccessKeyKey))
+	if err != nil {
+		return nil, err
+	}
+	token, err := ioutil.ReadFile(filepath.Join(secretPath, SessionTokenKey))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &Credentials{
+		Secret:          secret,
+		AccessKeyID:     strings.TrimSpace(string(id)),
+		SecretAccessKey: strings.TrimSpace(string(key)),
+		SessionToken:    strings.TrimSpace(string(token)),
+	}, nil
+}
+
+type s3Builder struct{}
+
+// NewBuilder returns a new builder for S3 credentials.
+func NewBuilder() credentials.Builder { return &s3Builder{} }
+
+// MatchingAnnotations implements credentials.Builder. S3 Secrets without
+// annotations apply to any endpoint.
+func (*s3Builder) MatchingAnnotations(secret *corev1.Secret) []string {
+	var flags []string
+	if secret.Type != SecretTypeS3 {
+		return flags
+	}
+	for _, v := range credentials.SortAnnotations(secret.Annotations, annotationPrefix) {
+		flags = append(flags, fmt.Sprintf("-%s=%s=%s", s3Flag, secret.Name, v))
+	}
+	if len(flags) == 0 {
+		flags = append(flags, fmt.Sprintf("-%s=%s", s3Flag, secret.Name))
+	}
+	return flags
+}
+
+func (*s3Builder) Write() error {
+	if config.Default == nil && len(config.Endpoints) == 0 {
+		return nil
+	}
+	path := ConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	content, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+// ConfigPath returns the path of the S3 credentials file that the builder
+// writes.
+func ConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".s3", "credentials.json")
+}
+
+// Lookup returns the credentials for the endpoint in the S3 credentials
+// file at path, as written by the builder, falling back to those for any
+// endpoint. It returns ok false if the file doesn't exist or has no
+// credentials that apply.
+func Lookup(path, endpoint string) (creds Credentials, ok bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Credentials{}, false, nil
+	} else if err != nil {
+		return Credentials{}, false, err
+	}
+	c := s3Config{}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Credentials{}, false, err
+	}
+	if creds, ok := c.Endpoints[endpointHost(endpoint)]; ok {
+		return creds, true, nil
+	}
+	if c.Default != nil {
+		return *c.Default, true, nil
+	}
+	return Credentials{}, false, nil
+}
+
+// endpointHost returns the host of an endpoint, which may be a URL such as
+// https://minio.example.com:9000/.
+func endpointHost(endpoint string) string {
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	return strings.SplitN(endpoint, "/", 2)[0]
+}
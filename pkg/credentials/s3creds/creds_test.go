@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3creds
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/build/pkg/credentials/credentialstest"
+)
+
+func TestFlagHandling(t *testing.T) {
+	credentialstest.Setup(t)
+	credentialstest.WriteSecret(t, "minio", map[string]string{
+		AccessKeyIDKey:     "minio-id",
+		SecretAccessKeyKey: "minio-key\n",
+	})
+	credentialstest.WriteSecret(t, "aws", map[string]string{
+		AccessKeyIDKey:     "aws-id",
+		SecretAccessKeyKey: "aws-key",
+		SessionTokenKey:    "aws-token",
+	})
+
+	if err := credentialstest.Write(t, flags, NewBuilder(),
+		"-s3=minio=https://minio.example.com:9000/",
+		"-s3=aws",
+	); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	for _, c := range []struct {
+		endpoint string
+		want     Credentials
+	}{{
+		endpoint: "minio.example.com:9000",
+		want:     Credentials{AccessKeyID: "minio-id", SecretAccessKey: "minio-key"},
+	}, {
+		endpoint: "http://minio.example.com:9000",
+		want:     Credentials{AccessKeyID: "minio-id", SecretAccessKey: "minio-key"},
+	}, {
+		endpoint: "",
+		want:     Credentials{AccessKeyID: "aws-id", SecretAccessKey: "aws-key", SessionToken: "aws-token"},
+	}} {
+		got, ok, err := Lookup(ConfigPath(), c.endpoint)
+		if err != nil || !ok {
+			t.Fatalf("Lookup(%q) = %v, %v", c.endpoint, ok, err)
+		}
+		if d := cmp.Diff(c.want, got); d != "" {
+			t.Errorf("Lookup(%q) diff -want, +got: %v", c.endpoint, d)
+		}
+	}
+}
+
+func TestFlagHandlingCollision(t *testing.T) {
+	credentialstest.Setup(t)
+	for _, name := range []string{"foo", "bar"} {
+		credentialstest.WriteSecret(t, name, map[string]string{
+			AccessKeyIDKey:     "id",
+			SecretAccessKeyKey: "key",
+		})
+	}
+
+	for _, args := range [][]string{
+		{"-s3=foo=minio.example.com", "-s3=bar=https://minio.example.com"},
+		{"-s3=foo", "-s3=bar"},
+		{"-s3=foo=a=b"},
+		{"-s3=missing"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags(fs)
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("flag.CommandLine.Parse(%v) = nil, wanted error", args)
+		}
+	}
+}
+
+func TestLookupMissing(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	if _, ok, err := Lookup(filepath.Join(dir, "credentials.json"), "minio.example.com"); ok || err != nil {
+		t.Errorf("Lookup() = %v, %v; wanted false, nil", ok, err)
+	}
+
+	credentialstest.Setup(t)
+	credentialstest.WriteSecret(t, "minio", map[string]string{
+		AccessKeyIDKey:     "id",
+		SecretAccessKeyKey: "key",
+	})
+	if err := credentialstest.Write(t, flags, NewBuilder(), "-s3=minio=minio.example.com"); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if _, ok, err := Lookup(ConfigPath(), "s3.amazonaws.com"); ok || err != nil {
+		t.Errorf("Lookup() = %v, %v; wanted false, nil", ok, err)
+	}
+}
+
+func TestMatchingAnnotations(t *testing.T) {
+	tests := []struct {
+		secret *corev1.Secret
+		want   []string
+	}{{
+		secret: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "minio",
+				Annotations: map[string]string{
+					"build.knative.dev/s3-0": "https://minio.example.com",
+					"build.knative.dev/s3-1": "https://minio.example.org",
+				},
+			},
+			Type: SecretTypeS3,
+		},
+		want: []string{
+			"-s3=minio=https://minio.example.com",
+			"-s3=minio=https://minio.example.org",
+		},
+	}, {
+		secret: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "aws"},
+			Type:       SecretTypeS3,
+		},
+		want: []string{"-s3=aws"},
+	}, {
+		secret: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "git",
+				Annotations: map[string]string{
+					"build.knative.dev/s3-0": "https://minio.example.com",
+				},
+			},
+			Type: corev1.SecretTypeBasicAuth,
+		},
+	}}
+
+	for _, ts := range tests {
+		gotFlag := NewBuilder().MatchingAnnotations(ts.secret)
+		if d := cmp.Diff(ts.want, gotFlag); d != "" {
+			t.Errorf("MatchingAnnotations(%s) diff -want, +got: %v", ts.secret.Name, d)
+		}
+	}
+}
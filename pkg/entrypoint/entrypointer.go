@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package entrypoint orders the steps of a build that run as regular
+// containers: each step waits for a file written by the step before it,
+// decides from the outcomes recorded there whether to run its command, and
+// then writes a file for the step after it.
+package entrypoint
+
+import (
+	"fmt"
+)
+
+// ErrSkipped is returned when a step's command doesn't run.
+var ErrSkipped = fmt.Errorf("skipped")
+
+// SkippedMessage is the termination message of a step that was skipped.
+const SkippedMessage = "build.knative.dev/skipped"
+
+// Outcome is the outcome of a step.
+type Outcome string
+
+const (
+	// Succeeded indicates that the step's command succeeded.
+	Succeeded Outcome = "Succeeded"
+	// Failed indicates that the step's command failed.
+	Failed Outcome = "Failed"
+	// Skipped indicates that the step's command didn't run.
+	Skipped Outcome = "Skipped"
+)
+
+// Outcomes records the outcome of each step that has finished, by name.
+type Outcomes map[string]Outcome
+
+// failed returns true if any of the steps failed.
+func (o Outcomes) failed() bool {
+	for _, outcome := range o {
+		if outcome == Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// RunPolicy controls whether a step runs after an earlier step failed.
+type RunPolicy string
+
+const (
+	// RunOnSuccess runs the step only if no earlier step failed.
+	RunOnSuccess RunPolicy = "OnSuccess"
+	// RunAlways runs the step whatever the outcomes of earlier steps.
+	RunAlways RunPolicy = "Always"
+)
+
+// Waiter waits for the file written by the previous step.
+type Waiter interface {
+	// Wait blocks until the file exists, and returns the outcomes it
+	// records.
+	Wait(file string) (Outcomes, error)
+}
+
+// Runner runs a step's command.
+type Runner interface {
+	Run(args ...string) error
+}
+
+// PostWriter writes the file that the next step waits for.
+type PostWriter interface {
+	// Write writes the file, recording the outcomes of the steps so far.
+	Write(file string, outcomes Outcomes) error
+}
+
+// Entrypointer runs a step's command once the previous step has finished.
+type Entrypointer struct {
+	// Name is the name of the step.
+	Name string
+
+	// Entrypoint is the command to run.
+	Entrypoint string
+	// Args are the arguments passed to Entrypoint.
+	Args []string
+
+	// WaitFile is the file to wait for before running the command; if
+	// empty, the command runs immediately.
+	WaitFile string
+	// PostFile is the file to write once the command finishes.
+	PostFile string
+
+	// RunPolicy controls whether the command runs after an earlier step
+	// failed; it defaults to RunOnSuccess.
+	RunPolicy RunPolicy
+	// WhenStep, if set, names an earlier step; the command runs only if
+	// that step's outcome is WhenOutcome, and RunPolicy is ignored.
+	WhenStep    string
+	WhenOutcome Outcome
+
+	Waiter     Waiter
+	Runner     Runner
+	PostWriter PostWriter
+}
+
+// Go waits for the previous step, runs the command if the step should run,
+// and notifies the next step. It returns the error of the command, or
+// ErrSkipped if the command didn't run.
+func (e Entrypointer) Go() error {
+	outcomes := Outcomes{}
+	if e.WaitFile != "" {
+		var err error
+		if outcomes, err = e.Waiter.Wait(e.WaitFile); err != nil {
+			return err
+		}
+	}
+
+	err := ErrSkipped
+	outcome := Skipped
+	if e.shouldRun(outcomes) {
+		err = e.Runner.Run(append([]string{e.Entrypoint}, e.Args...)...)
+		outcome = Succeeded
+		if err != nil {
+			outcome = Failed
+		}
+	}
+	outcomes[e.Name] = outcome
+
+	if e.PostFile != "" {
+		if werr := e.PostWriter.Write(e.PostFile, outcomes); werr != nil && err == nil {
+			return werr
+		}
+	}
+	return err
+}
+
+// shouldRun returns true if the step's command should run, given the
+// outcomes of the earlier steps.
+func (e Entrypointer) shouldRun(outcomes Outcomes) bool {
+	if e.WhenStep != "" {
+		return outcomes[e.WhenStep] == e.WhenOutcome
+	}
+	if e.RunPolicy == RunAlways {
+		return true
+	}
+	return !outcomes.failed()
+}
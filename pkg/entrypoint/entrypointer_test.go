@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entrypoint
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeWaiter struct {
+	waited   []string
+	outcomes Outcomes
+}
+
+func (f *fakeWaiter) Wait(file string) (Outcomes, error) {
+	f.waited = append(f.waited, file)
+	outcomes := Outcomes{}
+	for name, outcome := range f.outcomes {
+		outcomes[name] = outcome
+	}
+	return outcomes, nil
+}
+
+type fakeRunner struct {
+	args []string
+	err  error
+}
+
+func (f *fakeRunner) Run(args ...string) error {
+	f.args = args
+	return f.err
+}
+
+type fakePostWriter struct {
+	files    []string
+	outcomes Outcomes
+}
+
+func (f *fakePostWriter) Write(file string, outcomes Outcomes) error {
+	f.files = append(f.files, file)
+	f.outcomes = outcomes
+	return nil
+}
+
+func TestEntrypointer(t *testing.T) {
+	errFailed := errors.New("failed")
+	for _, c := range []struct {
+		desc               string
+		waitFile, postFile string
+		runPolicy          RunPolicy
+		whenStep           string
+		whenOutcome        Outcome
+		previous           Outcomes
+		runErr             error
+		wantWaited         []string
+		wantArgs           []string
+		wantPosts          []string
+		wantOutcomes       Outcomes
+		wantErr            error
+	}{{
+		desc:         "first step",
+		postFile:     "/builder/tools/0",
+		wantArgs:     []string{"echo", "hello"},
+		wantPosts:    []string{"/builder/tools/0"},
+		wantOutcomes: Outcomes{"step": Succeeded},
+	}, {
+		desc:         "later step",
+		waitFile:     "/builder/tools/0",
+		postFile:     "/builder/tools/1",
+		previous:     Outcomes{"first": Succeeded},
+		wantWaited:   []string{"/builder/tools/0"},
+		wantArgs:     []string{"echo", "hello"},
+		wantPosts:    []string{"/builder/tools/1"},
+		wantOutcomes: Outcomes{"first": Succeeded, "step": Succeeded},
+	}, {
+		desc:       "last step",
+		waitFile:   "/builder/tools/1",
+		wantWaited: []string{"/builder/tools/1"},
+		wantArgs:   []string{"echo", "hello"},
+	}, {
+		desc:         "step fails",
+		waitFile:     "/builder/tools/0",
+		postFile:     "/builder/tools/1",
+		runErr:       errFailed,
+		wantWaited:   []string{"/builder/tools/0"},
+		wantArgs:     []string{"echo", "hello"},
+		wantPosts:    []string{"/builder/tools/1"},
+		wantOutcomes: Outcomes{"step": Failed},
+		wantErr:      errFailed,
+	}, {
+		desc:         "earlier step failed",
+		waitFile:     "/builder/tools/1",
+		postFile:     "/builder/tools/2",
+		previous:     Outcomes{"first": Failed, "second": Skipped},
+		wantWaited:   []string{"/builder/tools/1"},
+		wantPosts:    []string{"/builder/tools/2"},
+		wantOutcomes: Outcomes{"first": Failed, "second": Skipped, "step": Skipped},
+		wantErr:      ErrSkipped,
+	}, {
+		desc:         "always run after failure",
+		waitFile:     "/builder/tools/0",
+		runPolicy:    RunAlways,
+		previous:     Outcomes{"first": Failed},
+		wantWaited:   []string{"/builder/tools/0"},
+		postFile:     "/builder/tools/1",
+		wantArgs:     []string{"echo", "hello"},
+		wantPosts:    []string{"/builder/tools/1"},
+		wantOutcomes: Outcomes{"first": Failed, "step": Succeeded},
+	}, {
+		desc:        "when step failed",
+		waitFile:    "/builder/tools/1",
+		whenStep:    "first",
+		whenOutcome: Failed,
+		previous:    Outcomes{"first": Failed, "second": Skipped},
+		wantWaited:  []string{"/builder/tools/1"},
+		wantArgs:    []string{"echo", "hello"},
+	}, {
+		desc:        "when step failed, but it succeeded",
+		waitFile:    "/builder/tools/1",
+		whenStep:    "first",
+		whenOutcome: Failed,
+		previous:    Outcomes{"first": Succeeded, "second": Failed},
+		wantWaited:  []string{"/builder/tools/1"},
+		wantErr:     ErrSkipped,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			w := &fakeWaiter{outcomes: c.previous}
+			r := &fakeRunner{err: c.runErr}
+			pw := &fakePostWriter{}
+			err := Entrypointer{
+				Name:        "step",
+				Entrypoint:  "echo",
+				Args:        []string{"hello"},
+				WaitFile:    c.waitFile,
+				PostFile:    c.postFile,
+				RunPolicy:   c.runPolicy,
+				WhenStep:    c.whenStep,
+				WhenOutcome: c.whenOutcome,
+				Waiter:      w,
+				Runner:      r,
+				PostWriter:  pw,
+			}.Go()
+			if err != c.wantErr {
+				t.Errorf("Go() = %v, want %v", err, c.wantErr)
+			}
+			if d := cmp.Diff(c.wantWaited, w.waited); d != "" {
+				t.Errorf("Diff waited:\n%s", d)
+			}
+			if d := cmp.Diff(c.wantArgs, r.args); d != "" {
+				t.Errorf("Diff args:\n%s", d)
+			}
+			if d := cmp.Diff(c.wantPosts, pw.files); d != "" {
+				t.Errorf("Diff post files:\n%s", d)
+			}
+			if d := cmp.Diff(c.wantOutcomes, pw.outcomes); d != "" {
+				t.Errorf("Diff outcomes:\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package extract writes fetched files into a destination directory,
+// refusing to write outside of it, either directly or through a symlink
+// written before them.
+package extract
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Target returns the path under dest of a fetched file or archive entry,
+// refusing names that would be written outside of dest.
+func Target(dest, name string) (string, error) {
+	p := filepath.Join(dest, name)
+	if p != filepath.Clean(dest) && !strings.HasPrefix(p, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%q is outside of the destination", name)
+	}
+	return p, nil
+}
+
+// MakeParents creates the directories between dest and p, returning an
+// error if any of them is a symlink, which could otherwise be used to write
+// outside of dest.
+func MakeParents(dest, p string) error {
+	missing, err := checkParents(dest, p)
+	if err != nil || !missing {
+		return err
+	}
+	return os.MkdirAll(filepath.Dir(p), 0755)
+}
+
+// CheckParents returns an error if any of the existing directories between
+// dest and p is a symlink, which could otherwise be used to remove or link
+// to files outside of dest.
+func CheckParents(dest, p string) error {
+	_, err := checkParents(dest, p)
+	return err
+}
+
+// checkParents checks the directories between dest and p, and returns
+// whether any of them is missing.
+func checkParents(dest, p string) (bool, error) {
+	if filepath.Clean(p) == filepath.Clean(dest) {
+		return false, nil
+	}
+	rel, err := filepath.Rel(dest, filepath.Dir(p))
+	if err != nil || rel == "." {
+		return false, err
+	}
+	dir := dest
+	for _, elem := range strings.Split(rel, string(os.PathSeparator)) {
+		dir = filepath.Join(dir, elem)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return false, fmt.Errorf("refusing to extract %q through symlink %q", p, dir)
+		}
+	}
+	return false, nil
+}
+
+// Remove removes whatever file, directory or symlink is at p under dest,
+// refusing to do so through a symlink.
+func Remove(dest, p string) error {
+	if err := CheckParents(dest, p); err != nil {
+		return err
+	}
+	return os.RemoveAll(p)
+}
+
+// MakeDir creates the directory p under dest, refusing to do so through a
+// symlink.
+func MakeDir(dest, p string) error {
+	if err := MakeParents(dest, p); err != nil {
+		return err
+	}
+	info, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return os.Mkdir(p, 0755)
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to extract directory %q over a file", p)
+	}
+	return nil
+}
+
+// Create creates the file at p under dest, replacing whatever file,
+// directory or symlink was there, so that a symlink isn't followed.
+func Create(dest, p string, mode os.FileMode) (*os.File, error) {
+	if err := MakeParents(dest, p); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(p); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+}
+
+// WriteFile writes the content of r to the file at p under dest, replacing
+// whatever was there.
+func WriteFile(dest, p string, r io.Reader, mode os.FileMode) error {
+	out, err := Create(dest, p, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Symlink creates a symlink to oldname at p under dest, replacing whatever
+// was there.
+func Symlink(dest, oldname, p string) error {
+	if err := MakeParents(dest, p); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(p); err != nil {
+		return err
+	}
+	return os.Symlink(oldname, p)
+}
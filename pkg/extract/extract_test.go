@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTarget(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "a/b", want: "/dest/a/b"},
+		{name: "./", want: "/dest"},
+		{name: "a/../b", want: "/dest/b"},
+		{name: "../evil", wantErr: true},
+		{name: "a/../../evil", wantErr: true},
+		{name: "/abs", want: "/dest/abs"},
+	} {
+		got, err := Target("/dest", c.name)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("Target(%q) = %q, wanted error", c.name, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("Target(%q) = %q, %v; want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	outside, err := ioutil.TempDir("", "extract-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	victim := filepath.Join(outside, "victim")
+	if err := ioutil.WriteFile(victim, []byte("safe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	if err := Symlink(dest, outside, filepath.Join(dest, "link")); err != nil {
+		t.Fatalf("Symlink() = %v", err)
+	}
+	if err := Symlink(dest, victim, filepath.Join(dest, "file")); err != nil {
+		t.Fatalf("Symlink() = %v", err)
+	}
+
+	// Nothing is written through the symlinked directory.
+	if err := WriteFile(dest, filepath.Join(dest, "link", "victim"), strings.NewReader("boom"), 0644); err == nil {
+		t.Error("WriteFile() through symlink succeeded")
+	}
+	if err := MakeDir(dest, filepath.Join(dest, "link", "dir")); err == nil {
+		t.Error("MakeDir() through symlink succeeded")
+	}
+	if err := MakeDir(dest, filepath.Join(dest, "link")); err == nil {
+		t.Error("MakeDir() over symlink succeeded")
+	}
+	// A file written over a symlink replaces it.
+	if err := WriteFile(dest, filepath.Join(dest, "file"), strings.NewReader("boom"), 0644); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	if b, err := ioutil.ReadFile(victim); err != nil || string(b) != "safe" {
+		t.Errorf("victim = %q, %v; want it untouched", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "dir")); !os.IsNotExist(err) {
+		t.Errorf("MakeDir() created a directory outside of the destination: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitverify verifies that the Git revisions that builds check out
+// are signed by trusted keys.
+package gitverify
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// UnverifiedRevisionReason is the prefix of the termination message of a
+// git-init whose revision wasn't signed by a trusted key.
+const UnverifiedRevisionReason = "UnverifiedRevision"
+
+// Type is the kind of signature that is verified.
+type Type string
+
+const (
+	// GPG verifies OpenPGP signatures against armored public keys.
+	GPG Type = "GPG"
+	// SSH verifies SSH signatures against allowed signers files.
+	SSH Type = "SSH"
+)
+
+// Signature describes a verified signature.
+type Signature struct {
+	// Signer is the user ID of the GPG key, or the principal of the SSH
+	// key, that made the signature.
+	Signer string
+	// Key is the fingerprint of the key that made the signature.
+	Key string
+}
+
+// UnverifiedError is returned when a revision isn't signed by a trusted
+// key.
+type UnverifiedError struct {
+	Object string
+	Detail string
+}
+
+func (e *UnverifiedError) Error() string {
+	return fmt.Sprintf("%s: %s is not signed by a trusted key: %s", UnverifiedRevisionReason, e.Object, e.Detail)
+}
+
+// Verify verifies the signature of the revision fetched into the
+// repository in the current directory, trusting the keys in the files in
+// keysDir. If FETCH_HEAD is an annotated tag, the tag's signature is
+// verified; otherwise that of HEAD.
+func Verify(t Type, keysDir string) (*Signature, error) {
+	keys, err := readKeys(keysDir)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir("", "git-verify")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	var args, env []string
+	switch t {
+	case GPG:
+		// Import the keys into a keyring of their own, so that no others
+		// are trusted.
+		if err := os.Chmod(tmp, 0700); err != nil {
+			return nil, err
+		}
+		env = []string{"GNUPGHOME=" + tmp}
+		for _, k := range keys {
+			c := exec.Command("gpg", "--batch", "--import")
+			c.Env = append(os.Environ(), env...)
+			c.Stdin = bytes.NewReader(k)
+			if out, err := c.CombinedOutput(); err != nil {
+				return nil, fmt.Errorf("importing GPG keys: %v\n%s", err, out)
+			}
+		}
+	case SSH:
+		signers := filepath.Join(tmp, "allowed_signers")
+		if err := ioutil.WriteFile(signers, bytes.Join(keys, []byte("\n")), 0600); err != nil {
+			return nil, err
+		}
+		args = []string{"-c", "gpg.ssh.allowedSignersFile=" + signers}
+	default:
+		return nil, fmt.Errorf("unknown signature type %q", t)
+	}
+
+	verify, object := "verify-commit", "HEAD"
+	if out, err := exec.Command("git", "cat-file", "-t", "FETCH_HEAD").Output(); err == nil && strings.TrimSpace(string(out)) == "tag" {
+		verify, object = "verify-tag", "FETCH_HEAD"
+	}
+	name, err := exec.Command("git", "rev-parse", object).Output()
+	if err != nil {
+		return nil, err
+	}
+	desc := fmt.Sprintf("%s %s", strings.TrimPrefix(verify, "verify-"), strings.TrimSpace(string(name)))
+
+	c := exec.Command("git", append(args, verify, "--raw", object)...)
+	c.Env = append(os.Environ(), env...)
+	out, err := c.CombinedOutput()
+	sig, perr := parse(t, string(out))
+	if perr != nil {
+		return nil, &UnverifiedError{Object: desc, Detail: perr.Error()}
+	}
+	if err != nil {
+		return nil, &UnverifiedError{Object: desc, Detail: err.Error()}
+	}
+	return sig, nil
+}
+
+// readKeys returns the contents of the files in dir.
+func readKeys(dir string) ([][]byte, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for _, f := range files {
+		// ConfigMap and Secret volumes hold their keys as symlinks to
+		// hidden files.
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, b)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no trusted keys in %q", dir)
+	}
+	return keys, nil
+}
+
+// parse returns the signature described by the output of git's
+// verify-commit or verify-tag with --raw.
+func parse(t Type, output string) (*Signature, error) {
+	switch t {
+	case GPG:
+		// The status lines of a good signature look like:
+		//   [GNUPG:] GOODSIG <key id> <user id>
+		//   [GNUPG:] VALIDSIG <fingerprint> ...
+		sig := &Signature{}
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.SplitN(strings.TrimPrefix(line, "[GNUPG:] "), " ", 3)
+			switch {
+			case fields[0] == "GOODSIG" && len(fields) == 3:
+				sig.Signer = fields[2]
+			case fields[0] == "VALIDSIG" && len(fields) >= 2:
+				sig.Key = fields[1]
+			case fields[0] == "NO_PUBKEY" && len(fields) >= 2:
+				return nil, fmt.Errorf("signed by unknown key %s", fields[1])
+			case fields[0] == "EXPKEYSIG", fields[0] == "REVKEYSIG", fields[0] == "EXPSIG", fields[0] == "BADSIG":
+				return nil, fmt.Errorf("%s", strings.TrimPrefix(line, "[GNUPG:] "))
+			}
+		}
+		if sig.Signer == "" || sig.Key == "" {
+			return nil, fmt.Errorf("no good signature")
+		}
+		return sig, nil
+	case SSH:
+		// The output of a good signature looks like:
+		//   Good "git" signature for <principal> with <type> key <fingerprint>
+		const good = `Good "git" signature for `
+		for _, line := range strings.Split(output, "\n") {
+			if !strings.HasPrefix(line, good) {
+				continue
+			}
+			rest := strings.TrimPrefix(line, good)
+			i := strings.LastIndex(rest, " with ")
+			j := strings.LastIndex(rest, " key ")
+			if i <= 0 || j < i {
+				continue
+			}
+			return &Signature{Signer: rest[:i], Key: rest[j+len(" key "):]}, nil
+		}
+		if strings.Contains(output, "No principal matched") {
+			return nil, fmt.Errorf("signed by a key that isn't an allowed signer")
+		}
+		return nil, fmt.Errorf("no good signature")
+	default:
+		return nil, fmt.Errorf("unknown signature type %q", t)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitverify
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		t       Type
+		output  string
+		want    *Signature
+		wantErr string
+	}{{
+		desc: "good GPG signature",
+		t:    GPG,
+		output: `[GNUPG:] NEWSIG jane@example.com
+[GNUPG:] KEY_CONSIDERED AE4B3D8C296EDAE5CF06D88BE7FD3CA11BAC6E79 0
+[GNUPG:] GOODSIG E7FD3CA11BAC6E79 Jane Doe <jane@example.com>
+[GNUPG:] VALIDSIG AE4B3D8C296EDAE5CF06D88BE7FD3CA11BAC6E79 2019-10-17 1792202431 0 4 0 22 8 00 AE4B3D8C296EDAE5CF06D88BE7FD3CA11BAC6E79
+[GNUPG:] TRUST_UNDEFINED 0 pgp
+`,
+		want: &Signature{Signer: "Jane Doe <jane@example.com>", Key: "AE4B3D8C296EDAE5CF06D88BE7FD3CA11BAC6E79"},
+	}, {
+		desc: "GPG signature by unknown key",
+		t:    GPG,
+		output: `[GNUPG:] NEWSIG jane@example.com
+[GNUPG:] ERRSIG E7FD3CA11BAC6E79 22 8 00 1792202431 9 AE4B3D8C296EDAE5CF06D88BE7FD3CA11BAC6E79
+[GNUPG:] NO_PUBKEY E7FD3CA11BAC6E79
+`,
+		wantErr: "signed by unknown key E7FD3CA11BAC6E79",
+	}, {
+		desc:    "GPG signature by expired key",
+		t:       GPG,
+		output:  "[GNUPG:] EXPKEYSIG E7FD3CA11BAC6E79 Jane Doe <jane@example.com>\n",
+		wantErr: "EXPKEYSIG E7FD3CA11BAC6E79 Jane Doe <jane@example.com>",
+	}, {
+		desc:    "unsigned for GPG",
+		t:       GPG,
+		wantErr: "no good signature",
+	}, {
+		desc:   "good SSH signature",
+		t:      SSH,
+		output: "Good \"git\" signature for jane@example.com with ED25519 key SHA256:3zYxEHbUrAHDrs5WqNEFWulGYeDKABc2liaWnG8VRFg\n",
+		want:   &Signature{Signer: "jane@example.com", Key: "SHA256:3zYxEHbUrAHDrs5WqNEFWulGYeDKABc2liaWnG8VRFg"},
+	}, {
+		desc:    "SSH signature by key that isn't allowed",
+		t:       SSH,
+		output:  "Good \"git\" signature with ED25519 key SHA256:3zYxEHbUrAHDrs5WqNEFWulGYeDKABc2liaWnG8VRFg\nNo principal matched.\n",
+		wantErr: "signed by a key that isn't an allowed signer",
+	}, {
+		desc:    "unsigned for SSH",
+		t:       SSH,
+		wantErr: "no good signature",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := parse(c.t, c.output)
+			if c.wantErr != "" {
+				if err == nil || err.Error() != c.wantErr {
+					t.Fatalf("parse() = %v, wanted %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() = %v", err)
+			}
+			if d := cmp.Diff(c.want, got); d != "" {
+				t.Errorf("parse() diff -want, +got: %v", d)
+			}
+		})
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	c := exec.Command("git", append([]string{"-c", "user.name=Jane", "-c", "user.email=jane@example.com"}, args...)...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v = %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// TestVerifySSH signs commits and tags with an SSH key, and verifies them
+// in a clone as git-init does.
+func TestVerifySSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	dir, err := ioutil.TempDir("", "git-verify")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := filepath.Join(dir, "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen = %v\n%s", err, out)
+	}
+	pub, err := ioutil.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	trusted := filepath.Join(dir, "trusted")
+	untrusted := filepath.Join(dir, "untrusted")
+	for _, d := range []string{trusted, untrusted} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("Mkdir() = %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(trusted, "jane"), []byte("jane@example.com "+string(pub)), 0644); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(untrusted, "bob"), []byte("bob@example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGUvcI6pWp7jdLvHdnT4qi3aTkQsyCt5j2cBaR1WbJ8B\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+
+	src := filepath.Join(dir, "src")
+	git(t, dir, "init", "-q", src)
+	sign := []string{"-c", "gpg.format=ssh", "-c", "user.signingkey=" + key + ".pub"}
+	git(t, src, "commit", "-q", "--allow-empty", "-m", "unsigned")
+	git(t, src, "tag", "unsigned")
+	git(t, src, append(sign, "commit", "-q", "--allow-empty", "-S", "-m", "signed")...)
+	git(t, src, "tag", "signed")
+	git(t, src, append(sign, "tag", "-s", "-m", "release", "v1", "unsigned")...)
+	fingerprint := strings.Fields(string(mustOutput(t, "ssh-keygen", "-l", "-f", key+".pub")))[1]
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() = %v", err)
+	}
+	defer os.Chdir(wd)
+
+	for _, c := range []struct {
+		revision string
+		keysDir  string
+		want     *Signature
+		wantErr  string
+	}{{
+		revision: "signed",
+		keysDir:  trusted,
+		want:     &Signature{Signer: "jane@example.com", Key: fingerprint},
+	}, {
+		revision: "v1",
+		keysDir:  trusted,
+		want:     &Signature{Signer: "jane@example.com", Key: fingerprint},
+	}, {
+		revision: "unsigned",
+		keysDir:  trusted,
+		wantErr:  "UnverifiedRevision: commit ",
+	}, {
+		revision: "signed",
+		keysDir:  untrusted,
+		wantErr:  "UnverifiedRevision: commit ",
+	}} {
+		t.Run(c.revision, func(t *testing.T) {
+			repo, err := ioutil.TempDir(dir, "repo")
+			if err != nil {
+				t.Fatalf("TempDir() = %v", err)
+			}
+			git(t, repo, "init", "-q")
+			git(t, repo, "remote", "add", "origin", src)
+			git(t, repo, "fetch", "-q", "--depth=1", "origin", c.revision)
+			git(t, repo, "reset", "-q", "--hard", "FETCH_HEAD")
+			if err := os.Chdir(repo); err != nil {
+				t.Fatalf("Chdir() = %v", err)
+			}
+
+			got, err := Verify(SSH, c.keysDir)
+			if c.wantErr != "" {
+				if _, ok := err.(*UnverifiedError); !ok || !strings.HasPrefix(err.Error(), c.wantErr) {
+					t.Fatalf("Verify() = %v, wanted *UnverifiedError %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+			if d := cmp.Diff(c.want, got); d != "" {
+				t.Errorf("Verify() diff -want, +got: %v", d)
+			}
+		})
+	}
+}
+
+func mustOutput(t *testing.T, name string, args ...string) []byte {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		t.Fatalf("%s %v = %v", name, args, err)
+	}
+	return out
+}
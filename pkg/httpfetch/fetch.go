@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpfetch fetches the HTTP source of a build: it downloads an
+// archive or file, verifies its checksum, and unpacks it into a directory.
+package httpfetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/knative/build/pkg/extract"
+)
+
+// ChecksumMismatchReason is the prefix of the termination message of a
+// fetcher whose download didn't have the expected checksum.
+const ChecksumMismatchReason = "ChecksumMismatch"
+
+// Format is how fetched content is unpacked.
+type Format string
+
+const (
+	// Tar unpacks a tar archive.
+	Tar Format = "tar"
+	// TarGz unpacks a gzipped tar archive.
+	TarGz Format = "tar.gz"
+	// Zip unpacks a zip archive.
+	Zip Format = "zip"
+	// File writes the content to a file named after the URL's path.
+	File Format = "file"
+)
+
+// ChecksumMismatchError is returned when the fetched content doesn't have
+// the expected checksum.
+type ChecksumMismatchError struct {
+	Want, Got string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: expected sha256 %s, got %s", ChecksumMismatchReason, e.Want, e.Got)
+}
+
+// defaultClient is used by fetchers without a client. Its timeout bounds
+// the whole download, so that a stalled server fails the fetch rather than
+// hanging until the build times out.
+var defaultClient = &http.Client{Timeout: 30 * time.Minute}
+
+// Fetcher fetches content over HTTP(S) and unpacks it into Dest.
+type Fetcher struct {
+	URL string
+	// SHA256, if set, is the hex-encoded checksum the content must have.
+	SHA256 string
+	Format Format
+	Dest   string
+	Header http.Header
+	// Client defaults to one whose requests time out after 30 minutes.
+	Client *http.Client
+}
+
+// Fetch downloads the content and unpacks it. The content is downloaded
+// to a temporary file first, so that nothing is unpacked unless its
+// checksum matches.
+func (f Fetcher) Fetch() error {
+	tmp, err := ioutil.TempFile("", "http-fetch")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := f.download(tmp); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dest, 0755); err != nil {
+		return err
+	}
+
+	if f.Format == File || f.Format == "" {
+		name, err := fileName(f.URL)
+		if err != nil {
+			return err
+		}
+		return extract.WriteFile(f.Dest, filepath.Join(f.Dest, name), tmp, 0644)
+	}
+	return Unpack(tmp, f.Format, f.Dest)
+}
+
+// Unpack unpacks the tar, gzipped tar or zip archive in file into dest.
+// Entries that would be written outside of dest, either directly or
+// through a symlink unpacked before them, are rejected.
+func Unpack(file *os.File, format Format, dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	switch format {
+	case Tar:
+		return untar(file, dest)
+	case TarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return untar(gz, dest)
+	case Zip:
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return unzip(file, info.Size(), dest)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// download writes the content to w, and checks its checksum.
+func (f Fetcher) download(w io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, f.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, vs := range f.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	client := f.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %q: unexpected status %s", f.URL, resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return err
+	}
+	if f.SHA256 != "" {
+		if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, f.SHA256) {
+			return &ChecksumMismatchError{Want: strings.ToLower(f.SHA256), Got: got}
+		}
+	}
+	return nil
+}
+
+// fileName returns the last element of the URL's path.
+func fileName(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "", fmt.Errorf("URL %q doesn't name a file", rawurl)
+	}
+	return name, nil
+}
+
+func untar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		p, err := extract.Target(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := extract.MakeDir(dest, p); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extract.WriteFile(dest, p, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := extract.Symlink(dest, hdr.Linkname, p); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unzip(r io.ReaderAt, size int64, dest string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		p, err := extract.Target(dest, zf.Name)
+		if err != nil {
+			return err
+		}
+		if zf.FileInfo().IsDir() {
+			if err := extract.MakeDir(dest, p); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = extract.WriteFile(dest, p, rc, zf.Mode().Perm())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpfetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func makeTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(makeTar(t, files)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readDir returns the content of each file under dir, by relative path.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	if err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[rel] = string(b)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestFetch(t *testing.T) {
+	files := map[string]string{
+		"main.go":     "package main",
+		"pkg/pkg.go":  "package pkg",
+		"pkg/pkg.txt": "hello",
+	}
+	for _, c := range []struct {
+		desc    string
+		format  Format
+		content []byte
+		path    string
+		want    map[string]string
+	}{{
+		desc:    "tar",
+		format:  Tar,
+		content: makeTar(t, files),
+		path:    "/src.tar",
+		want:    files,
+	}, {
+		desc:    "tar.gz",
+		format:  TarGz,
+		content: makeTarGz(t, files),
+		path:    "/src.tar.gz",
+		want:    files,
+	}, {
+		desc:    "zip",
+		format:  Zip,
+		content: makeZip(t, files),
+		path:    "/src.zip",
+		want:    files,
+	}, {
+		desc:    "file",
+		format:  File,
+		content: []byte("#!/bin/sh"),
+		path:    "/scripts/build.sh",
+		want:    map[string]string{"build.sh": "#!/bin/sh"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer token" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				w.Write(c.content)
+			}))
+			defer srv.Close()
+
+			dest, err := ioutil.TempDir("", "httpfetch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dest)
+
+			sum := sha256.Sum256(c.content)
+			if err := (Fetcher{
+				URL:    srv.URL + c.path,
+				SHA256: hex.EncodeToString(sum[:]),
+				Format: c.format,
+				Dest:   dest,
+				Header: http.Header{"Authorization": {"Bearer token"}},
+			}).Fetch(); err != nil {
+				t.Fatalf("Fetch() = %v", err)
+			}
+			if d := cmp.Diff(c.want, readDir(t, dest)); d != "" {
+				t.Errorf("Diff:\n%s", d)
+			}
+		})
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	content := makeTar(t, map[string]string{"../escape": "boom"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	dest, err := ioutil.TempDir("", "httpfetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	err = Fetcher{
+		URL:    srv.URL + "/src.tar",
+		SHA256: "0000000000000000000000000000000000000000000000000000000000000000",
+		Format: Tar,
+		Dest:   dest,
+	}.Fetch()
+	if _, ok := err.(*ChecksumMismatchError); !ok {
+		t.Errorf("Fetch() with wrong checksum = %v, want ChecksumMismatchError", err)
+	}
+	if files := readDir(t, dest); len(files) != 0 {
+		t.Errorf("Fetch() with wrong checksum unpacked %v", files)
+	}
+
+	if err := (Fetcher{URL: srv.URL + "/src.tar", Format: Tar, Dest: dest}).Fetch(); err == nil {
+		t.Error("Fetch() of archive escaping its destination succeeded, want error")
+	}
+
+	if err := (Fetcher{URL: srv.URL + "/missing", Format: Tar, Dest: dest}).Fetch(); err == nil {
+		t.Error("Fetch() of missing archive succeeded, want error")
+	}
+}
+
+func TestUnpackSymlinks(t *testing.T) {
+	outside, err := ioutil.TempDir("", "httpfetch-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	victim := filepath.Join(outside, "victim")
+	if err := ioutil.WriteFile(victim, []byte("safe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		desc    string
+		entries []tar.Header
+		wantErr bool
+		want    map[string]string
+	}{{
+		desc: "write through symlinked parent",
+		entries: []tar.Header{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "link/victim", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		wantErr: true,
+	}, {
+		desc: "directory through symlinked parent",
+		entries: []tar.Header{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "link/dir/", Typeflag: tar.TypeDir, Mode: 0755},
+		},
+		wantErr: true,
+	}, {
+		desc: "file replacing symlink",
+		entries: []tar.Header{
+			{Name: "file", Typeflag: tar.TypeSymlink, Linkname: victim},
+			{Name: "file", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		want: map[string]string{"file": "boom"},
+	}, {
+		desc: "relative symlink",
+		entries: []tar.Header{
+			{Name: "pkg/pkg.txt", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "pkg.txt", Typeflag: tar.TypeSymlink, Linkname: "pkg/pkg.txt"},
+		},
+		want: map[string]string{"pkg/pkg.txt": "boom", "pkg.txt": "boom"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, hdr := range c.entries {
+				hdr := hdr
+				var content []byte
+				if hdr.Typeflag == tar.TypeReg {
+					content = []byte("boom")
+					hdr.Size = int64(len(content))
+				}
+				if err := tw.WriteHeader(&hdr); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := tw.Write(content); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			archive, err := ioutil.TempFile("", "httpfetch-archive")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(archive.Name())
+			defer archive.Close()
+			if _, err := archive.Write(buf.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := archive.Seek(0, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			dest, err := ioutil.TempDir("", "httpfetch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dest)
+
+			err = Unpack(archive, Tar, dest)
+			if c.wantErr {
+				if err == nil {
+					t.Error("Unpack() succeeded, want error")
+				}
+			} else if err != nil {
+				t.Fatalf("Unpack() = %v", err)
+			} else if d := cmp.Diff(c.want, readDir(t, dest)); d != "" {
+				t.Errorf("Diff:\n%s", d)
+			}
+
+			if d := cmp.Diff(map[string]string{"victim": "safe"}, readDir(t, outside)); d != "" {
+				t.Errorf("Unpack() wrote outside of its destination:\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imagefetch fetches the image source of a build: it pulls an image
+// from a registry and extracts its filesystem, or a path within it, into a
+// directory.
+package imagefetch
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/knative/build/pkg/extract"
+)
+
+// Media types of the manifests that are fetched.
+const (
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// Names of the files in layers that mark deleted files.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// Keychain returns the credentials for a registry, and false if it has
+// none.
+type Keychain func(registry string) (username, password string, ok bool, err error)
+
+// Fetcher pulls an image and extracts it into Dest.
+type Fetcher struct {
+	Reference Reference
+	// Path, if set, is the path of the directory or file within the image
+	// to extract; by default the whole filesystem is extracted.
+	Path     string
+	Dest     string
+	Keychain Keychain
+	Client   *http.Client
+	// OS and Architecture select the image from multi-platform images;
+	// they default to those of the running binary.
+	OS, Architecture string
+}
+
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// Fetch pulls the image and extracts it, returning the digest of the image
+// that the reference resolved to.
+func (f Fetcher) Fetch() (string, error) {
+	reg := &registry{
+		ref:      f.Reference,
+		client:   f.Client,
+		keychain: f.Keychain,
+	}
+	if reg.client == nil {
+		reg.client = http.DefaultClient
+	}
+
+	m, digest, err := reg.manifest(f.Reference.identifier())
+	if err != nil {
+		return "", err
+	}
+	if m.MediaType == mediaTypeOCIIndex || m.MediaType == mediaTypeDockerList {
+		d, err := f.choose(m.Manifests)
+		if err != nil {
+			return "", err
+		}
+		if m, _, err = reg.manifest(d.Digest); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(f.Dest, 0755); err != nil {
+		return "", err
+	}
+	found := false
+	for _, l := range m.Layers {
+		extracted, err := f.fetchLayer(reg, l)
+		if err != nil {
+			return "", err
+		}
+		found = found || extracted
+	}
+	if !found && f.Path != "" {
+		return "", fmt.Errorf("path %q not found in image %s", f.Path, f.Reference)
+	}
+	return digest, nil
+}
+
+// choose returns the manifest for the fetcher's platform from an index.
+func (f Fetcher) choose(manifests []descriptor) (descriptor, error) {
+	goos, goarch := f.OS, f.Architecture
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	for _, d := range manifests {
+		if d.Platform != nil && d.Platform.OS == goos && d.Platform.Architecture == goarch {
+			return d, nil
+		}
+	}
+	return descriptor{}, fmt.Errorf("image %s has no manifest for platform %s/%s", f.Reference, goos, goarch)
+}
+
+// fetchLayer downloads the layer and applies it to the destination,
+// returning whether any of its files were within the fetcher's path. The
+// layer is downloaded to a temporary file first, so that nothing is
+// extracted unless its digest matches.
+func (f Fetcher) fetchLayer(reg *registry, l descriptor) (bool, error) {
+	tmp, err := ioutil.TempFile("", "image-layer")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := reg.blob(l.Digest, tmp); err != nil {
+		return false, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	var r io.Reader = tmp
+	switch {
+	case strings.HasSuffix(l.MediaType, "gzip"):
+		gz, err := gzip.NewReader(tmp)
+		if err != nil {
+			return false, err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(l.MediaType, "tar"):
+	default:
+		return false, fmt.Errorf("unsupported layer media type %q", l.MediaType)
+	}
+	return f.apply(r)
+}
+
+// within returns the path relative to the fetcher's path of a file in the
+// image, and whether the file is within it.
+func (f Fetcher) within(name string) (string, bool) {
+	prefix := path.Clean("/" + f.Path)
+	if prefix == "/" {
+		return strings.TrimPrefix(name, "/"), name != "/"
+	}
+	if name == prefix {
+		return ".", true
+	}
+	if strings.HasPrefix(name, prefix+"/") {
+		return name[len(prefix)+1:], true
+	}
+	return "", false
+}
+
+// apply extracts the files of a layer that are within the fetcher's path,
+// and removes those the layer deletes.
+func (f Fetcher) apply(r io.Reader) (bool, error) {
+	found := false
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return found, nil
+		} else if err != nil {
+			return found, err
+		}
+		name := path.Clean("/" + hdr.Name)
+		dir, base := path.Split(name)
+
+		if base == whiteoutOpaque {
+			if rel, ok := f.within(path.Clean(dir)); ok {
+				if err := clearDir(f.Dest, filepath.Join(f.Dest, rel)); err != nil {
+					return found, err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			if rel, ok := f.within(path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))); ok {
+				if err := extract.Remove(f.Dest, filepath.Join(f.Dest, rel)); err != nil {
+					return found, err
+				}
+			}
+			continue
+		}
+
+		rel, ok := f.within(name)
+		if !ok {
+			continue
+		}
+		found = true
+		p := filepath.Join(f.Dest, rel)
+		if rel == "." && hdr.Typeflag != tar.TypeDir {
+			// The path names a single file, which is extracted into the
+			// destination.
+			p = filepath.Join(f.Dest, base)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := extract.MakeDir(f.Dest, p); err != nil {
+				return found, err
+			}
+		case tar.TypeReg:
+			if err := extract.WriteFile(f.Dest, p, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return found, err
+			}
+		case tar.TypeSymlink:
+			if err := extract.Symlink(f.Dest, hdr.Linkname, p); err != nil {
+				return found, err
+			}
+		case tar.TypeLink:
+			target, ok := f.within(path.Clean("/" + hdr.Linkname))
+			if !ok {
+				continue
+			}
+			// Neither the link nor its target may be reached through a
+			// symlink extracted from an earlier layer.
+			oldname := filepath.Join(f.Dest, target)
+			if err := extract.CheckParents(f.Dest, oldname); err != nil {
+				return found, err
+			}
+			if err := extract.MakeParents(f.Dest, p); err != nil {
+				return found, err
+			}
+			if err := os.RemoveAll(p); err != nil {
+				return found, err
+			}
+			if err := os.Link(oldname, p); err != nil {
+				return found, err
+			}
+		}
+	}
+}
+
+// clearDir removes the contents of dir under dest, if it exists, refusing
+// to do so through a symlink.
+func clearDir(dest, dir string) error {
+	if err := extract.CheckParents(dest, dir); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to clear %q, which isn't a directory", dir)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.RemoveAll(filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registry fetches the manifests and blobs of a repository, authorizing
+// itself as the registry requests.
+type registry struct {
+	ref      Reference
+	client   *http.Client
+	keychain Keychain
+
+	// authorization is the value of the Authorization header sent once
+	// the registry has challenged a request.
+	authorization string
+}
+
+// manifest returns the manifest with the tag or digest, and its digest.
+func (r *registry) manifest(identifier string) (*manifest, string, error) {
+	resp, err := r.get("manifests/"+identifier, mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(b)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(identifier, "sha256:") && identifier != digest {
+		return nil, "", fmt.Errorf("manifest %s has digest %s", identifier, digest)
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, "", err
+	}
+	if m.MediaType == "" {
+		m.MediaType = resp.Header.Get("Content-Type")
+	}
+	return m, digest, nil
+}
+
+// blob writes the blob with the digest to w, and checks its digest.
+func (r *registry) blob(digest string, w io.Writer) error {
+	resp, err := r.get("blobs/" + digest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return err
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("blob %s has digest %s", digest, got)
+	}
+	return nil
+}
+
+// get fetches a path under the repository's API, authorizing and retrying
+// the request if the registry challenges it.
+func (r *registry) get(p string, accept ...string) (*http.Response, error) {
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, r.ref.baseURL()+r.ref.Repository+"/"+p, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if r.authorization != "" {
+			req.Header.Set("Authorization", r.authorization)
+		}
+		return r.client.Do(req)
+	}
+
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authorize(challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = do(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s of %s: unexpected status %s", p, r.ref, resp.Status)
+	}
+	return resp, nil
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize sets the authorization with which to answer the challenge.
+func (r *registry) authorize(challenge string) error {
+	var username, password string
+	var ok bool
+	if r.keychain != nil {
+		var err error
+		if username, password, ok, err = r.keychain(r.ref.Registry); err != nil {
+			return err
+		}
+	}
+
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		if !ok {
+			return fmt.Errorf("registry %s requires credentials", r.ref.Registry)
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(username, password)
+		r.authorization = req.Header.Get("Authorization")
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication challenge %q from registry %s", challenge, r.ref.Registry)
+	}
+
+	params := map[string]string{}
+	for _, m := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	u, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid token realm in challenge %q", challenge)
+	}
+	q := u.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	q.Set("scope", fmt.Sprintf("repository:%s:pull", r.ref.Repository))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if ok {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching token for %s: unexpected status %s", r.ref, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	r.authorization = "Bearer " + token.Token
+	return nil
+}
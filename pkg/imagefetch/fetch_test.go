@@ -0,0 +1,460 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testRegistry is an in-process registry serving one repository, which
+// requires a bearer token if it has a password.
+type testRegistry struct {
+	t         *testing.T
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	password  string
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	return &testRegistry{
+		t:         t,
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+	}
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// addLayer adds a gzipped layer with the files, by path, returning its
+// descriptor. Files with empty content are directories.
+func (r *testRegistry) addLayer(files map[string]string) descriptor {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if content == "" {
+			hdr = &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			r.t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		r.t.Fatal(err)
+	}
+	d := descriptor{
+		MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
+		Digest:    digestOf(buf.Bytes()),
+		Size:      int64(buf.Len()),
+	}
+	r.blobs[d.Digest] = buf.Bytes()
+	return d
+}
+
+// addManifest adds the manifest under the tag, returning its descriptor.
+func (r *testRegistry) addManifest(tag string, m manifest) descriptor {
+	b, err := json.Marshal(m)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	d := descriptor{MediaType: m.MediaType, Digest: digestOf(b), Size: int64(len(b))}
+	r.manifests[d.Digest] = b
+	if tag != "" {
+		r.manifests[tag] = b
+	}
+	return d
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != r.password {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		if got, want := req.URL.Query().Get("scope"), "repository:test/bundle:pull"; got != want {
+			http.Error(w, "bad scope "+got, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"token":"secret-token"}`))
+		return
+	}
+	if r.password != "" && req.Header.Get("Authorization") != "Bearer secret-token" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+req.Host+`/token",service="test"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/v2/test/bundle/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		http.NotFound(w, req)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, prefix), "/", 2)
+	var b []byte
+	var ok bool
+	switch parts[0] {
+	case "manifests":
+		b, ok = r.manifests[parts[1]]
+	case "blobs":
+		b, ok = r.blobs[parts[1]]
+	}
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Write(b)
+}
+
+// readDir returns the content of each file under dir, by relative path.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	if err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[rel] = string(b)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestFetch(t *testing.T) {
+	reg := newTestRegistry(t)
+	base := reg.addLayer(map[string]string{
+		"app/":             "",
+		"app/bundle.js":    "old",
+		"app/stale.js":     "stale",
+		"app/assets/":      "",
+		"app/assets/a.css": "a",
+		"etc/config":       "config",
+	})
+	// The top layer replaces a file, deletes another, and replaces the
+	// contents of a directory.
+	top := reg.addLayer(map[string]string{
+		"app/bundle.js":           "new",
+		"app/.wh.stale.js":        "",
+		"app/assets/.wh..wh..opq": "",
+		"app/assets/b.css":        "b",
+	})
+	img := reg.addManifest("v1", manifest{
+		MediaType: mediaTypeOCIManifest,
+		Layers:    []descriptor{base, top},
+	})
+	other := reg.addManifest("", manifest{
+		MediaType: mediaTypeOCIManifest,
+		Layers:    []descriptor{reg.addLayer(map[string]string{"arm": "arm"})},
+	})
+	other.Platform = &platform{OS: "linux", Architecture: "arm64"}
+	img.Platform = &platform{OS: "linux", Architecture: "amd64"}
+	index := reg.addManifest("multi", manifest{
+		MediaType: mediaTypeOCIIndex,
+		Manifests: []descriptor{other, img},
+	})
+
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for _, c := range []struct {
+		desc       string
+		ref        string
+		path       string
+		wantDigest string
+		want       map[string]string
+	}{{
+		desc:       "whole filesystem by tag",
+		ref:        host + "/test/bundle:v1",
+		wantDigest: img.Digest,
+		want: map[string]string{
+			"app/bundle.js":    "new",
+			"app/assets/b.css": "b",
+			"etc/config":       "config",
+		},
+	}, {
+		desc:       "directory by digest",
+		ref:        host + "/test/bundle@" + img.Digest,
+		path:       "/app",
+		wantDigest: img.Digest,
+		want: map[string]string{
+			"bundle.js":    "new",
+			"assets/b.css": "b",
+		},
+	}, {
+		desc:       "single file",
+		ref:        host + "/test/bundle:v1",
+		path:       "etc/config",
+		wantDigest: img.Digest,
+		want: map[string]string{
+			"config": "config",
+		},
+	}, {
+		desc:       "platform from index",
+		ref:        host + "/test/bundle:multi",
+		path:       "/etc",
+		wantDigest: index.Digest,
+		want: map[string]string{
+			"config": "config",
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			ref, err := ParseReference(c.ref)
+			if err != nil {
+				t.Fatalf("ParseReference() = %v", err)
+			}
+			dest, err := ioutil.TempDir("", "imagefetch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dest)
+
+			digest, err := Fetcher{
+				Reference:    ref,
+				Path:         c.path,
+				Dest:         dest,
+				OS:           "linux",
+				Architecture: "amd64",
+			}.Fetch()
+			if err != nil {
+				t.Fatalf("Fetch() = %v", err)
+			}
+			if digest != c.wantDigest {
+				t.Errorf("Fetch() = %s, want %s", digest, c.wantDigest)
+			}
+			if d := cmp.Diff(c.want, readDir(t, dest)); d != "" {
+				t.Errorf("Diff:\n%s", d)
+			}
+		})
+	}
+}
+
+func TestFetchAuth(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.password = "pass"
+	reg.addManifest("v1", manifest{
+		MediaType: mediaTypeDockerManifest,
+		Layers:    []descriptor{reg.addLayer(map[string]string{"file": "content"})},
+	})
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+	ref, err := ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/test/bundle:v1")
+	if err != nil {
+		t.Fatalf("ParseReference() = %v", err)
+	}
+
+	dest, err := ioutil.TempDir("", "imagefetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	keychain := func(password string) Keychain {
+		return func(registry string) (string, string, bool, error) {
+			if registry != ref.Registry {
+				t.Errorf("keychain asked for %q, want %q", registry, ref.Registry)
+			}
+			return "user", password, true, nil
+		}
+	}
+	if _, err := (Fetcher{Reference: ref, Dest: dest, Keychain: keychain("wrong")}).Fetch(); err == nil {
+		t.Error("Fetch() with wrong credentials succeeded, want error")
+	}
+	if _, err := (Fetcher{Reference: ref, Dest: dest, Keychain: keychain("pass")}).Fetch(); err != nil {
+		t.Fatalf("Fetch() = %v", err)
+	}
+	if d := cmp.Diff(map[string]string{"file": "content"}, readDir(t, dest)); d != "" {
+		t.Errorf("Diff:\n%s", d)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	reg := newTestRegistry(t)
+	layer := reg.addLayer(map[string]string{"file": "content"})
+	reg.addManifest("v1", manifest{
+		MediaType: mediaTypeOCIManifest,
+		Layers:    []descriptor{layer},
+	})
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for _, c := range []struct {
+		desc string
+		ref  string
+		path string
+		// corrupt, if set, replaces the layer's content.
+		corrupt bool
+	}{{
+		desc: "missing tag",
+		ref:  host + "/test/bundle:v2",
+	}, {
+		desc: "missing path",
+		ref:  host + "/test/bundle:v1",
+		path: "/nope",
+	}, {
+		desc:    "corrupt layer",
+		ref:     host + "/test/bundle:v1",
+		corrupt: true,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			if c.corrupt {
+				good := reg.blobs[layer.Digest]
+				reg.blobs[layer.Digest] = []byte("corrupt")
+				defer func() { reg.blobs[layer.Digest] = good }()
+			}
+			ref, err := ParseReference(c.ref)
+			if err != nil {
+				t.Fatalf("ParseReference() = %v", err)
+			}
+			dest, err := ioutil.TempDir("", "imagefetch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dest)
+			if _, err := (Fetcher{Reference: ref, Path: c.path, Dest: dest}).Fetch(); err == nil {
+				t.Error("Fetch() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestApplySymlinks(t *testing.T) {
+	outside, err := ioutil.TempDir("", "imagefetch-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	victim := filepath.Join(outside, "victim")
+
+	// layer returns a layer with the entries; regular files contain "boom".
+	layer := func(entries ...tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range entries {
+			hdr := hdr
+			var content []byte
+			if hdr.Typeflag == tar.TypeReg {
+				content = []byte("boom")
+				hdr.Size = int64(len(content))
+			}
+			if err := tw.WriteHeader(&hdr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+	symlink := tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: outside}
+
+	for _, c := range []struct {
+		desc    string
+		second  []tar.Header
+		wantErr bool
+		want    map[string]string
+	}{{
+		desc:    "whiteout through symlink",
+		second:  []tar.Header{{Name: "a/.wh.victim", Typeflag: tar.TypeReg}},
+		wantErr: true,
+	}, {
+		desc:    "opaque whiteout through symlink",
+		second:  []tar.Header{{Name: "a/.wh..wh..opq", Typeflag: tar.TypeReg}},
+		wantErr: true,
+	}, {
+		desc:    "hardlink through symlink",
+		second:  []tar.Header{{Name: "link", Typeflag: tar.TypeLink, Linkname: "a/victim"}},
+		wantErr: true,
+	}, {
+		desc:    "file through symlink",
+		second:  []tar.Header{{Name: "a/victim", Typeflag: tar.TypeReg, Mode: 0644}},
+		wantErr: true,
+	}, {
+		desc: "whiteout of symlink",
+		second: []tar.Header{
+			{Name: ".wh.a", Typeflag: tar.TypeReg},
+			{Name: "a/victim", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "link", Typeflag: tar.TypeLink, Linkname: "a/victim"},
+		},
+		want: map[string]string{"a/victim": "boom", "link": "boom"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := ioutil.WriteFile(victim, []byte("safe"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			dest, err := ioutil.TempDir("", "imagefetch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dest)
+
+			f := Fetcher{Dest: dest}
+			if _, err := f.apply(layer(symlink)); err != nil {
+				t.Fatalf("apply() of first layer = %v", err)
+			}
+			_, err = f.apply(layer(c.second...))
+			if c.wantErr {
+				if err == nil {
+					t.Error("apply() of second layer succeeded, want error")
+				}
+			} else if err != nil {
+				t.Fatalf("apply() of second layer = %v", err)
+			} else if d := cmp.Diff(c.want, readDir(t, dest)); d != "" {
+				t.Errorf("Diff:\n%s", d)
+			}
+			if d := cmp.Diff(map[string]string{"victim": "safe"}, readDir(t, outside)); d != "" {
+				t.Errorf("apply() changed files outside of its destination:\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefetch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:6ca13d52ca70c883e0f0bb101e425a89e8624de51db2d2392593af6a84118090"
+	for _, c := range []struct {
+		in      string
+		want    Reference
+		wantURL string
+	}{{
+		in:      "ubuntu",
+		want:    Reference{Registry: "index.docker.io", Repository: "library/ubuntu", Tag: "latest"},
+		wantURL: "https://registry-1.docker.io/v2/",
+	}, {
+		in:      "knative/build:v1.2",
+		want:    Reference{Registry: "index.docker.io", Repository: "knative/build", Tag: "v1.2"},
+		wantURL: "https://registry-1.docker.io/v2/",
+	}, {
+		in:      "gcr.io/project/bundle@" + digest,
+		want:    Reference{Registry: "gcr.io", Repository: "project/bundle", Digest: digest},
+		wantURL: "https://gcr.io/v2/",
+	}, {
+		in:      "localhost:5000/bundle:dev",
+		want:    Reference{Registry: "localhost:5000", Repository: "bundle", Tag: "dev"},
+		wantURL: "http://localhost:5000/v2/",
+	}} {
+		got, err := ParseReference(c.in)
+		if err != nil {
+			t.Errorf("ParseReference(%q) = %v", c.in, err)
+			continue
+		}
+		if d := cmp.Diff(c.want, got); d != "" {
+			t.Errorf("ParseReference(%q) diff:\n%s", c.in, d)
+		}
+		if got := got.baseURL(); got != c.wantURL {
+			t.Errorf("ParseReference(%q).baseURL() = %q, want %q", c.in, got, c.wantURL)
+		}
+	}
+
+	for _, in := range []string{"", "UPPER/case", "bundle@sha256:abc", "bundle:bad tag"} {
+		if got, err := ParseReference(in); err == nil {
+			t.Errorf("ParseReference(%q) = %v, want error", in, got)
+		}
+	}
+}
@@ -16,25 +16,90 @@ package logs
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	buildv1alpha1 "github.com/knative/build/pkg/client/clientset/versioned/typed/build/v1alpha1"
+	"github.com/knative/build/pkg/entrypoint"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-const buildExecuteFailed = "BuildExecuteFailed"
+const (
+	buildExecuteFailed = "BuildExecuteFailed"
+
+	// stepPrefix is the prefix of the names of the containers that
+	// execute a build's steps.
+	stepPrefix = "build-step-"
+
+	// Pods annotated with the following run the build's steps as regular
+	// containers, ordered by an injected entrypoint, rather than as init
+	// containers.
+	stepRunnerAnnotationKey = "build.knative.dev/stepRunner"
+	entrypointStepRunner    = "Entrypoint"
+
+	// reconnectDelay is how long to wait before retrying to watch the
+	// build's pod after the watch fails.
+	reconnectDelay = time.Second
+)
+
+// Options controls which of a build's logs are printed, and how.
+type Options struct {
+	// Timestamps prefixes each line with the time it was logged.
+	Timestamps bool
+	// Since, if positive, skips lines logged before this long ago.
+	Since time.Duration
+	// Steps, if not empty, are the only steps whose logs are printed.
+	Steps []string
+	// NoColor prints text without ANSI colours.
+	NoColor bool
+	// JSON prints each line as a JSON-encoded Line.
+	JSON bool
+	// AllSteps keeps printing the logs of the remaining steps after a step
+	// fails. Only builds whose steps the entrypoint runs, such as those
+	// with step policies or sidecars, run steps after a failure; the
+	// remaining steps of other builds are reported as skipped.
+	AllSteps bool
+}
+
+// selected returns true if the logs of the container are printed.
+func (o Options) selected(containerName string) bool {
+	if len(o.Steps) == 0 {
+		return true
+	}
+	for _, s := range o.Steps {
+		if containerName == s || containerName == stepPrefix+s {
+			return true
+		}
+	}
+	return false
+}
+
+// Line is a line of a build's logs, as printed with Options.JSON.
+type Line struct {
+	// Step is the name of the container that executes the step.
+	Step string `json:"step"`
+	// Timestamp is the RFC 3339 time at which the line was logged.
+	Timestamp string `json:"timestamp"`
+	// Line is the text of the line, without its newline.
+	Line string `json:"line"`
+	// Status is set for lines that report the state of the step rather
+	// than its output: "waiting", "failed" or "skipped".
+	Status string `json:"status,omitempty"`
+}
 
 // Tail tails the logs for a build.
-func Tail(ctx context.Context, out io.Writer, buildName, namespace string) error {
+func Tail(ctx context.Context, out io.Writer, buildName, namespace string, opts Options) error {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
 
@@ -62,6 +127,15 @@ func Tail(ctx context.Context, out io.Writer, buildName, namespace string) error
 		return fmt.Errorf("watching pod: %v", err)
 	}
 
+	pr := &printer{out: out, opts: opts, now: time.Now}
+	return tail(ctx, pr, &watcher, func(containerName string, follow bool) error {
+		return printContainerLogs(ctx, pr, pods, podName, containerName, follow)
+	})
+}
+
+// tail prints the logs of the pod's steps in the order they run, using
+// printLogs to print, and follow if asked, the logs of a container.
+func tail(ctx context.Context, pr *printer, watcher *podWatcher, printLogs func(containerName string, follow bool) error) error {
 	pod, err := watcher.waitForPod(ctx, func(p *v1.Pod) bool {
 		return len(p.Status.InitContainerStatuses) > 0
 	})
@@ -69,15 +143,17 @@ func Tail(ctx context.Context, out io.Writer, buildName, namespace string) error
 		return err
 	}
 
-	for i, container := range pod.Status.InitContainerStatuses {
-		pod, err := watcher.waitForPod(ctx, func(p *v1.Pod) bool {
-			waiting := p.Status.InitContainerStatuses[i].State.Waiting
-			if waiting == nil {
+	for _, name := range stepContainers(pod) {
+		name := name
+		selected := pr.opts.selected(name)
+		pod, err := watcher.waitForPod(ctx, func(pod *v1.Pod) bool {
+			waiting := containerStatus(pod, name).State.Waiting
+			if waiting == nil || podDone(pod) {
 				return true
 			}
 
-			if waiting.Message != "" {
-				fmt.Fprintln(out, red(fmt.Sprintf("[%s] %s", container.Name, waiting.Message)))
+			if waiting.Message != "" && selected {
+				pr.status(name, "waiting", waiting.Message)
 			}
 
 			return false
@@ -86,35 +162,95 @@ func Tail(ctx context.Context, out io.Writer, buildName, namespace string) error
 			return fmt.Errorf("waiting for container: %v", err)
 		}
 
-		container := pod.Status.InitContainerStatuses[i]
-		followContainer := container.State.Terminated == nil
-		if err := printContainerLogs(ctx, out, pods, podName, container.Name, followContainer); err != nil {
-			return fmt.Errorf("printing logs: %v", err)
+		container := containerStatus(pod, name)
+		if container.State.Waiting != nil {
+			// The pod finished without running the step, because
+			// an earlier step failed.
+			if selected {
+				pr.status(name, "skipped", "Not run")
+			}
+			continue
+		}
+
+		if selected {
+			followContainer := container.State.Terminated == nil
+			if err := printLogs(name, followContainer); err != nil {
+				return fmt.Errorf("printing logs: %v", err)
+			}
 		}
 
 		pod, err = watcher.waitForPod(ctx, func(p *v1.Pod) bool {
-			return p.Status.InitContainerStatuses[i].State.Terminated != nil
+			return containerStatus(p, name).State.Terminated != nil
 		})
 		if err != nil {
 			return fmt.Errorf("waiting for container termination: %v", err)
 		}
 
-		container = pod.Status.InitContainerStatuses[i]
-		terminated := container.State.Terminated
-		if terminated.ExitCode != 0 {
+		terminated := containerStatus(pod, name).State.Terminated
+		switch {
+		case terminated.ExitCode != 0:
 			message := "Build Failed"
 			if terminated.Message != "" {
 				message += ": " + terminated.Message
 			}
 
-			fmt.Fprintln(out, red(fmt.Sprintf("[%s] %s", container.Name, message)))
-			return nil
+			pr.status(name, "failed", message)
+			if !pr.opts.AllSteps {
+				return nil
+			}
+		case terminated.Message == entrypoint.SkippedMessage:
+			// The entrypoint didn't run the step's command, because
+			// of the step's policy.
+			if selected {
+				pr.status(name, "skipped", "Not run")
+			}
 		}
 	}
 
 	return nil
 }
 
+// stepContainers returns the names of the pod's containers whose logs are
+// printed, in the order they run: its init containers, which fetch the
+// build's sources and, unless the entrypoint runs them, execute its steps,
+// followed by the containers of the steps the entrypoint runs.
+func stepContainers(pod *v1.Pod) []string {
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	if pod.Annotations[stepRunnerAnnotationKey] == entrypointStepRunner {
+		// The other containers are sidecars.
+		for _, c := range pod.Spec.Containers {
+			if strings.HasPrefix(c.Name, stepPrefix) {
+				names = append(names, c.Name)
+			}
+		}
+	}
+	return names
+}
+
+// containerStatus returns the status of the named container, which is
+// waiting until the pod reports it.
+func containerStatus(pod *v1.Pod, name string) v1.ContainerStatus {
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, s := range statuses {
+			if s.Name == name {
+				return s
+			}
+		}
+	}
+	return v1.ContainerStatus{
+		Name:  name,
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}},
+	}
+}
+
+// podDone returns true if none of the pod's containers will run again.
+func podDone(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
 type podWatcher struct {
 	pods corev1.PodInterface
 	name string
@@ -126,31 +262,68 @@ type podWatcher struct {
 func (w *podWatcher) start(ctx context.Context) error {
 	w.versions = make(chan *v1.Pod, 100)
 
-	watcher, err := w.pods.Watch(metav1.ListOptions{
-		IncludeUninitialized: true,
-		FieldSelector:        fields.OneTermEqualSelector("metadata.name", w.name).String(),
-	})
+	watcher, err := w.watch("")
 	if err != nil {
 		return fmt.Errorf("watching pod: %v", err)
 	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				watcher.Stop()
-				return
-			case evt, ok := <-watcher.ResultChan():
-				if !ok {
-					// TODO: reconnect watch
+	go w.run(ctx, watcher)
+
+	return nil
+}
+
+func (w *podWatcher) watch(resourceVersion string) (watch.Interface, error) {
+	return w.pods.Watch(metav1.ListOptions{
+		IncludeUninitialized: true,
+		FieldSelector:        fields.OneTermEqualSelector("metadata.name", w.name).String(),
+		ResourceVersion:      resourceVersion,
+	})
+}
+
+// run sends the versions of the pod seen by the watcher, watching the pod
+// again whenever the API server closes the watch.
+func (w *podWatcher) run(ctx context.Context, watcher watch.Interface) {
+	var resourceVersion string
+	for {
+		select {
+		case <-ctx.Done():
+			watcher.Stop()
+			return
+		case evt, ok := <-watcher.ResultChan():
+			if ok {
+				if pod, isPod := evt.Object.(*v1.Pod); isPod {
+					resourceVersion = pod.ResourceVersion
+					w.versions <- pod
+					continue
 				}
+				// The watch failed, for instance because the
+				// resource version we resumed from is too old,
+				// so start again from the pod's current version.
+				watcher.Stop()
+				resourceVersion = ""
+			}
 
-				w.versions <- evt.Object.(*v1.Pod)
+			if watcher = w.rewatch(ctx, resourceVersion); watcher == nil {
+				return
 			}
 		}
-	}()
+	}
+}
 
-	return nil
+// rewatch watches the pod from the resource version, retrying until it
+// succeeds or the context is done, in which case it returns nil.
+func (w *podWatcher) rewatch(ctx context.Context, resourceVersion string) watch.Interface {
+	for {
+		watcher, err := w.watch(resourceVersion)
+		if err == nil {
+			return watcher
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
 }
 
 func (w *podWatcher) waitForPod(ctx context.Context, predicate func(pod *v1.Pod) bool) (*v1.Pod, error) {
@@ -170,22 +343,29 @@ func (w *podWatcher) waitForPod(ctx context.Context, predicate func(pod *v1.Pod)
 	}
 }
 
-func printContainerLogs(ctx context.Context, out io.Writer, pods corev1.PodExpansion, podName, containerName string, follow bool) error {
-	rc, err := pods.GetLogs(podName, &v1.PodLogOptions{
+func printContainerLogs(ctx context.Context, p *printer, pods corev1.PodExpansion, podName, containerName string, follow bool) error {
+	logOptions := &v1.PodLogOptions{
 		Container: containerName,
 		Follow:    follow,
-	}).Stream()
+		// Lines are always split from their timestamps, since they are
+		// part of the JSON output.
+		Timestamps: p.opts.Timestamps || p.opts.JSON,
+	}
+	if p.opts.Since > 0 {
+		// Round up, so that no line newer than Since is skipped.
+		seconds := int64((p.opts.Since + time.Second - 1) / time.Second)
+		logOptions.SinceSeconds = &seconds
+	}
+	rc, err := pods.GetLogs(podName, logOptions).Stream()
 	if err != nil {
 		return err
 	}
 	defer rc.Close()
 
-	return streamLogs(ctx, out, containerName, rc)
+	return streamLogs(ctx, p, containerName, rc)
 }
 
-func streamLogs(ctx context.Context, out io.Writer, containerName string, rc io.Reader) error {
-	prefix := green(fmt.Sprintf("[%s]", containerName)) + " "
-
+func streamLogs(ctx context.Context, p *printer, containerName string, rc io.Reader) error {
 	r := bufio.NewReader(rc)
 	for {
 		select {
@@ -194,10 +374,10 @@ func streamLogs(ctx context.Context, out io.Writer, containerName string, rc io.
 		default:
 		}
 
-		line, err := r.ReadBytes('\n')
+		line, err := r.ReadString('\n')
 		if err == io.EOF {
 			if len(line) > 0 {
-				fmt.Fprintf(out, "%s%s\n", prefix, line)
+				p.line(containerName, line)
 			}
 			return nil
 		}
@@ -205,7 +385,59 @@ func streamLogs(ctx context.Context, out io.Writer, containerName string, rc io.
 			return err
 		}
 
-		fmt.Fprintf(out, "%s%s", prefix, line)
+		p.line(containerName, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// printer prints lines of logs as configured by its options.
+type printer struct {
+	out  io.Writer
+	opts Options
+	now  func() time.Time
+}
+
+// line prints a line of the container's logs, which starts with its
+// timestamp if the logs were requested with timestamps.
+func (p *printer) line(containerName, line string) {
+	var timestamp string
+	if p.opts.Timestamps || p.opts.JSON {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			timestamp, line = line[:i], line[i+1:]
+		} else {
+			timestamp, line = line, ""
+		}
+	}
+	p.print(Line{Step: containerName, Timestamp: timestamp, Line: line})
+}
+
+// status prints a line that reports the state of the container.
+func (p *printer) status(containerName, status, message string) {
+	var timestamp string
+	if p.opts.Timestamps || p.opts.JSON {
+		timestamp = p.now().UTC().Format(time.RFC3339Nano)
+	}
+	p.print(Line{Step: containerName, Timestamp: timestamp, Line: message, Status: status})
+}
+
+func (p *printer) print(l Line) {
+	if p.opts.JSON {
+		// Encoding strings can't fail.
+		json.NewEncoder(p.out).Encode(l)
+		return
+	}
+
+	prefix := fmt.Sprintf("[%s]", l.Step)
+	text := l.Line
+	if l.Timestamp != "" && p.opts.Timestamps {
+		text = l.Timestamp + " " + text
+	}
+	switch {
+	case p.opts.NoColor:
+		fmt.Fprintf(p.out, "%s %s\n", prefix, text)
+	case l.Status != "":
+		fmt.Fprintln(p.out, red(fmt.Sprintf("%s %s", prefix, text)))
+	default:
+		fmt.Fprintf(p.out, "%s %s\n", green(prefix), text)
 	}
 }
 
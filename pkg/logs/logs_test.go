@@ -0,0 +1,305 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestStreamLogs(t *testing.T) {
+	now := func() time.Time { return time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC) }
+	for _, c := range []struct {
+		desc string
+		opts Options
+		logs string
+		want string
+	}{{
+		desc: "colors",
+		logs: "hello\nworld",
+		want: "\033[32m[build-step-foo]\033[0m hello\n\033[32m[build-step-foo]\033[0m world\n\033[31m[build-step-foo] Build Failed\033[0m\n",
+	}, {
+		desc: "no color",
+		opts: Options{NoColor: true},
+		logs: "hello\nworld\n",
+		want: "[build-step-foo] hello\n[build-step-foo] world\n[build-step-foo] Build Failed\n",
+	}, {
+		desc: "timestamps",
+		opts: Options{NoColor: true, Timestamps: true},
+		logs: "2019-03-01T11:59:58.5Z hello\n2019-03-01T11:59:59Z world\n",
+		want: "[build-step-foo] 2019-03-01T11:59:58.5Z hello\n[build-step-foo] 2019-03-01T11:59:59Z world\n[build-step-foo] 2019-03-01T12:00:00Z Build Failed\n",
+	}, {
+		desc: "json",
+		opts: Options{JSON: true},
+		logs: "2019-03-01T11:59:58.5Z hello \"world\"\n2019-03-01T11:59:59Z\n",
+		want: `{"step":"build-step-foo","timestamp":"2019-03-01T11:59:58.5Z","line":"hello \"world\""}
+{"step":"build-step-foo","timestamp":"2019-03-01T11:59:59Z","line":""}
+{"step":"build-step-foo","timestamp":"2019-03-01T12:00:00Z","line":"Build Failed","status":"failed"}
+`,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			p := &printer{out: &out, opts: c.opts, now: now}
+			if err := streamLogs(context.Background(), p, "build-step-foo", strings.NewReader(c.logs)); err != nil {
+				t.Fatalf("streamLogs() = %v", err)
+			}
+			p.status("build-step-foo", "failed", "Build Failed")
+			if d := cmp.Diff(c.want, out.String()); d != "" {
+				t.Errorf("Output diff -want, +got: %v", d)
+			}
+		})
+	}
+}
+
+func TestOptionsSelected(t *testing.T) {
+	opts := Options{Steps: []string{"compile", "build-step-credential-initializer"}}
+	for name, want := range map[string]bool{
+		"build-step-compile":                true,
+		"build-step-credential-initializer": true,
+		"build-step-git-source-0":           false,
+		"compile":                           true,
+	} {
+		if got := opts.selected(name); got != want {
+			t.Errorf("selected(%q) = %t, want %t", name, got, want)
+		}
+	}
+	if !(Options{}).selected("build-step-anything") {
+		t.Error("Options without steps should select every step")
+	}
+}
+
+func TestPodWatcherReconnects(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	watchers := make(chan *watch.FakeWatcher, 3)
+	versions := make(chan string, 3)
+	cs.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		versions <- action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion
+		w := watch.NewFake()
+		watchers <- w
+		return true, w, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := podWatcher{pods: cs.CoreV1().Pods("default"), name: "pod"}
+	if err := w.start(ctx); err != nil {
+		t.Fatalf("start() = %v", err)
+	}
+	pod := func(version string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", ResourceVersion: version},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+	wait := func(phase v1.PodPhase) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if _, err := w.waitForPod(ctx, func(p *v1.Pod) bool { return p.Status.Phase == phase }); err != nil {
+			t.Fatalf("waitForPod(%s) = %v", phase, err)
+		}
+	}
+
+	first := <-watchers
+	first.Add(pod("1", v1.PodPending))
+	wait(v1.PodPending)
+
+	// The API server closes the watch, which resumes from the last version.
+	first.Stop()
+	second := <-watchers
+	second.Modify(pod("2", v1.PodRunning))
+	wait(v1.PodRunning)
+
+	// The watch fails, which starts again from the current version.
+	second.Error(&metav1.Status{Reason: metav1.StatusReasonExpired})
+	third := <-watchers
+	third.Add(pod("5", v1.PodSucceeded))
+	wait(v1.PodSucceeded)
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, <-versions)
+	}
+	if d := cmp.Diff([]string{"", "1", ""}, got); d != "" {
+		t.Errorf("Watched resource versions diff -want, +got: %v", d)
+	}
+}
+
+func TestTail(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}
+	terminated := func(exitCode int32, message string) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: exitCode, Message: message}}
+	}
+	status := func(name string, state v1.ContainerState) v1.ContainerStatus {
+		return v1.ContainerStatus{Name: name, State: state}
+	}
+	containers := func(names ...string) []v1.Container {
+		var cs []v1.Container
+		for _, n := range names {
+			cs = append(cs, v1.Container{Name: n})
+		}
+		return cs
+	}
+
+	// The steps run as init containers; the second fails.
+	initSpec := v1.PodSpec{
+		InitContainers: containers("build-step-credential-initializer", "build-step-a", "build-step-b", "build-step-c"),
+		Containers:     containers("nop"),
+	}
+	initPods := []*v1.Pod{{
+		Spec: initSpec,
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			InitContainerStatuses: []v1.ContainerStatus{
+				status("build-step-credential-initializer", terminated(0, "")),
+				status("build-step-a", running),
+				status("build-step-b", waiting),
+				status("build-step-c", waiting),
+			},
+		},
+	}, {
+		Spec: initSpec,
+		Status: v1.PodStatus{
+			Phase: v1.PodFailed,
+			InitContainerStatuses: []v1.ContainerStatus{
+				status("build-step-credential-initializer", terminated(0, "")),
+				status("build-step-a", terminated(1, "boom")),
+				status("build-step-b", waiting),
+				status("build-step-c", waiting),
+			},
+		},
+	}}
+
+	// The entrypoint runs the steps as containers, alongside a sidecar;
+	// after the first fails, it skips the second and runs the third, which
+	// always runs. Container statuses aren't ordered like the spec.
+	entrypointSpec := v1.PodSpec{
+		InitContainers: containers("build-step-credential-initializer", "build-step-place-tools"),
+		Containers:     containers("build-step-a", "build-step-b", "build-step-c", "sidecar-db"),
+	}
+	entrypointInit := []v1.ContainerStatus{
+		status("build-step-credential-initializer", terminated(0, "")),
+		status("build-step-place-tools", terminated(0, "")),
+	}
+	entrypointMeta := metav1.ObjectMeta{
+		Annotations: map[string]string{"build.knative.dev/stepRunner": "Entrypoint"},
+	}
+	entrypointPods := []*v1.Pod{{
+		ObjectMeta: entrypointMeta,
+		Spec:       entrypointSpec,
+		Status: v1.PodStatus{
+			Phase:                 v1.PodRunning,
+			InitContainerStatuses: entrypointInit,
+			ContainerStatuses: []v1.ContainerStatus{
+				status("sidecar-db", running),
+				status("build-step-c", running),
+				status("build-step-b", running),
+				status("build-step-a", terminated(1, "boom")),
+			},
+		},
+	}, {
+		ObjectMeta: entrypointMeta,
+		Spec:       entrypointSpec,
+		Status: v1.PodStatus{
+			Phase:                 v1.PodRunning,
+			InitContainerStatuses: entrypointInit,
+			ContainerStatuses: []v1.ContainerStatus{
+				status("sidecar-db", running),
+				status("build-step-c", terminated(0, "")),
+				status("build-step-b", terminated(0, "build.knative.dev/skipped")),
+				status("build-step-a", terminated(1, "boom")),
+			},
+		},
+	}}
+
+	for _, c := range []struct {
+		desc     string
+		pods     []*v1.Pod
+		allSteps bool
+		want     string
+	}{{
+		desc: "init containers",
+		pods: initPods,
+		want: `[build-step-credential-initializer] logs (follow: false)
+[build-step-a] logs (follow: true)
+[build-step-a] Build Failed: boom
+`,
+	}, {
+		desc:     "init containers, all steps",
+		pods:     initPods,
+		allSteps: true,
+		want: `[build-step-credential-initializer] logs (follow: false)
+[build-step-a] logs (follow: true)
+[build-step-a] Build Failed: boom
+[build-step-b] Not run
+[build-step-c] Not run
+`,
+	}, {
+		desc: "entrypoint",
+		pods: entrypointPods,
+		want: `[build-step-credential-initializer] logs (follow: false)
+[build-step-place-tools] logs (follow: false)
+[build-step-a] logs (follow: false)
+[build-step-a] Build Failed: boom
+`,
+	}, {
+		desc:     "entrypoint, all steps",
+		pods:     entrypointPods,
+		allSteps: true,
+		want: `[build-step-credential-initializer] logs (follow: false)
+[build-step-place-tools] logs (follow: false)
+[build-step-a] logs (follow: false)
+[build-step-a] Build Failed: boom
+[build-step-b] logs (follow: true)
+[build-step-b] Not run
+[build-step-c] logs (follow: false)
+`,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			w := &podWatcher{versions: make(chan *v1.Pod, len(c.pods))}
+			for _, p := range c.pods {
+				w.versions <- p
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var out bytes.Buffer
+			pr := &printer{out: &out, opts: Options{NoColor: true, AllSteps: c.allSteps}, now: time.Now}
+			if err := tail(ctx, pr, w, func(containerName string, follow bool) error {
+				pr.line(containerName, fmt.Sprintf("logs (follow: %t)", follow))
+				return nil
+			}); err != nil {
+				t.Fatalf("tail() = %v", err)
+			}
+			if d := cmp.Diff(c.want, out.String()); d != "" {
+				t.Errorf("Output diff -want, +got: %v", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify sends CloudEvents about builds to sinks as their state
+// changes.
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/pkg/cloudevents"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The types of the CloudEvents sent about builds.
+const (
+	BuildStarted   = "dev.knative.build.started"
+	StepCompleted  = "dev.knative.build.step.completed"
+	BuildSucceeded = "dev.knative.build.succeeded"
+	BuildFailed    = "dev.knative.build.failed"
+	BuildTimedOut  = "dev.knative.build.timedout"
+	BuildCancelled = "dev.knative.build.cancelled"
+)
+
+// Data is the payload of the CloudEvents sent about a build.
+type Data struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	UID       types.UID `json:"uid"`
+	// Step names the step that completed, for StepCompleted events.
+	Step string `json:"step,omitempty"`
+	// Status is the build's status when the event occurred.
+	Status v1alpha1.BuildStatus `json:"status"`
+}
+
+// Notification is a CloudEvent to send to a sink.
+type Notification struct {
+	// Sink is the URI to which the event is sent.
+	Sink string
+	// EventType is one of the types above.
+	EventType string
+	Data      Data
+
+	id   string
+	time time.Time
+}
+
+// New returns a notification about the build. Its ID and time are fixed
+// when it's created, so that sinks can recognize redeliveries.
+func New(sink, eventType string, build *v1alpha1.Build, step string) Notification {
+	return Notification{
+		Sink:      sink,
+		EventType: eventType,
+		Data: Data{
+			Name:      build.Name,
+			Namespace: build.Namespace,
+			UID:       build.UID,
+			Step:      step,
+			Status:    *build.Status.DeepCopy(),
+		},
+		id:   uuid.New().String(),
+		time: time.Now(),
+	}
+}
+
+// Source returns the URI of the build, which is the source of the
+// notification.
+func (n Notification) Source() string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/builds/%s", v1alpha1.SchemeGroupVersion, n.Data.Namespace, n.Data.Name)
+}
+
+// Sender delivers notifications. Notifications about the same build are
+// delivered in the order they were sent, and failed deliveries are retried
+// with exponential backoff.
+type Sender struct {
+	// Client sends the notifications.
+	Client *http.Client
+	// Attempts is how many times delivery of a notification is attempted.
+	Attempts int
+	// Backoff is the delay before the first retry; the delay doubles with
+	// each retry.
+	Backoff time.Duration
+	// Failed is called with the notifications that couldn't be delivered,
+	// and the last error delivering each.
+	Failed func(Notification, error)
+
+	mu     sync.Mutex
+	queues map[string][]Notification
+}
+
+// Send queues the notification for delivery after the earlier
+// notifications with the same key, which identifies the build.
+func (s *Sender) Send(key string, n Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queues == nil {
+		s.queues = map[string][]Notification{}
+	}
+	q, running := s.queues[key]
+	s.queues[key] = append(q, n)
+	if !running {
+		go s.drain(key)
+	}
+}
+
+// drain delivers the notifications queued with the key until there are
+// none left.
+func (s *Sender) drain(key string) {
+	for {
+		s.mu.Lock()
+		q := s.queues[key]
+		if len(q) == 0 {
+			delete(s.queues, key)
+			s.mu.Unlock()
+			return
+		}
+		n := q[0]
+		s.mu.Unlock()
+
+		if err := s.deliver(n); err != nil && s.Failed != nil {
+			s.Failed(n, err)
+		}
+
+		s.mu.Lock()
+		s.queues[key] = s.queues[key][1:]
+		s.mu.Unlock()
+	}
+}
+
+// deliver sends the notification until its sink accepts it or it runs out
+// of attempts.
+func (s *Sender) deliver(n Notification) error {
+	var err error
+	for attempt := 0; attempt < s.Attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.Backoff << uint(attempt-1))
+		}
+		if err = s.post(n); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *Sender) post(n Notification) error {
+	builder := cloudevents.Builder{
+		Source:    n.Source(),
+		EventType: n.EventType,
+		Encoding:  cloudevents.BinaryV01,
+	}
+	req, err := builder.Build(n.Sink, n.Data, cloudevents.V01EventContext{
+		EventID:   n.id,
+		EventTime: n.time,
+	})
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sink %s responded %s", n.Sink, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// received is a CloudEvent received by a sink.
+type received struct {
+	ID, Type, Source string
+	Data             Data
+}
+
+// sink is a local HTTP sink that fails the first failures requests.
+type sink struct {
+	mu       sync.Mutex
+	failures int
+	events   []received
+}
+
+func (s *sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	e := received{
+		ID:     r.Header.Get("CE-EventID"),
+		Type:   r.Header.Get("CE-EventType"),
+		Source: r.Header.Get("CE-Source"),
+	}
+	if err := json.NewDecoder(r.Body).Decode(&e.Data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.events = append(s.events, e)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *sink) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received{}, s.events...)
+}
+
+// waitFor waits until cond returns true.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("Timed out waiting")
+}
+
+func newBuild(name string) *v1alpha1.Build {
+	b := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID("uid-" + name),
+		},
+	}
+	b.Status.StepsCompleted = []string{"build-step-compile"}
+	return b
+}
+
+func TestSendInOrder(t *testing.T) {
+	s := &sink{failures: 1}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	sender := &Sender{
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+		Failed: func(n Notification, err error) {
+			t.Errorf("Failed to deliver %s: %v", n.EventType, err)
+		},
+	}
+	build := newBuild("ordered")
+	started := New(server.URL, BuildStarted, build, "")
+	sender.Send("default/ordered", started)
+	sender.Send("default/ordered", New(server.URL, StepCompleted, build, "compile"))
+	sender.Send("default/ordered", New(server.URL, BuildSucceeded, build, ""))
+
+	waitFor(t, func() bool { return len(s.received()) == 3 })
+	got := s.received()
+	var types []string
+	for _, e := range got {
+		types = append(types, e.Type)
+	}
+	if d := cmp.Diff([]string{BuildStarted, StepCompleted, BuildSucceeded}, types); d != "" {
+		t.Errorf("Unexpected event types (-want, +got): %s", d)
+	}
+	// The first event was retried, with its original ID.
+	if got[0].ID != started.id {
+		t.Errorf("Redelivered event has ID %q, want %q", got[0].ID, started.id)
+	}
+	if want := "/apis/build.knative.dev/v1alpha1/namespaces/default/builds/ordered"; got[0].Source != want {
+		t.Errorf("Source = %q, want %q", got[0].Source, want)
+	}
+	wantData := Data{
+		Name:      "ordered",
+		Namespace: "default",
+		UID:       build.UID,
+		Step:      "compile",
+		Status:    build.Status,
+	}
+	if d := cmp.Diff(wantData, got[1].Data); d != "" {
+		t.Errorf("Unexpected data (-want, +got): %s", d)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	s := &sink{failures: 3}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	failed := make(chan error, 1)
+	sender := &Sender{
+		Attempts: 2,
+		Backoff:  time.Millisecond,
+		Failed: func(n Notification, err error) {
+			failed <- err
+		},
+	}
+	build := newBuild("failing")
+	sender.Send("default/failing", New(server.URL, BuildStarted, build, ""))
+	sender.Send("default/failing", New(server.URL, BuildFailed, build, ""))
+
+	select {
+	case err := <-failed:
+		if !strings.Contains(err.Error(), "503") {
+			t.Errorf("Unexpected delivery error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delivery to fail")
+	}
+	// The next event is still delivered, on its second attempt.
+	waitFor(t, func() bool { return len(s.received()) == 1 })
+	if got := s.received()[0].Type; got != BuildFailed {
+		t.Errorf("Delivered %s, want %s", got, BuildFailed)
+	}
+}
+
+func TestSendUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	failed := make(chan error, 1)
+	sender := &Sender{
+		Attempts: 1,
+		Failed: func(n Notification, err error) {
+			failed <- err
+		},
+	}
+	sender.Send("default/unreachable", New(server.URL, BuildStarted, newBuild("unreachable"), ""))
+	select {
+	case err := <-failed:
+		if !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("Unexpected delivery error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delivery to fail")
+	}
+}
@@ -17,17 +17,25 @@ limitations under the License.
 package build
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/knative/build/pkg/apis/build/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
 )
 
 // ApplyTemplate applies the values in the template to the build, and replaces
 // placeholders for declared parameters with the build's matching arguments.
-func ApplyTemplate(u *v1alpha1.Build, tmpl v1alpha1.BuildTemplateInterface) (*v1alpha1.Build, error) {
+// Arguments whose values come from ConfigMaps are read with kubeclient.
+// Those whose values come from Secrets are passed to the steps in
+// environment variables that the kubelet expands in their commands, args and
+// environment, so that the values don't appear in the build's pod.
+func ApplyTemplate(u *v1alpha1.Build, tmpl v1alpha1.BuildTemplateInterface, kubeclient kubernetes.Interface) (*v1alpha1.Build, error) {
 	build := u.DeepCopy()
 	if tmpl == nil {
 		return build, nil
@@ -35,52 +43,230 @@ func ApplyTemplate(u *v1alpha1.Build, tmpl v1alpha1.BuildTemplateInterface) (*v1
 	tmpl = tmpl.Copy()
 	build.Spec.Steps = tmpl.TemplateSpec().Steps
 	build.Spec.Volumes = append(build.Spec.Volumes, tmpl.TemplateSpec().Volumes...)
+	if len(build.Spec.StepTimeouts) == 0 {
+		build.Spec.StepTimeouts = tmpl.TemplateSpec().StepTimeouts
+	}
+	if len(build.Spec.StepPolicies) == 0 {
+		build.Spec.StepPolicies = tmpl.TemplateSpec().StepPolicies
+	}
+	declared := sets.NewString()
+	for _, r := range build.Spec.Results {
+		declared.Insert(r.Name)
+	}
+	for _, r := range tmpl.TemplateSpec().Results {
+		if !declared.Has(r.Name) {
+			build.Spec.Results = append(build.Spec.Results, r)
+		}
+	}
 
 	// Apply template arguments or parameter defaults.
 	replacements := map[string]string{}
-	if tmpl != nil {
-		for _, p := range tmpl.TemplateSpec().Parameters {
-			if p.Default != nil {
-				replacements[p.Name] = *p.Default
+	arrayReplacements := map[string][]string{}
+	arrays := sets.NewString()
+	for _, p := range tmpl.TemplateSpec().Parameters {
+		if p.Type == v1alpha1.ParameterTypeArray {
+			arrays.Insert(p.Name)
+			if p.DefaultValues != nil {
+				arrayReplacements[p.Name] = p.DefaultValues
 			}
+		} else if p.Default != nil {
+			replacements[p.Name] = *p.Default
 		}
 	}
+	var secretEnv []corev1.EnvVar
 	if build.Spec.Template != nil {
+		args, err := resolveArguments(build, kubeclient)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range args {
+			if arrays.Has(a.Name) {
+				arrayReplacements[a.Name] = a.Values
+			} else {
+				replacements[a.Name] = a.Value
+			}
+		}
 		for _, a := range build.Spec.Template.Arguments {
-			replacements[a.Name] = a.Value
+			if a.ValueFrom == nil || a.ValueFrom.SecretKeyRef == nil {
+				continue
+			}
+			env := corev1.EnvVar{
+				Name:      secretArgumentEnvVar(a.Name),
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: a.ValueFrom.SecretKeyRef.DeepCopy()},
+			}
+			delete(arrayReplacements, a.Name)
+			replacements[a.Name] = fmt.Sprintf("$(%s)", env.Name)
+			secretEnv = append(secretEnv, env)
 		}
 	}
 
-	build = ApplyReplacements(build, replacements)
+	build = ApplyReplacements(build, replacements, arrayReplacements)
+	if len(secretEnv) > 0 {
+		if err := checkSecretArguments(build, secretEnv); err != nil {
+			return nil, err
+		}
+		for i := range build.Spec.Steps {
+			build.Spec.Steps[i].Env = append(append([]corev1.EnvVar{}, secretEnv...), build.Spec.Steps[i].Env...)
+		}
+	}
 	return build, nil
 }
 
+// secretArgumentEnvVar returns the name of the environment variable that
+// holds the value of the named argument from a Secret.
+func secretArgumentEnvVar(name string) string {
+	return "BUILD_SECRET_ARG_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// checkSecretArguments returns an error if the placeholder of an argument
+// from a Secret was replaced outside the steps' commands, args and
+// environment, where the kubelet doesn't expand its variable.
+func checkSecretArguments(build *v1alpha1.Build, secretEnv []corev1.EnvVar) error {
+	var fields []string
+	for _, step := range build.Spec.Steps {
+		fields = append(fields, step.Name, step.Image, step.WorkingDir)
+		for _, vm := range step.VolumeMounts {
+			fields = append(fields, vm.Name, vm.MountPath, vm.SubPath)
+		}
+	}
+	for _, c := range build.Spec.Caches {
+		fields = append(fields, c.Key)
+	}
+	volumes, err := json.Marshal(build.Spec.Volumes)
+	if err != nil {
+		return err
+	}
+	fields = append(fields, string(volumes))
+	for _, env := range secretEnv {
+		ref := fmt.Sprintf("$(%s)", env.Name)
+		for _, f := range fields {
+			if strings.Contains(f, ref) {
+				return validationError("SecretArgumentNotAllowed", "argument from Secret %q may only be used in the commands, args and environment of steps", env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveArguments returns the arguments of the build's template, with the
+// values of those that specify ValueFrom resolved. Resolved arguments keep
+// their ValueFrom, so that validation can tell where their values came from.
+func resolveArguments(build *v1alpha1.Build, kubeclient kubernetes.Interface) ([]v1alpha1.ArgumentSpec, error) {
+	var args []v1alpha1.ArgumentSpec
+	for _, a := range build.Spec.Template.Arguments {
+		if a.ValueFrom != nil {
+			value, err := resolveValueFrom(build, a.Name, a.ValueFrom, kubeclient)
+			if err != nil {
+				return nil, err
+			}
+			a.Value = value
+		}
+		args = append(args, a)
+	}
+	return args, nil
+}
+
+func resolveValueFrom(build *v1alpha1.Build, name string, vs *v1alpha1.ArgumentValueSource, kubeclient kubernetes.Interface) (string, error) {
+	switch {
+	case vs.ConfigMapKeyRef != nil:
+		ref := vs.ConfigMapKeyRef
+		optional := ref.Optional != nil && *ref.Optional
+		cm, err := kubeclient.CoreV1().ConfigMaps(build.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			if optional {
+				return "", nil
+			}
+			return "", validationError("ConfigMapNotFound", "argument %q references ConfigMap %q, which does not exist", name, ref.Name)
+		} else if err != nil {
+			return "", err
+		}
+		value, ok := cm.Data[ref.Key]
+		if !ok && !optional {
+			return "", validationError("MissingConfigMapKey", "argument %q references key %q of ConfigMap %q, which does not exist", name, ref.Key, ref.Name)
+		}
+		return value, nil
+
+	case vs.SecretKeyRef != nil:
+		ref := vs.SecretKeyRef
+		optional := ref.Optional != nil && *ref.Optional
+		secret, err := kubeclient.CoreV1().Secrets(build.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			if optional {
+				return "", nil
+			}
+			return "", validationError("SecretNotFound", "argument %q references Secret %q, which does not exist", name, ref.Name)
+		} else if err != nil {
+			return "", err
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok && !optional {
+			return "", validationError("MissingSecretKey", "argument %q references key %q of Secret %q, which does not exist", name, ref.Key, ref.Name)
+		}
+		return string(value), nil
+
+	case vs.FieldRef != nil:
+		value, err := build.FieldValue(vs.FieldRef.FieldPath)
+		if err != nil {
+			return "", validationError("UnsupportedFieldPath", "argument %q: %v", name, err)
+		}
+		return value, nil
+	}
+	return "", validationError("MissingValueSource", "argument %q has no source for its value", name)
+}
+
 // ApplyReplacements replaces placeholders for declared parameters with the specified replacements.
-func ApplyReplacements(build *v1alpha1.Build, replacements map[string]string) *v1alpha1.Build {
+// Args and command entries that consist of just the placeholder of an array
+// replacement expand into one entry for each of its values; elsewhere the
+// placeholder is replaced by the values separated by spaces.
+func ApplyReplacements(build *v1alpha1.Build, replacements map[string]string, arrayReplacements map[string][]string) *v1alpha1.Build {
 	build = build.DeepCopy()
 
 	applyReplacements := func(in string) string {
 		for k, v := range replacements {
 			in = strings.Replace(in, fmt.Sprintf("${%s}", k), v, -1)
 		}
+		for k, v := range arrayReplacements {
+			in = strings.Replace(in, fmt.Sprintf("${%s}", k), strings.Join(v, " "), -1)
+		}
 		return in
 	}
+	applyArrayReplacements := func(in []string) []string {
+		if in == nil {
+			return nil
+		}
+		out := []string{}
+		for _, s := range in {
+			if strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}") {
+				if v, ok := arrayReplacements[s[len("${"):len(s)-len("}")]]; ok {
+					out = append(out, v...)
+					continue
+				}
+			}
+			out = append(out, applyReplacements(s))
+		}
+		return out
+	}
 
 	// Apply variable expansion to steps fields.
 	steps := build.Spec.Steps
 	for i := range steps {
 		steps[i].Name = applyReplacements(steps[i].Name)
 		steps[i].Image = applyReplacements(steps[i].Image)
-		for ia, a := range steps[i].Args {
-			steps[i].Args[ia] = applyReplacements(a)
-		}
+		steps[i].Args = applyArrayReplacements(steps[i].Args)
 		for ie, e := range steps[i].Env {
 			steps[i].Env[ie].Value = applyReplacements(e.Value)
 		}
 		steps[i].WorkingDir = applyReplacements(steps[i].WorkingDir)
-		for ic, c := range steps[i].Command {
-			steps[i].Command[ic] = applyReplacements(c)
-		}
+		steps[i].Command = applyArrayReplacements(steps[i].Command)
 		for iv, v := range steps[i].VolumeMounts {
 			steps[i].VolumeMounts[iv].Name = applyReplacements(v.Name)
 			steps[i].VolumeMounts[iv].MountPath = applyReplacements(v.MountPath)
@@ -96,6 +282,12 @@ func ApplyReplacements(build *v1alpha1.Build, replacements map[string]string) *v
 		}
 	}
 
+	// Apply variable expansion to the keys of the build's caches, so that
+	// they may depend on its arguments.
+	for i, c := range build.Spec.Caches {
+		build.Spec.Caches[i].Key = applyReplacements(c.Key)
+	}
+
 	if buildTmpl := build.Spec.Template; buildTmpl != nil && len(buildTmpl.Env) > 0 {
 		// Apply variable expansion to the build's overridden
 		// environment variables
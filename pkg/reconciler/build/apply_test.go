@@ -17,11 +17,16 @@ limitations under the License.
 package build
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/knative/build/pkg/apis/build/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 )
 
 func TestApplyTemplate(t *testing.T) {
@@ -33,6 +38,23 @@ func TestApplyTemplate(t *testing.T) {
 		tmpl  v1alpha1.BuildTemplateInterface
 		want  *v1alpha1.Build // if nil, expect error.
 	}{{
+		// The template's results are declared along with the build's.
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Results: []v1alpha1.ResultSpec{{Name: "digest", Description: "from build"}},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			Spec: v1alpha1.BuildTemplateSpec{
+				Results: []v1alpha1.ResultSpec{{Name: "digest"}, {Name: "url"}},
+			},
+		},
+		want: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Results: []v1alpha1.ResultSpec{{Name: "digest", Description: "from build"}, {Name: "url"}},
+			},
+		},
+	}, {
 		// Build's Steps are overwritten. This doesn't pass
 		// ValidateBuild anyway.
 		build: &v1alpha1.Build{
@@ -612,7 +634,7 @@ func TestApplyTemplate(t *testing.T) {
 		},
 	}} {
 		wantErr := c.want == nil
-		got, err := ApplyTemplate(c.build, c.tmpl)
+		got, err := ApplyTemplate(c.build, c.tmpl, nil)
 		if err != nil && !wantErr {
 			t.Errorf("ApplyTemplate(%d); unexpected error %v", i, err)
 		} else if err == nil && wantErr {
@@ -623,10 +645,77 @@ func TestApplyTemplate(t *testing.T) {
 	}
 }
 
+func TestApplyTemplateSecretArguments(t *testing.T) {
+	kubeclient := fakekubeclientset.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "ns"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	})
+	ref := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+		Key:                  "token",
+	}
+	build := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "ns"},
+		Spec: v1alpha1.BuildSpec{
+			Template: &v1alpha1.TemplateInstantiationSpec{
+				Name: "template",
+				Arguments: []v1alpha1.ArgumentSpec{{
+					Name:      "api-token",
+					ValueFrom: &v1alpha1.ArgumentValueSource{SecretKeyRef: ref},
+				}},
+			},
+		},
+	}
+	tmpl := &v1alpha1.BuildTemplate{
+		Spec: v1alpha1.BuildTemplateSpec{
+			Parameters: []v1alpha1.ParameterSpec{{Name: "api-token"}},
+			Steps: []corev1.Container{{
+				Name:  "deploy",
+				Image: "deployer",
+				Args:  []string{"--token=${api-token}"},
+				Env:   []corev1.EnvVar{{Name: "TOKEN", Value: "${api-token}"}},
+			}},
+		},
+	}
+
+	got, err := ApplyTemplate(build, tmpl, kubeclient)
+	if err != nil {
+		t.Fatalf("ApplyTemplate() = %v", err)
+	}
+	if d := cmp.Diff(corev1.Container{
+		Name:  "deploy",
+		Image: "deployer",
+		Args:  []string{"--token=$(BUILD_SECRET_ARG_API_TOKEN)"},
+		Env: []corev1.EnvVar{{
+			Name:      "BUILD_SECRET_ARG_API_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: ref},
+		}, {
+			Name:  "TOKEN",
+			Value: "$(BUILD_SECRET_ARG_API_TOKEN)",
+		}},
+	}, got.Spec.Steps[0]); d != "" {
+		t.Errorf("Diff step (-want, +got): %s", d)
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("json.Marshal() = %v", err)
+	}
+	if strings.Contains(string(b), "s3cr3t") {
+		t.Errorf("Applied build contains the Secret's value: %s", b)
+	}
+
+	// The kubelet doesn't expand variables in images.
+	tmpl.Spec.Steps[0].Image = "deployer:${api-token}"
+	if _, err := ApplyTemplate(build, tmpl, kubeclient); err == nil {
+		t.Error("ApplyTemplate() with a Secret argument in an image succeeded, want error")
+	}
+}
+
 func TestApplyReplacements(t *testing.T) {
 	type args struct {
-		build        *v1alpha1.Build
-		replacements map[string]string
+		build             *v1alpha1.Build
+		replacements      map[string]string
+		arrayReplacements map[string][]string
 	}
 	tests := []struct {
 		name string
@@ -814,10 +903,68 @@ func TestApplyReplacements(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "array replacements",
+			args: args{
+				build: &v1alpha1.Build{
+					Spec: v1alpha1.BuildSpec{
+						Steps: []corev1.Container{{
+							Name:    "build",
+							Image:   "builder",
+							Command: []string{"make", "${targets}"},
+							Args:    []string{"--flag=${flag}", "${extra}", "--all=${extra}"},
+						}},
+					},
+				},
+				replacements: map[string]string{
+					"flag": "value",
+				},
+				arrayReplacements: map[string][]string{
+					"targets": {"build", "test"},
+					"extra":   {"-v", "-j4"},
+				},
+			},
+			want: &v1alpha1.Build{
+				Spec: v1alpha1.BuildSpec{
+					Steps: []corev1.Container{{
+						Name:    "build",
+						Image:   "builder",
+						Command: []string{"make", "build", "test"},
+						Args:    []string{"--flag=value", "-v", "-j4", "--all=-v -j4"},
+					}},
+				},
+			},
+		},
+		{
+			name: "cache key replacements",
+			args: args{
+				build: &v1alpha1.Build{
+					Spec: v1alpha1.BuildSpec{
+						Caches: []v1alpha1.CacheSpec{{
+							Name:      "go-mod",
+							MountPath: "/go/pkg/mod",
+							Key:       "go-${GO_SUM}",
+						}},
+					},
+				},
+				replacements: map[string]string{
+					"GO_SUM": "abc123",
+				},
+			},
+			want: &v1alpha1.Build{
+				Spec: v1alpha1.BuildSpec{
+					Caches: []v1alpha1.CacheSpec{{
+						Name:      "go-mod",
+						MountPath: "/go/pkg/mod",
+						Key:       "go-abc123",
+					}},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ApplyReplacements(tt.args.build, tt.args.replacements); !reflect.DeepEqual(got, tt.want) {
+			if got := ApplyReplacements(tt.args.build, tt.args.replacements, tt.args.arrayReplacements); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("ApplyReplacements() = %v, want %v", got, tt.want)
 			}
 		})
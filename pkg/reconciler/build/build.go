@@ -26,7 +26,10 @@ import (
 	clientset "github.com/knative/build/pkg/client/clientset/versioned"
 	buildscheme "github.com/knative/build/pkg/client/clientset/versioned/scheme"
 	listers "github.com/knative/build/pkg/client/listers/build/v1alpha1"
-	"github.com/knative/build/pkg/reconciler/build/resources"
+	"github.com/knative/build/pkg/commitstatus"
+	"github.com/knative/build/pkg/credentials/gitcreds"
+	"github.com/knative/build/pkg/notify"
+	buildtemplateresources "github.com/knative/build/pkg/reconciler/buildtemplate/resources"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	"github.com/knative/pkg/controller"
 	"github.com/knative/pkg/logging"
@@ -39,6 +42,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -58,6 +62,22 @@ type Reconciler struct {
 	clusterBuildTemplatesLister listers.ClusterBuildTemplateLister
 	podsLister                  corelisters.PodLister
 
+	// googleBuilder executes builds with Google Cloud Build; it is nil
+	// unless the controller is configured with a GCP project.
+	googleBuilder Builder
+	// enqueueAfter requeues a build to poll builders that implement Poller.
+	enqueueAfter func(interface{}, time.Duration)
+	// recorder records Events on builds as they progress.
+	recorder record.EventRecorder
+	// stats reports the metrics of builds as they progress.
+	stats StatsReporter
+	// notifier sends CloudEvents about builds as they progress.
+	notifier *notify.Sender
+	// commitStatuses posts the commit statuses of builds.
+	commitStatuses *commitstatus.Sender
+	// gitTokens caches the API tokens minted for posting commit statuses.
+	gitTokens *gitcreds.TokenCache
+
 	// Sugared logger is easier to use but is not as performant as the
 	// raw logger. In performance critical paths, call logger.Desugar()
 	// and use the returned raw logger instead. In addition to the
@@ -110,28 +130,44 @@ func (c *Reconciler) Reconcile(ctx context.Context, key string) error {
 		return c.cancelBuild(build, logger)
 	}
 
-	// If the build hasn't started yet, validate it and create a Pod for it
-	// and record that pod's name in the build status.
-	var p *corev1.Pod
-	if build.Status.Cluster == nil || build.Status.Cluster.PodName == "" {
-		// Add a unique suffix to avoid confusion when a build
-		// is deleted and re-created with the same name.
-		// We don't use GenerateName here because k8s fakes don't support it.
-		podName, err := resources.GetUniquePodName(build.Name)
+	builder, err := c.builderFor(build)
+	if err != nil {
+		build.Status.SetCondition(&duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "BuildValidationFailed",
+			Message: err.Error(),
+		})
+		if updateErr := c.updateStatus(build); updateErr != nil {
+			return updateErr
+		}
+		c.finished(build)
+		return err
+	}
+
+	// If the build hasn't started yet, validate it and hand it to its
+	// builder, recording what it needs to track the build in the status.
+	var status v1alpha1.BuildStatus
+	var started bool
+	retrying := len(build.Status.RetriesStatus) > 0
+	if !builder.IsStarted(&build.Status) {
+		// A failed attempt is only retried once its backoff has passed.
+		if wait := retryWait(build); wait > 0 {
+			if c.enqueueAfter != nil {
+				c.enqueueAfter(build, wait)
+			}
+			return nil
+		}
+		// update with a dummy status first to avoid race condition of another event while the build is being started
+		prepared, err := builder.Prepare(build)
 		if err != nil {
 			return err
 		}
-		// update with a dummy status first to avoid race condition of another event while the pod is being created
-		build.Status = v1alpha1.BuildStatus{
-			Builder: v1alpha1.ClusterBuildProvider,
-			Cluster: &v1alpha1.ClusterSpec{
-				Namespace: build.Namespace,
-				PodName:   podName,
-			},
-			StartTime: &metav1.Time{
-				Time: time.Now(),
-			},
+		if retrying {
+			prepared.StartTime = build.Status.StartTime
+			prepared.RetriesStatus = build.Status.RetriesStatus
 		}
+		build.Status = prepared
 		if err := c.updateStatus(build); err != nil {
 			return err
 		}
@@ -142,6 +178,7 @@ func (c *Reconciler) Reconcile(ctx context.Context, key string) error {
 				Cluster: &v1alpha1.ClusterSpec{
 					PodName: "",
 				},
+				RetriesStatus: build.Status.RetriesStatus,
 			}
 			build.Status.SetCondition(&duckv1alpha1.Condition{
 				Type:    v1alpha1.BuildSucceeded,
@@ -152,48 +189,84 @@ func (c *Reconciler) Reconcile(ctx context.Context, key string) error {
 			if err = c.updateStatus(build); err != nil {
 				return err
 			}
+			c.finished(build)
 			return buildErr
 		}
 
-		// p is defined above, so we can't use :=.
-		var podErr error
-		p, podErr = c.startPodForBuild(build)
-		if podErr != nil {
+		// status is defined above, so we can't use :=.
+		var execErr error
+		status, execErr = c.startBuild(builder, build)
+		if execErr != nil {
 			build.Status.SetCondition(&duckv1alpha1.Condition{
 				Type:    v1alpha1.BuildSucceeded,
 				Status:  corev1.ConditionFalse,
 				Reason:  "BuildExecuteFailed",
-				Message: podErr.Error(),
+				Message: execErr.Error(),
 			})
 			if err = c.updateStatus(build); err != nil {
 				return err
 			}
-			return podErr
+			c.finished(build)
+			return execErr
+		}
+		recordStarted(c.recorder, build, status)
+		started = true
+		// Start goroutine that waits for either build timeout or build finish;
+		// retries share the timeout of the first attempt.
+		if !retrying {
+			go c.timeoutHandler.wait(build)
 		}
-		// Start goroutine that waits for either build timeout or build finish
-		go c.timeoutHandler.wait(build)
 	} else {
-		// If the build is ongoing, update its status based on its pod, and
-		// check if it's timed out.
-		p, err = c.podsLister.Pods(build.Namespace).Get(build.Status.Cluster.PodName)
+		// If the build is ongoing, update its status based on its builder's
+		// view of it.
+		status, err = builder.Status(build)
 		if err != nil {
-			// TODO: What if the pod is deleted out from under us?
 			return err
 		}
 	}
 
-	// Update the build's status based on the pod's status.
+	// The failed attempt is recorded with its own start time, before the
+	// build's is restored below.
+	if shouldRetry(build, status) {
+		return c.retryBuild(build, status)
+	}
+	// Builders only know about the current attempt.
+	if retrying {
+		status.StartTime = build.Status.StartTime
+		status.RetriesStatus = build.Status.RetriesStatus
+	}
+	if cond := status.GetCondition(v1alpha1.BuildSucceeded); cond != nil && cond.Reason == "StepTimeout" {
+		// Builders report that a step timed out, but leave it running.
+		c.Logger.Infof("Build %q has a step that timed out, stopping it: %s", build.Name, cond.Message)
+		if err := builder.Stop(build); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	previous := build.Status
 	statusLock(build)
-	build.Status = resources.BuildStatusFromPod(p, build.Spec)
+	build.Status = status
 	statusUnlock(build)
 	if isDone(&build.Status) {
 		// release goroutine that waits for build timeout
 		c.timeoutHandler.release(build)
 		// and remove key from status map
 		defer statusMap.Delete(key)
+	} else if p, ok := builder.(Poller); ok && c.enqueueAfter != nil {
+		c.enqueueAfter(build, p.PollInterval())
 	}
 
-	return c.updateStatus(build)
+	if err := c.updateStatus(build); err != nil {
+		return err
+	}
+	recordTransitions(c.recorder, build, previous)
+	c.reportTransitions(build, previous)
+	if started {
+		c.notify(build, notify.BuildStarted, "")
+	}
+	c.notifyTransitions(build, previous)
+	c.commitStatusTransitions(build, previous, started)
+	return nil
 }
 
 func (c *Reconciler) updateStatus(u *v1alpha1.Build) error {
@@ -209,16 +282,19 @@ func (c *Reconciler) updateStatus(u *v1alpha1.Build) error {
 		return fmt.Errorf("can't update status of failed build %q", newb.Name)
 	}
 
+	// Only the notifier records notification failures, possibly while
+	// the build is reconciled.
+	u.Status.NotificationFailures = newb.Status.NotificationFailures
 	newb.Status = u.Status
 
 	_, err = c.buildclientset.BuildV1alpha1().Builds(u.Namespace).UpdateStatus(newb)
 	return err
 }
 
-// startPodForBuild starts a new Pod to execute the build.
+// startBuild hands the build to its builder for execution.
 //
-// This applies any build template that's specified, and creates the pod.
-func (c *Reconciler) startPodForBuild(build *v1alpha1.Build) (*corev1.Pod, error) {
+// This applies any build template that's specified, and executes the result.
+func (c *Reconciler) startBuild(builder Builder, build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
 	namespace := build.Namespace
 	var tmpl v1alpha1.BuildTemplateInterface
 	var err error
@@ -230,7 +306,7 @@ func (c *Reconciler) startPodForBuild(build *v1alpha1.Build) (*corev1.Pod, error
 				if errors.IsNotFound(err) {
 					runtime.HandleError(fmt.Errorf("cluster build template %q does not exist", build.Spec.Template.Name))
 				}
-				return nil, err
+				return build.Status, err
 			}
 		} else {
 			tmpl, err = c.buildTemplatesLister.BuildTemplates(namespace).Get(build.Spec.Template.Name)
@@ -239,21 +315,43 @@ func (c *Reconciler) startPodForBuild(build *v1alpha1.Build) (*corev1.Pod, error
 				if errors.IsNotFound(err) {
 					runtime.HandleError(fmt.Errorf("build template %q in namespace %q does not exist", build.Spec.Template.Name, namespace))
 				}
-				return nil, err
+				return build.Status, err
 			}
 		}
 	}
-	build, err = ApplyTemplate(build, tmpl)
+	if tmpl != nil {
+		tmpl, err = buildtemplateresources.Flatten(tmpl, buildtemplateresources.ListerTemplateGetter{
+			BuildTemplates:        c.buildTemplatesLister,
+			ClusterBuildTemplates: c.clusterBuildTemplatesLister,
+		})
+		if err != nil {
+			return build.Status, err
+		}
+	}
+	applied, err := ApplyTemplate(build, tmpl, c.kubeclientset)
 	if err != nil {
-		return nil, err
+		return build.Status, err
 	}
 
-	p, err := resources.MakePod(build, c.kubeclientset)
+	return builder.Execute(applied)
+}
+
+// finished records that the build finished, in an Event and its metrics,
+// notifies its sink and posts its commit statuses.
+func (c *Reconciler) finished(build *v1alpha1.Build) {
+	recordDone(c.recorder, build)
+	c.report(c.stats.ReportDone(build))
+	c.notify(build, doneEventType(build), "")
+	c.postCommitStatuses(build)
+}
+
+// stop terminates the build with the builder that started it.
+func (c *Reconciler) stop(build *v1alpha1.Build) error {
+	builder, err := c.builderFor(build)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	c.Logger.Infof("Creating pod %q in namespace %q for build %q", p.Name, p.Namespace, build.Name)
-	return c.kubeclientset.CoreV1().Pods(p.Namespace).Create(p)
+	return builder.Stop(build)
 }
 
 // isCancelled returns true if the build's spec indicates the build is cancelled.
@@ -272,14 +370,8 @@ func (c *Reconciler) cancelBuild(build *v1alpha1.Build, logger *zap.SugaredLogge
 	if err := c.updateStatus(build); err != nil {
 		return err
 	}
-	if build.Status.Cluster == nil {
-		logger.Warnf("build %q has no pod running yet", build.Name)
-		return nil
-	}
-	if err := c.kubeclientset.CoreV1().Pods(build.Namespace).Delete(build.Status.Cluster.PodName, &metav1.DeleteOptions{}); err != nil {
-		return err
-	}
-	return nil
+	c.finished(build)
+	return c.stop(build)
 }
 
 // isDone returns true if the build's status indicates the build is done.
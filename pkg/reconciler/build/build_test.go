@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,8 +42,10 @@ import (
 	kuberrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
 	rtesting "github.com/knative/pkg/reconciler/testing"
 )
@@ -130,6 +133,12 @@ func (f *fixture) updateClusterBuildTemplateIndex(ctx context.Context, cbt *v1al
 	fakecbtinformer.Get(ctx).Informer().GetIndexer().Add(cbt)
 }
 
+// recordedEvents returns the events recorded by the reconciler since the
+// last call.
+func recordedEvents(ctx context.Context) []string {
+	return drainEvents(controller.GetEventRecorder(ctx).(*record.FakeRecorder))
+}
+
 func getKey(b *v1alpha1.Build, t *testing.T) string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(b)
 	if err != nil {
@@ -416,11 +425,131 @@ func TestBuildWithTemplate(t *testing.T) {
 	}
 }
 
+func TestBuildWithTemplateMisorderedStepPolicy(t *testing.T) {
+	tmpl := &v1alpha1.BuildTemplate{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-template",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Steps: []corev1.Container{{
+				Name:    "build",
+				Image:   "builder",
+				Command: []string{"make"},
+			}, {
+				Name:    "test",
+				Image:   "builder",
+				Command: []string{"make", "test"},
+			}},
+		},
+	}
+
+	// The build's policy can only be checked against the template's steps
+	// once the template is applied.
+	b := newBuild("test-misordered-policy")
+	b.Spec.Template = &v1alpha1.TemplateInstantiationSpec{
+		Kind: v1alpha1.BuildTemplateKind,
+		Name: tmpl.Name,
+	}
+	b.Spec.StepPolicies = []v1alpha1.StepPolicy{{
+		Step: "build",
+		When: &v1alpha1.StepCondition{Step: "test", Outcome: v1alpha1.StepFailed},
+	}}
+	if err := b.Validate(context.Background()); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	f := &fixture{
+		t:       t,
+		objects: []runtime.Object{b, tmpl},
+	}
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f.createBuild(ctx, b)
+	f.createBuildTemplate(ctx, tmpl)
+	f.createServiceAccount(ctx)
+
+	r := f.newReconciler(ctx)
+	f.updateIndex(ctx, b)
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+	f.updateBuildTemplateIndex(ctx, tmpl)
+
+	if err := r.Reconcile(context.Background(), getKey(b, t)); err == nil {
+		t.Errorf("Expect error syncing build")
+	}
+
+	buildClient := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace)
+	b, err := buildClient.Get(b.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("error fetching build: %v", err)
+	}
+	cond := b.Status.GetCondition(duckv1alpha1.ConditionSucceeded)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != "BuildValidationFailed" || !strings.Contains(cond.Message, "stepPolicies.when.step") {
+		t.Errorf("Unexpected build status %v", cond)
+	}
+}
+
+func TestStartBuildWithMissingConfigMap(t *testing.T) {
+	tmpl := &v1alpha1.BuildTemplate{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-template",
+			Namespace: metav1.NamespaceDefault,
+		},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Parameters: []v1alpha1.ParameterSpec{{Name: "replicas"}},
+		},
+	}
+	b := newBuild("test-missing-configmap")
+	b.Spec.Template = &v1alpha1.TemplateInstantiationSpec{
+		Kind: v1alpha1.BuildTemplateKind,
+		Name: tmpl.Name,
+		Arguments: []v1alpha1.ArgumentSpec{{
+			Name: "replicas",
+			ValueFrom: &v1alpha1.ArgumentValueSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+					Key:                  "replicas",
+				},
+			},
+		}},
+	}
+
+	f := &fixture{
+		t:       t,
+		objects: []runtime.Object{b, tmpl},
+	}
+	ctx, _ := rtesting.SetupFakeContext(t)
+	f.createBuildTemplate(ctx, tmpl)
+	f.updateBuildTemplateIndex(ctx, tmpl)
+	r := f.newReconciler(ctx).(*Reconciler)
+	builder, err := r.builderFor(b)
+	if err != nil {
+		t.Fatalf("builderFor() = %v", err)
+	}
+
+	// The ConfigMap may be deleted after the build was validated.
+	status, err := r.startBuild(builder, b)
+	if err == nil || !strings.Contains(err.Error(), "ConfigMapNotFound") {
+		t.Errorf("startBuild() = %v, want ConfigMapNotFound", err)
+	}
+	if d := cmp.Diff(b.Status, status); d != "" {
+		t.Errorf("startBuild() changed the status (-want, +got): %s", d)
+	}
+}
+
 func TestBasicFlows(t *testing.T) {
 	for _, c := range []struct {
 		desc          string
 		podStatus     corev1.PodStatus
 		wantCondition *duckv1alpha1.Condition
+		wantEvents    []string
 	}{{
 		desc:      "success",
 		podStatus: corev1.PodStatus{Phase: corev1.PodSucceeded},
@@ -428,6 +557,7 @@ func TestBasicFlows(t *testing.T) {
 			Type:   v1alpha1.BuildSucceeded,
 			Status: corev1.ConditionTrue,
 		},
+		wantEvents: []string{`Normal BuildSucceeded Build "success" succeeded`},
 	}, {
 		desc:      "running",
 		podStatus: corev1.PodStatus{Phase: corev1.PodRunning},
@@ -447,6 +577,7 @@ func TestBasicFlows(t *testing.T) {
 			Status:  corev1.ConditionFalse,
 			Message: "boom",
 		},
+		wantEvents: []string{"Warning BuildFailed boom"},
 	}, {
 		desc: "pending-waiting-message",
 		podStatus: corev1.PodStatus{
@@ -510,6 +641,10 @@ func TestBasicFlows(t *testing.T) {
 			}
 
 			podName := b.Status.Cluster.PodName
+			wantCreated := []string{fmt.Sprintf("Normal PodCreated Created pod %q", podName)}
+			if d := cmp.Diff(wantCreated, recordedEvents(ctx)); d != "" {
+				t.Errorf("Unexpected events (-want, +got): %s", d)
+			}
 			p, err := fakekubeclient.Get(ctx).CoreV1().Pods(metav1.NamespaceDefault).Get(podName, metav1.GetOptions{})
 			if err != nil {
 				t.Fatalf("error getting pod %q: %v", podName, err)
@@ -536,6 +671,9 @@ func TestBasicFlows(t *testing.T) {
 			if d := cmp.Diff(gotCondition, c.wantCondition, ignoreVolatileTime); d != "" {
 				t.Errorf("Unexpected build status %s", d)
 			}
+			if d := cmp.Diff(c.wantEvents, recordedEvents(ctx)); d != "" {
+				t.Errorf("Unexpected events (-want, +got): %s", d)
+			}
 		})
 	}
 }
@@ -588,6 +726,97 @@ func TestTimeoutFlow(t *testing.T) {
 	}, ignoreVolatileTime); d != "" {
 		t.Errorf("Unexpected build status %s", d)
 	}
+	events := recordedEvents(ctx)
+	if want := fmt.Sprintf("Warning BuildTimeout Build %q failed to finish within \"500ms\"", b.Name); len(events) == 0 || events[len(events)-1] != want {
+		t.Errorf("Got events %q, want last event %q", events, want)
+	}
+}
+
+// fakeGoogleBuilder is a Google builder whose builds run until they're
+// stopped.
+type fakeGoogleBuilder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (fb *fakeGoogleBuilder) Validate(*v1alpha1.Build) error { return nil }
+
+func (fb *fakeGoogleBuilder) Prepare(*v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	return v1alpha1.BuildStatus{
+		Builder:   v1alpha1.GoogleBuildProvider,
+		Google:    &v1alpha1.GoogleSpec{Operation: "operations/build/project/op"},
+		StartTime: &metav1.Time{Time: time.Now()},
+	}, nil
+}
+
+func (fb *fakeGoogleBuilder) IsStarted(status *v1alpha1.BuildStatus) bool {
+	return status.Google != nil && status.Google.Operation != ""
+}
+
+func (fb *fakeGoogleBuilder) Execute(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	return build.Status, nil
+}
+
+func (fb *fakeGoogleBuilder) Status(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	return build.Status, nil
+}
+
+func (fb *fakeGoogleBuilder) Stop(build *v1alpha1.Build) error {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.stopped = append(fb.stopped, build.Status.Google.Operation)
+	return nil
+}
+
+func (fb *fakeGoogleBuilder) stoppedOperations() []string {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return append([]string{}, fb.stopped...)
+}
+
+func TestTimeoutFlowGoogleBuilder(t *testing.T) {
+	b := newBuild("timeout-google")
+	b.Spec.Builder = v1alpha1.GoogleBuildProvider
+	b.Spec.Timeout = &metav1.Duration{Duration: 500 * time.Millisecond}
+
+	f := &fixture{
+		t:       t,
+		objects: []runtime.Object{b},
+	}
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f.createBuild(ctx, b)
+	f.createServiceAccount(ctx)
+
+	r := f.newReconciler(ctx)
+	gb := &fakeGoogleBuilder{}
+	r.(*Reconciler).googleBuilder = gb
+	f.updateIndex(ctx, b)
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	if err := r.Reconcile(context.Background(), getKey(b, t)); err != nil {
+		t.Errorf("Not Expect error when syncing build")
+	}
+
+	// The build's operation is cancelled when it times out.
+	waitUntil(t, func() bool { return len(gb.stoppedOperations()) > 0 })
+	if d := cmp.Diff([]string{"operations/build/project/op"}, gb.stoppedOperations()); d != "" {
+		t.Errorf("Unexpected stopped operations (-want, +got): %s", d)
+	}
+	buildClient := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace)
+	waitUntil(t, func() bool {
+		b, err := buildClient.Get(b.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error fetching build: %v", err)
+		}
+		cond := b.Status.GetCondition(duckv1alpha1.ConditionSucceeded)
+		return cond != nil && cond.Reason == "BuildTimeout"
+	})
 }
 
 func TestCancelledFlow(t *testing.T) {
@@ -649,4 +878,305 @@ func TestCancelledFlow(t *testing.T) {
 	}, ignoreVolatileTime); d != "" {
 		t.Errorf("Unexpected build status %s", d)
 	}
+	events := recordedEvents(ctx)
+	if want := fmt.Sprintf("Warning BuildCancelled Build %q was cancelled", b.Name); len(events) == 0 || events[len(events)-1] != want {
+		t.Errorf("Got events %q, want last event %q", events, want)
+	}
+}
+
+func TestBuildWithUnconfiguredGoogleBuilder(t *testing.T) {
+	b := newBuild("test-google")
+	b.Spec.Builder = v1alpha1.GoogleBuildProvider
+
+	f := &fixture{
+		t:       t,
+		objects: []runtime.Object{b},
+	}
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f.createBuild(ctx, b)
+	f.createServiceAccount(ctx)
+
+	r := f.newReconciler(ctx)
+	f.updateIndex(ctx, b)
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	if err := r.Reconcile(context.Background(), getKey(b, t)); err == nil {
+		t.Errorf("Expect error syncing build")
+	}
+
+	buildClient := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace)
+	b, err := buildClient.Get(b.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("error fetching build: %v", err)
+	}
+	if d := cmp.Diff(b.Status.GetCondition(duckv1alpha1.ConditionSucceeded), &duckv1alpha1.Condition{
+		Type:    duckv1alpha1.ConditionSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "BuildValidationFailed",
+		Message: "BuilderNotConfigured: the Google builder is not configured for this controller",
+	}, ignoreVolatileTime); d != "" {
+		t.Errorf("Unexpected build status %s", d)
+	}
+	wantEvents := []string{"Warning BuildValidationFailed BuilderNotConfigured: the Google builder is not configured for this controller"}
+	if d := cmp.Diff(wantEvents, recordedEvents(ctx)); d != "" {
+		t.Errorf("Unexpected events (-want, +got): %s", d)
+	}
+}
+
+func TestRetryFlow(t *testing.T) {
+	b := newBuild("retry")
+	b.Spec.Retries = 2
+	b.Spec.Steps = []corev1.Container{{Name: "step", Image: "busybox"}}
+
+	f := &fixture{
+		t:       t,
+		objects: []runtime.Object{b},
+	}
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f.createBuild(ctx, b)
+	f.createServiceAccount(ctx)
+
+	r := f.newReconciler(ctx)
+	f.updateIndex(ctx, b)
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	buildClient := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace)
+	podClient := fakekubeclient.Get(ctx).CoreV1().Pods(metav1.NamespaceDefault)
+
+	// failAttempt starts the build's pod, which is created at start, and
+	// fails it once its step finishes at finish.
+	failAttempt := func(start, finish metav1.Time) string {
+		t.Helper()
+		if err := r.Reconcile(ctx, getKey(b, t)); err != nil {
+			t.Fatalf("error syncing build: %v", err)
+		}
+		var err error
+		b, err = buildClient.Get(b.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error fetching build: %v", err)
+		}
+		if b.Status.Cluster == nil || b.Status.Cluster.PodName == "" {
+			t.Fatalf("build status did not specify podName: %v", b.Status.Cluster)
+		}
+		p, err := podClient.Get(b.Status.Cluster.PodName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error getting pod: %v", err)
+		}
+		p.CreationTimestamp = start
+		p.Status = corev1.PodStatus{
+			Phase:   corev1.PodFailed,
+			Message: "boom",
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "build-step-credential-initializer",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+			}, {
+				Name: "build-step-step",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					StartedAt:  start,
+					FinishedAt: finish,
+				}},
+			}},
+		}
+		if _, err := podClient.Update(p); err != nil {
+			t.Fatalf("error updating pod: %v", err)
+		}
+		f.updatePodIndex(ctx, p)
+		f.updateIndex(ctx, b)
+		if err := r.Reconcile(ctx, getKey(b, t)); err != nil {
+			t.Fatalf("error syncing build: %v", err)
+		}
+		b, err = buildClient.Get(b.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error fetching build: %v", err)
+		}
+		f.updateIndex(ctx, b)
+		return p.Name
+	}
+
+	at := func(minutes int) metav1.Time {
+		return metav1.NewTime(time.Date(2019, 4, 1, 12, minutes, 0, 0, time.UTC))
+	}
+	firstPod := failAttempt(at(0), at(5))
+	if d := cmp.Diff(b.Status.GetCondition(duckv1alpha1.ConditionSucceeded), &duckv1alpha1.Condition{
+		Type:    duckv1alpha1.ConditionSucceeded,
+		Status:  corev1.ConditionUnknown,
+		Reason:  "Retrying",
+		Message: "attempt 1 failed: boom",
+	}, ignoreVolatileTime); d != "" {
+		t.Errorf("Unexpected build status %s", d)
+	}
+	if len(b.Status.RetriesStatus) != 1 || b.Status.RetriesStatus[0].PodName != firstPod {
+		t.Errorf("RetriesStatus = %v, want one attempt with pod %q", b.Status.RetriesStatus, firstPod)
+	}
+
+	secondPod := failAttempt(at(10), at(12))
+	if secondPod == firstPod {
+		t.Errorf("retry reused pod name %q", firstPod)
+	}
+	// Each attempt records its own times.
+	for i, want := range []struct{ start, completion metav1.Time }{
+		{at(0), at(5)},
+		{at(10), at(12)},
+	} {
+		if got := b.Status.RetriesStatus[i]; !got.StartTime.Equal(&want.start) || !got.CompletionTime.Equal(&want.completion) {
+			t.Errorf("Attempt %d ran from %v to %v, want %v to %v", i+1, got.StartTime, got.CompletionTime, want.start, want.completion)
+		}
+	}
+
+	failAttempt(at(20), at(21))
+	if d := cmp.Diff(b.Status.GetCondition(duckv1alpha1.ConditionSucceeded), &duckv1alpha1.Condition{
+		Type:    duckv1alpha1.ConditionSucceeded,
+		Status:  corev1.ConditionFalse,
+		Message: "boom",
+	}, ignoreVolatileTime); d != "" {
+		t.Errorf("Unexpected build status %s", d)
+	}
+	if len(b.Status.RetriesStatus) != 2 {
+		t.Errorf("RetriesStatus = %v, want two attempts", b.Status.RetriesStatus)
+	}
+}
+
+func TestCacheFlow(t *testing.T) {
+	withCache := func(name string) *v1alpha1.Build {
+		b := newBuild(name)
+		b.UID = types.UID(name + "-uid")
+		b.Spec.Caches = []v1alpha1.CacheSpec{{
+			Name:      "deps",
+			MountPath: "/deps",
+			Key:       "abc123",
+		}}
+		return b
+	}
+	first, second, third, fourth := withCache("first"), withCache("second"), withCache("third"), withCache("fourth")
+
+	f := &fixture{
+		t:       t,
+		objects: []runtime.Object{first, second, third, fourth},
+	}
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f.createBuild(ctx, first, second, third, fourth)
+	f.createServiceAccount(ctx)
+
+	r := f.newReconciler(ctx)
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	buildClient := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(metav1.NamespaceDefault)
+	podClient := fakekubeclient.Get(ctx).CoreV1().Pods(metav1.NamespaceDefault)
+	claimClient := fakekubeclient.Get(ctx).CoreV1().PersistentVolumeClaims(metav1.NamespaceDefault)
+
+	// start reconciles the build and returns its updated copy.
+	start := func(b *v1alpha1.Build) *v1alpha1.Build {
+		t.Helper()
+		f.updateIndex(ctx, b)
+		if err := r.Reconcile(ctx, getKey(b, t)); err != nil {
+			t.Fatalf("error syncing build: %v", err)
+		}
+		b, err := buildClient.Get(b.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error fetching build: %v", err)
+		}
+		f.updateIndex(ctx, b)
+		return b
+	}
+	// wantCache checks the build's cache status and its pod's volume.
+	wantCache := func(b *v1alpha1.Build, readOnly bool) {
+		t.Helper()
+		want := []v1alpha1.CacheStatus{{
+			Name:      "deps",
+			ClaimName: "build-cache-deps-6ca13d52ca70c883",
+			ReadOnly:  readOnly,
+		}}
+		if d := cmp.Diff(want, b.Status.Caches); d != "" {
+			t.Errorf("Caches diff of build %q:\n%s", b.Name, d)
+		}
+		p, err := podClient.Get(b.Status.Cluster.PodName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error getting pod: %v", err)
+		}
+		var found bool
+		for _, v := range p.Spec.Volumes {
+			if c := v.PersistentVolumeClaim; c != nil && c.ClaimName == want[0].ClaimName {
+				found = true
+				if c.ReadOnly != readOnly {
+					t.Errorf("cache volume of build %q has ReadOnly %t, want %t", b.Name, c.ReadOnly, readOnly)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("pod of build %q does not mount the cache: %v", b.Name, p.Spec.Volumes)
+		}
+	}
+	wantHolder := func(holder string) {
+		t.Helper()
+		claim, err := claimClient.Get("build-cache-deps-6ca13d52ca70c883", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error getting claim: %v", err)
+		}
+		if got := claim.Annotations["build.knative.dev/cacheHolder"]; got != holder {
+			t.Errorf("cache holder = %q, want %q", got, holder)
+		}
+	}
+
+	// The first build provisions the cache and may write to it.
+	first = start(first)
+	wantCache(first, false)
+	wantHolder("first-uid")
+	// Its claim may be mounted by builds on other nodes.
+	claim, err := claimClient.Get("build-cache-deps-6ca13d52ca70c883", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting claim: %v", err)
+	}
+	if d := cmp.Diff([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, claim.Spec.AccessModes); d != "" {
+		t.Errorf("AccessModes diff of cache claim:\n%s", d)
+	}
+
+	// A concurrent build shares the cache read-only.
+	second = start(second)
+	wantCache(second, true)
+	wantHolder("first-uid")
+
+	// Once the first build is done, it releases the cache.
+	p, err := podClient.Get(first.Status.Cluster.PodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting pod: %v", err)
+	}
+	p.Status.Phase = corev1.PodSucceeded
+	f.updatePodIndex(ctx, p)
+	first = start(first)
+	if !isDone(&first.Status) {
+		t.Fatalf("build %q isn't done: %v", first.Name, first.Status)
+	}
+	wantHolder("")
+
+	// So a later build may write to it.
+	third = start(third)
+	wantCache(third, false)
+	wantHolder("third-uid")
+
+	// A build recreated with the name of the holder doesn't inherit its
+	// hold, so once the holder is deleted, another build takes it over.
+	recreated := withCache("third")
+	recreated.UID = "recreated-uid"
+	f.updateIndex(ctx, recreated)
+	fourth = start(fourth)
+	wantCache(fourth, false)
+	wantHolder("fourth-uid")
 }
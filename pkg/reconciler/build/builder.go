@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"flag"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+)
+
+var (
+	// The builder used for Builds that don't specify one.
+	defaultBuilder = flag.String("builder", string(v1alpha1.ClusterBuildProvider),
+		"The builder used to execute Builds that don't specify one (Cluster or Google).")
+	// The GCP project in which Builds are executed by the Google builder.
+	gcbProject = flag.String("gcb-project", "",
+		"The GCP project in which the Google builder executes Builds; if empty, the Google builder is disabled.")
+	// The base URL of the Cloud Build API.
+	gcbEndpoint = flag.String("gcb-endpoint", "https://cloudbuild.googleapis.com/",
+		"The base URL of the Google Cloud Build API.")
+)
+
+// Builder is implemented by each of the backends that can execute a Build.
+type Builder interface {
+	// Validate checks that the build can be executed by this builder.
+	Validate(build *v1alpha1.Build) error
+
+	// Prepare returns the status to record for the build before it is
+	// executed. Builders whose IsStarted reports prepared builds as
+	// started must be able to track them before Execute succeeds.
+	Prepare(build *v1alpha1.Build) (v1alpha1.BuildStatus, error)
+
+	// IsStarted returns true if the status shows that the build was
+	// handed to this builder.
+	IsStarted(status *v1alpha1.BuildStatus) bool
+
+	// Execute starts executing the build, whose template has already
+	// been applied, and returns its initial status.
+	Execute(build *v1alpha1.Build) (v1alpha1.BuildStatus, error)
+
+	// Status returns the current status of a started build.
+	Status(build *v1alpha1.Build) (v1alpha1.BuildStatus, error)
+
+	// Stop terminates a started build.
+	Stop(build *v1alpha1.Build) error
+}
+
+// Poller is implemented by builders whose progress isn't observed through
+// informers, and so must be polled while a build executes.
+type Poller interface {
+	// PollInterval returns how often an executing build is reconciled.
+	PollInterval() time.Duration
+}
+
+// builderFor returns the Builder that executes the build. Builds that have
+// started keep the builder that started them.
+func (c *Reconciler) builderFor(build *v1alpha1.Build) (Builder, error) {
+	provider := build.Status.Builder
+	if provider == "" {
+		provider = build.Spec.Builder
+	}
+	if provider == "" {
+		provider = v1alpha1.BuildProvider(*defaultBuilder)
+	}
+
+	switch provider {
+	case v1alpha1.ClusterBuildProvider:
+		return &clusterBuilder{
+			kubeclientset: c.kubeclientset,
+			podsLister:    c.podsLister,
+			buildsLister:  c.buildsLister,
+			logger:        c.Logger,
+			enqueueAfter:  c.enqueueAfter,
+		}, nil
+	case v1alpha1.GoogleBuildProvider:
+		if c.googleBuilder == nil {
+			return nil, validationError("BuilderNotConfigured", "the Google builder is not configured for this controller")
+		}
+		return c.googleBuilder, nil
+	default:
+		return nil, validationError("UnknownBuilder", "unknown builder %q", provider)
+	}
+}
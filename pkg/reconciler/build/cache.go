@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/reconciler/build/resources"
+)
+
+// acquireCaches provisions or reuses the claims that hold the build's
+// caches. The build holds each cache that no other running build holds,
+// and may write to it; the caches other builds hold are mounted read-only.
+func (cb *clusterBuilder) acquireCaches(build *v1alpha1.Build) ([]v1alpha1.CacheStatus, error) {
+	var statuses []v1alpha1.CacheStatus
+	for _, c := range build.Spec.Caches {
+		claim, err := resources.MakeCacheClaim(build, c)
+		if err != nil {
+			return nil, err
+		}
+		status := v1alpha1.CacheStatus{
+			Name:      c.Name,
+			ClaimName: claim.Name,
+		}
+		if _, err := cb.kubeclientset.CoreV1().PersistentVolumeClaims(build.Namespace).Create(claim); err == nil {
+			cb.logger.Infof("Provisioned cache %q for build %q", claim.Name, build.Name)
+		} else if errors.IsAlreadyExists(err) {
+			held, err := cb.holdCache(build, claim.Name)
+			if err != nil {
+				return nil, err
+			}
+			status.ReadOnly = !held
+		} else {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// holdCache tries to make the build the holder of the existing claim, and
+// returns whether it succeeded. Claims held by builds that are done or have
+// been deleted, even if a build with the same name has since been created,
+// are taken over.
+func (cb *clusterBuilder) holdCache(build *v1alpha1.Build, claimName string) (bool, error) {
+	claims := cb.kubeclientset.CoreV1().PersistentVolumeClaims(build.Namespace)
+	claim, err := claims.Get(claimName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	holder := claim.Annotations[resources.CacheHolderAnnotationKey]
+	if holder == string(build.UID) {
+		return true, nil
+	}
+	if holder != "" && cb.isRunning(build.Namespace, types.UID(holder)) {
+		return false, nil
+	}
+
+	if claim.Annotations == nil {
+		claim.Annotations = map[string]string{}
+	}
+	claim.Annotations[resources.CacheHolderAnnotationKey] = string(build.UID)
+	if _, err := claims.Update(claim); err != nil {
+		// Another build took hold of the cache first.
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// isRunning returns true if the build with the UID exists and isn't done.
+func (cb *clusterBuilder) isRunning(namespace string, uid types.UID) bool {
+	builds, err := cb.buildsLister.Builds(namespace).List(labels.Everything())
+	if err != nil {
+		return false
+	}
+	for _, b := range builds {
+		if b.UID == uid {
+			return !isDone(&b.Status)
+		}
+	}
+	return false
+}
+
+// releaseCaches lets other builds write to the caches the build holds.
+func (cb *clusterBuilder) releaseCaches(build *v1alpha1.Build) error {
+	claims := cb.kubeclientset.CoreV1().PersistentVolumeClaims(build.Namespace)
+	for _, c := range build.Status.Caches {
+		if c.ReadOnly {
+			continue
+		}
+		claim, err := claims.Get(c.ClaimName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if claim.Annotations[resources.CacheHolderAnnotationKey] != string(build.UID) {
+			continue
+		}
+		delete(claim.Annotations, resources.CacheHolderAnnotationKey)
+		if _, err := claims.Update(claim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	listers "github.com/knative/build/pkg/client/listers/build/v1alpha1"
+	"github.com/knative/build/pkg/reconciler/build/resources"
+)
+
+// clusterBuilder executes Builds as Pods in the Build's namespace.
+type clusterBuilder struct {
+	kubeclientset kubernetes.Interface
+	podsLister    corelisters.PodLister
+	logger        *zap.SugaredLogger
+	// buildsLister finds whether the builds holding caches are running.
+	buildsLister listers.BuildLister
+	// enqueueAfter requeues a build when its running step will time out.
+	enqueueAfter func(interface{}, time.Duration)
+}
+
+var _ Builder = (*clusterBuilder)(nil)
+
+// Validate ensures the build can be translated to a Pod.
+func (cb *clusterBuilder) Validate(build *v1alpha1.Build) error {
+	_, err := resources.MakePod(build, cb.kubeclientset)
+	return err
+}
+
+// Prepare picks the name of the build's pod.
+func (cb *clusterBuilder) Prepare(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	// Add a unique suffix to avoid confusion when a build
+	// is deleted and re-created with the same name.
+	// We don't use GenerateName here because k8s fakes don't support it.
+	podName, err := resources.GetUniquePodName(build.Name)
+	if err != nil {
+		return v1alpha1.BuildStatus{}, err
+	}
+	return v1alpha1.BuildStatus{
+		Builder: v1alpha1.ClusterBuildProvider,
+		Cluster: &v1alpha1.ClusterSpec{
+			Namespace: build.Namespace,
+			PodName:   podName,
+		},
+		StartTime: &metav1.Time{
+			Time: time.Now(),
+		},
+	}, nil
+}
+
+// IsStarted returns true if a pod name has been picked for the build.
+func (cb *clusterBuilder) IsStarted(status *v1alpha1.BuildStatus) bool {
+	return status.Cluster != nil && status.Cluster.PodName != ""
+}
+
+// Execute provisions the build's caches and creates its pod.
+func (cb *clusterBuilder) Execute(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	caches, err := cb.acquireCaches(build)
+	if err != nil {
+		return build.Status, err
+	}
+	build.Status.Caches = caches
+	p, err := resources.MakePod(build, cb.kubeclientset)
+	if err == nil {
+		cb.logger.Infof("Creating pod %q in namespace %q for build %q", p.Name, p.Namespace, build.Name)
+		p, err = cb.kubeclientset.CoreV1().Pods(p.Namespace).Create(p)
+	}
+	if err != nil {
+		if releaseErr := cb.releaseCaches(build); releaseErr != nil {
+			cb.logger.Errorf("Failed to release caches of build %q: %v", build.Name, releaseErr)
+		}
+		return build.Status, err
+	}
+	status := resources.BuildStatusFromPod(p, build.Spec)
+	status.Caches = caches
+	return status, nil
+}
+
+// Status returns the build's status based on its pod.
+func (cb *clusterBuilder) Status(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	p, err := cb.podsLister.Pods(build.Namespace).Get(build.Status.Cluster.PodName)
+	if err != nil {
+		// TODO: What if the pod is deleted out from under us?
+		return build.Status, err
+	}
+	// Nothing about the pod changes when a step times out, so check back
+	// when it would.
+	if d, ok := resources.NextStepTimeout(p, time.Now()); ok && cb.enqueueAfter != nil {
+		cb.enqueueAfter(build, d)
+	}
+	status := resources.BuildStatusFromPod(p, build.Spec)
+	status.Caches = build.Status.Caches
+	// Sidecars keep running after the build's steps finish, so stop them
+	// to let the pod complete.
+	if isDone(&status) {
+		if stopped := resources.StopSidecars(p); stopped != nil {
+			cb.logger.Infof("Stopping sidecars of pod %q for build %q", p.Name, build.Name)
+			if _, err := cb.kubeclientset.CoreV1().Pods(p.Namespace).Update(stopped); err != nil {
+				return build.Status, err
+			}
+		}
+		if err := cb.releaseCaches(build); err != nil {
+			return build.Status, err
+		}
+	}
+	return status, nil
+}
+
+// Stop deletes the build's pod and releases its caches.
+func (cb *clusterBuilder) Stop(build *v1alpha1.Build) error {
+	if err := cb.releaseCaches(build); err != nil {
+		return err
+	}
+	if build.Status.Cluster == nil {
+		cb.logger.Warnf("build %q has no pod running yet", build.Name)
+		return nil
+	}
+	return cb.kubeclientset.CoreV1().Pods(build.Namespace).Delete(build.Status.Cluster.PodName, &metav1.DeleteOptions{})
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"text/template"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/commitstatus"
+	"github.com/knative/build/pkg/credentials/gitcreds"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// commitStatusAttempts is how many times posting a commit status is
+	// attempted; the delay between attempts starts at commitStatusBackoff
+	// and doubles after each.
+	commitStatusAttempts = 5
+	commitStatusBackoff  = time.Second
+	// commitStatusTimeout bounds each request that posts a commit status,
+	// or that mints the token to post it with.
+	commitStatusTimeout = 10 * time.Second
+	// defaultCommitStatusContext is the context of statuses whose source
+	// doesn't specify one.
+	defaultCommitStatusContext = "knative-build"
+)
+
+// commitStatusTransitions posts commit statuses for the build's Git
+// sources that ask for them: pending once the commit of a source is known,
+// and the build's outcome once it finishes. started is true if the build's
+// current attempt was started by this reconcile.
+func (c *Reconciler) commitStatusTransitions(build *v1alpha1.Build, previous v1alpha1.BuildStatus, started bool) {
+	if isDone(&build.Status) {
+		if !isDone(&previous) {
+			c.postCommitStatuses(build)
+		}
+		return
+	}
+	for _, source := range gitSources(build.Spec) {
+		commit := sourceCommit(build.Status, source)
+		if commit == "" || (!started && sourceCommit(previous, source) != "") {
+			continue
+		}
+		c.postCommitStatus(build, source, commit, commitstatus.Pending, "Build is running")
+	}
+}
+
+// postCommitStatuses posts the outcome of the finished build for its Git
+// sources that ask for it. No status is posted for a source whose commit
+// isn't known: one whose revision isn't a commit SHA, of a build that
+// finished, for example by timing out, before the source was fetched.
+func (c *Reconciler) postCommitStatuses(build *v1alpha1.Build) {
+	state, description := commitstatus.Failure, "Build failed"
+	if cond := build.Status.GetCondition(v1alpha1.BuildSucceeded); cond != nil {
+		switch outcome(cond.Status, cond.Reason) {
+		case outcomeSucceeded:
+			state, description = commitstatus.Success, "Build succeeded"
+		case outcomeCancelled:
+			state = commitstatus.Error
+		}
+		if state != commitstatus.Success && cond.Message != "" {
+			description = cond.Message
+		}
+	}
+	for _, source := range gitSources(build.Spec) {
+		if commit := sourceCommit(build.Status, source); commit != "" {
+			c.postCommitStatus(build, source, commit, state, description)
+		}
+	}
+}
+
+// postCommitStatus queues a status for the commit of the source, which is
+// posted in the background with a token from the Git credentials of the
+// build's ServiceAccount. Failures are recorded in an Event, and don't
+// affect the build.
+func (c *Reconciler) postCommitStatus(build *v1alpha1.Build, source v1alpha1.SourceSpec, commit string, state commitstatus.State, description string) {
+	if c.commitStatuses == nil {
+		return
+	}
+	spec := source.Git.CommitStatus
+	u := commitstatus.Update{
+		Build:    build.DeepCopy(),
+		Provider: spec.Provider,
+		APIURL:   spec.APIURL,
+		RepoURL:  source.Git.Url,
+		Commit:   commit,
+		Status: commitstatus.Status{
+			State:       state,
+			Context:     spec.Context,
+			Description: description,
+		},
+	}
+	if u.Status.Context == "" {
+		u.Status.Context = defaultCommitStatusContext
+	}
+	target, err := commitStatusTarget(build, spec.TargetURL, commit)
+	if err != nil {
+		c.recordCommitStatusFailure(u, err)
+		return
+	}
+	u.Status.TargetURL = target
+	c.commitStatuses.Send(build.Namespace+"/"+build.Name, u)
+}
+
+// recordCommitStatusFailure records in an Event that a commit status of the
+// build couldn't be posted.
+func (c *Reconciler) recordCommitStatusFailure(u commitstatus.Update, err error) {
+	c.Logger.Warnf("Failed to post %s commit status of build %q for %s: %v", u.Status.State, u.Build.Name, u.RepoURL, err)
+	c.recorder.Eventf(u.Build, corev1.EventTypeWarning, "CommitStatusFailed", "Failed to post %s status for commit %s: %v", u.Status.State, u.Commit, err)
+}
+
+// commitStatusToken returns the API token with which the update is posted,
+// which is chosen by the host of the API it's posted to rather than that of
+// the repository, so that it's only sent where its secret is meant for.
+func (c *Reconciler) commitStatusToken(u commitstatus.Update) (string, error) {
+	return c.gitAPIToken(u.Build, commitstatus.CredentialsURL(u.Provider, u.APIURL))
+}
+
+// gitAPIToken returns the API token for the Git host at hostURL from the
+// first of the secrets of the build's ServiceAccount that is annotated for
+// it.
+func (c *Reconciler) gitAPIToken(build *v1alpha1.Build, hostURL string) (string, error) {
+	saName := build.Spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	sa, err := c.kubeclientset.CoreV1().ServiceAccounts(build.Namespace).Get(saName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, se := range sa.Secrets {
+		secret, err := c.kubeclientset.CoreV1().Secrets(build.Namespace).Get(se.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		token, ok, err := gitcreds.APIToken(c.commitStatuses.Client, c.gitTokens, secret, hostURL)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("no secret of ServiceAccount %q holds an API token for %s", saName, hostURL)
+}
+
+// commitStatusTarget renders the target URL template of a commit status.
+func commitStatusTarget(build *v1alpha1.Build, text, commit string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("targetURL").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	data := struct {
+		Namespace, Name, Commit, PodName string
+	}{
+		Namespace: build.Namespace,
+		Name:      build.Name,
+		Commit:    commit,
+	}
+	if build.Status.Cluster != nil {
+		data.PodName = build.Status.Cluster.PodName
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// gitSources returns the build's Git sources that ask for commit statuses.
+func gitSources(spec v1alpha1.BuildSpec) []v1alpha1.SourceSpec {
+	var sources []v1alpha1.SourceSpec
+	if spec.Source != nil {
+		sources = append(sources, *spec.Source)
+	}
+	sources = append(sources, spec.Sources...)
+	var git []v1alpha1.SourceSpec
+	for _, s := range sources {
+		if s.Git != nil && s.Git.CommitStatus != nil {
+			git = append(git, s)
+		}
+	}
+	return git
+}
+
+// sourceCommit returns the commit that the Git source resolved to, or its
+// revision if that is a full commit SHA, or "" if neither is known.
+func sourceCommit(status v1alpha1.BuildStatus, source v1alpha1.SourceSpec) string {
+	for _, s := range status.SourcesStatus {
+		if s.Name == source.Name && s.Commit != "" {
+			return s.Commit
+		}
+	}
+	if rev := source.Git.Revision; len(rev) == 40 {
+		if _, err := hex.DecodeString(rev); err == nil {
+			return rev
+		}
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	fakebuildclient "github.com/knative/build/pkg/client/injection/client/fake"
+	"github.com/knative/build/pkg/credentials/gitcreds"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	"github.com/knative/pkg/controller"
+	fakekubeclient "github.com/knative/pkg/injection/clients/kubeclient/fake"
+	rtesting "github.com/knative/pkg/reconciler/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+// statusAPI is a fake GitHub API that records the commit statuses posted
+// to it.
+type statusAPI struct {
+	mu       sync.Mutex
+	statuses []map[string]string
+}
+
+func (s *statusAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/repos/owner/repo/statuses/"+testCommit || r.Header.Get("Authorization") != "Bearer secret-token" {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	var status map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.statuses = append(s.statuses, status)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *statusAPI) received() []map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]string{}, s.statuses...)
+}
+
+// commitStatusBuild returns a build of a GitHub repository that posts its
+// commit statuses to apiURL.
+func commitStatusBuild(name, apiURL string) *v1alpha1.Build {
+	b := newBuild(name)
+	b.Spec.Source = &v1alpha1.SourceSpec{
+		Git: &v1alpha1.GitSourceSpec{
+			Url:      "https://github.com/owner/repo.git",
+			Revision: testCommit,
+			CommitStatus: &v1alpha1.CommitStatusSpec{
+				APIURL:    apiURL,
+				TargetURL: "https://ci.example.com/{{.Namespace}}/{{.Name}}/{{.PodName}}",
+			},
+		},
+	}
+	return b
+}
+
+// addTokenSecret adds a token secret for the host of hostURL to the default
+// ServiceAccount.
+func addTokenSecret(t *testing.T, ctx context.Context, hostURL string) {
+	t.Helper()
+	kube := fakekubeclient.Get(ctx).CoreV1()
+	if _, err := kube.Secrets(metav1.NamespaceDefault).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "github-token",
+			Annotations: map[string]string{"build.knative.dev/git-0": hostURL},
+		},
+		Type: gitcreds.TokenSecretType,
+		Data: map[string][]byte{gitcreds.TokenKey: []byte("secret-token")},
+	}); err != nil {
+		t.Fatalf("Failed to create Secret: %v", err)
+	}
+	sa, err := kube.ServiceAccounts(metav1.NamespaceDefault).Get("default", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get ServiceAccount: %v", err)
+	}
+	sa.Secrets = append(sa.Secrets, corev1.ObjectReference{Name: "github-token"})
+	if _, err := kube.ServiceAccounts(metav1.NamespaceDefault).Update(sa); err != nil {
+		t.Fatalf("Failed to update ServiceAccount: %v", err)
+	}
+}
+
+func TestCommitStatusFlow(t *testing.T) {
+	api := &statusAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	b := commitStatusBuild("commit-status", server.URL)
+	runBuild(t, ctx, b, corev1.PodSucceeded, func(*Reconciler) { addTokenSecret(t, ctx, server.URL) })
+
+	waitUntil(t, func() bool { return len(api.received()) >= 2 })
+	statuses := api.received()
+	if len(statuses) != 2 {
+		t.Fatalf("Received %d commit statuses, want 2: %v", len(statuses), statuses)
+	}
+	for i, want := range []string{"pending", "success"} {
+		s := statuses[i]
+		if s["state"] != want || s["context"] != defaultCommitStatusContext || !strings.HasPrefix(s["target_url"], "https://ci.example.com/default/commit-status/commit-status-pod-") {
+			t.Errorf("Unexpected commit status %d: %v", i, s)
+		}
+	}
+}
+
+func TestCommitStatusTimeout(t *testing.T) {
+	api := &statusAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx, _ := rtesting.SetupFakeContext(t)
+	f := &fixture{t: t}
+	f.createServiceAccount(ctx)
+	addTokenSecret(t, ctx, server.URL)
+	r := f.newReconciler(ctx).(*Reconciler)
+
+	// The timeout handler calls finished with builds it stops.
+	b := commitStatusBuild("commit-status-timeout", server.URL)
+	b.Spec.Source.Git.Revision = "master"
+	b.Status.SourcesStatus = []v1alpha1.SourceStatus{{Commit: testCommit}}
+	b.Status.SetCondition(&duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "BuildTimeout",
+		Message: "Build \"commit-status-timeout\" failed to finish within \"20m0s\"",
+	})
+	r.finished(b)
+
+	waitUntil(t, func() bool { return len(api.received()) >= 1 })
+	want := []map[string]string{{
+		"state":       "failure",
+		"context":     defaultCommitStatusContext,
+		"description": "Build \"commit-status-timeout\" failed to finish within \"20m0s\"",
+		"target_url":  "https://ci.example.com/default/commit-status-timeout/",
+	}}
+	if d := cmp.Diff(want, api.received()); d != "" {
+		t.Errorf("Unexpected commit statuses (-want, +got): %s", d)
+	}
+}
+
+func TestCommitStatusNoCredentials(t *testing.T) {
+	api := &statusAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	// The token for the repository's host isn't sent to an API on another
+	// host.
+	b := commitStatusBuild("commit-status-no-creds", server.URL)
+	runBuild(t, ctx, b, corev1.PodSucceeded, func(r *Reconciler) {
+		addTokenSecret(t, ctx, "https://github.com")
+		r.commitStatuses.Attempts = 2
+		r.commitStatuses.Backoff = time.Millisecond
+	})
+
+	var warnings int
+	waitUntil(t, func() bool {
+		for _, e := range recordedEvents(ctx) {
+			if strings.HasPrefix(e, "Warning CommitStatusFailed ") {
+				warnings++
+			}
+		}
+		return warnings >= 2
+	})
+	if warnings != 2 {
+		t.Errorf("Recorded %d CommitStatusFailed events, want 2", warnings)
+	}
+	if got := len(api.received()); got != 0 {
+		t.Errorf("Received %d commit statuses, want 0", got)
+	}
+	b, err := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace).Get(b.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching build: %v", err)
+	}
+	if !isDone(&b.Status) || b.Status.GetCondition(v1alpha1.BuildSucceeded).Status != corev1.ConditionTrue {
+		t.Errorf("Build didn't succeed: %+v", b.Status)
+	}
+}
@@ -18,6 +18,7 @@ package build
 
 import (
 	"context"
+	"net/http"
 
 	buildclient "github.com/knative/build/pkg/client/injection/client"
 	buildinformer "github.com/knative/build/pkg/client/injection/informers/build/v1alpha1/build"
@@ -27,16 +28,27 @@ import (
 	podinformer "github.com/knative/pkg/injection/informers/kubeinformers/corev1/pod"
 
 	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/commitstatus"
+	"github.com/knative/build/pkg/credentials/gitcreds"
+	"github.com/knative/build/pkg/notify"
+	"github.com/knative/build/pkg/reconciler/build/google"
 	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
 	"github.com/knative/pkg/logging"
 	"github.com/knative/pkg/logging/logkey"
 	"go.uber.org/zap"
+	googleoauth "golang.org/x/oauth2/google"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
 	controllerAgentName = "build-controller"
+	cloudPlatformScope  = "https://www.googleapis.com/auth/cloud-platform"
 )
 
 // NewController returns a new build controller
@@ -53,12 +65,19 @@ func NewController(
 	buildTemplateInformer := buildtemplateinformer.Get(ctx)
 	clusterBuildTemplateInformer := clusterbuildtemplateinformer.Get(ctx)
 
-	timeoutHandler := NewTimeoutHandler(logger, kubeclientset, buildclientset, ctx.Done())
-	timeoutHandler.CheckTimeouts()
-
 	// Enrich the logs with controller name
 	logger = logger.Named(controllerAgentName).With(zap.String(logkey.ControllerType, controllerAgentName))
 
+	// Tests supply their own recorder through the context.
+	recorder := controller.GetEventRecorder(ctx)
+	if recorder == nil {
+		logger.Debug("Creating event broadcaster")
+		eventBroadcaster := record.NewBroadcaster()
+		eventBroadcaster.StartLogging(logger.Named("event-broadcaster").Infof)
+		eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+		recorder = eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+	}
+
 	r := &Reconciler{
 		kubeclientset:               kubeclientset,
 		buildclientset:              buildclientset,
@@ -67,9 +86,49 @@ func NewController(
 		clusterBuildTemplatesLister: clusterBuildTemplateInformer.Lister(),
 		podsLister:                  podInformer.Lister(),
 		Logger:                      logger,
-		timeoutHandler:              timeoutHandler,
+		recorder:                    recorder,
+		stats:                       NewStatsReporter(),
+		gitTokens:                   &gitcreds.TokenCache{},
+	}
+	r.notifier = &notify.Sender{
+		Client:   &http.Client{Timeout: notifyTimeout},
+		Attempts: notifyAttempts,
+		Backoff:  notifyBackoff,
+		Failed:   r.recordNotificationFailure,
+	}
+	r.commitStatuses = &commitstatus.Sender{
+		Client:   &http.Client{Timeout: commitStatusTimeout},
+		Token:    r.commitStatusToken,
+		Attempts: commitStatusAttempts,
+		Backoff:  commitStatusBackoff,
+		Failed:   r.recordCommitStatusFailure,
+	}
+	if *gcbProject != "" {
+		hc, err := googleoauth.DefaultClient(ctx, cloudPlatformScope)
+		if err != nil {
+			logger.Fatalw("Failed to create Google Cloud Build client", zap.Error(err))
+		}
+		r.googleBuilder = google.NewBuilder(&google.Client{
+			HTTPClient: hc,
+			Endpoint:   *gcbEndpoint,
+		}, *gcbProject)
 	}
+	// Builds that time out are stopped by their builders, so the timeout
+	// handler is started once the builders are configured.
+	r.timeoutHandler = NewTimeoutHandler(logger, kubeclientset, buildclientset, r.stop, r.finished, ctx.Done())
+	r.timeoutHandler.CheckTimeouts()
 	impl := controller.NewImpl(r, logger, "Builds")
+	r.enqueueAfter = impl.EnqueueAfter
+
+	// Periodically report the number of active builds, once the builds
+	// informer has synced.
+	go func() {
+		if !cache.WaitForCacheSync(ctx.Done(), buildInformer.Informer().HasSynced) {
+			return
+		}
+		reported := map[string]bool{}
+		wait.Until(func() { r.reportActiveBuilds(reported) }, activeBuildsInterval, ctx.Done())
+	}()
 
 	logger.Info("Setting up event handlers")
 	// Set up an event handler for when Build resources change
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// recordStarted records an Event for what the builder created to execute
+// the build.
+func recordStarted(recorder record.EventRecorder, build *v1alpha1.Build, status v1alpha1.BuildStatus) {
+	switch {
+	case status.Cluster != nil && status.Cluster.PodName != "":
+		recorder.Eventf(build, corev1.EventTypeNormal, "PodCreated", "Created pod %q", status.Cluster.PodName)
+	case status.Google != nil && status.Google.Operation != "":
+		recorder.Eventf(build, corev1.EventTypeNormal, "OperationCreated", "Created operation %q", status.Google.Operation)
+	}
+}
+
+// recordTransitions records Events for the changes between the previous
+// and the current status of the build: its steps starting or finishing,
+// and the build itself finishing.
+func recordTransitions(recorder record.EventRecorder, build *v1alpha1.Build, previous v1alpha1.BuildStatus) {
+	for i, state := range build.Status.StepStates {
+		var was corev1.ContainerState
+		if i < len(previous.StepStates) {
+			was = previous.StepStates[i]
+		}
+		if was.Terminated != nil {
+			continue
+		}
+		step := stepName(build, i)
+		term := state.Terminated
+		if term != nil && term.Reason == "Skipped" {
+			recorder.Eventf(build, corev1.EventTypeNormal, "StepSkipped", "Step %s was skipped", step)
+			continue
+		}
+		if was.Running == nil && (state.Running != nil || term != nil) {
+			recorder.Eventf(build, corev1.EventTypeNormal, "StepStarted", "Step %s started", step)
+		}
+		switch {
+		case term == nil:
+		case term.ExitCode != 0:
+			recorder.Eventf(build, corev1.EventTypeWarning, "StepFailed", "Step %s failed with exit code %d", step, term.ExitCode)
+		default:
+			recorder.Eventf(build, corev1.EventTypeNormal, "StepFinished", "Step %s finished", step)
+		}
+	}
+	if isDone(&build.Status) && !isDone(&previous) {
+		recordDone(recorder, build)
+	}
+}
+
+// recordDone records an Event for the build finishing, whose reason and
+// message are those of its Succeeded condition.
+func recordDone(recorder record.EventRecorder, build *v1alpha1.Build) {
+	cond := build.Status.GetCondition(v1alpha1.BuildSucceeded)
+	if cond == nil {
+		return
+	}
+	switch cond.Status {
+	case corev1.ConditionTrue:
+		recorder.Eventf(build, corev1.EventTypeNormal, "BuildSucceeded", "Build %q succeeded", build.Name)
+	case corev1.ConditionFalse:
+		reason, msg := cond.Reason, cond.Message
+		if reason == "" {
+			reason = "BuildFailed"
+		}
+		if msg == "" {
+			msg = fmt.Sprintf("Build %q failed", build.Name)
+		}
+		recorder.Event(build, corev1.EventTypeWarning, reason, msg)
+	}
+}
+
+// stepName names the build's i-th step in Events. Steps that come from a
+// template aren't in the build's spec, so those are numbered instead.
+func stepName(build *v1alpha1.Build, i int) string {
+	if build.Spec.Template == nil && i < len(build.Spec.Steps) && build.Spec.Steps[i].Name != "" {
+		return fmt.Sprintf("%q", build.Spec.Steps[i].Name)
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
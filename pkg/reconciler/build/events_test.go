@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// drainEvents returns the events recorded so far.
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestRecordTransitions(t *testing.T) {
+	waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	finished := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	failed := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}}
+	skipped := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Skipped"}}
+	building := duckv1alpha1.Condition{
+		Type:   v1alpha1.BuildSucceeded,
+		Status: corev1.ConditionUnknown,
+		Reason: "Building",
+	}
+
+	for _, c := range []struct {
+		desc     string
+		steps    []corev1.Container
+		previous []corev1.ContainerState
+		current  []corev1.ContainerState
+		cond     duckv1alpha1.Condition
+		want     []string
+	}{{
+		desc:    "step started",
+		steps:   []corev1.Container{{Name: "compile"}, {Name: "push"}},
+		current: []corev1.ContainerState{running, waiting},
+		cond:    building,
+		want:    []string{`Normal StepStarted Step "compile" started`},
+	}, {
+		desc:     "step finished and next started",
+		steps:    []corev1.Container{{Name: "compile"}, {Name: "push"}},
+		previous: []corev1.ContainerState{running, waiting},
+		current:  []corev1.ContainerState{finished, running},
+		cond:     building,
+		want: []string{
+			`Normal StepFinished Step "compile" finished`,
+			`Normal StepStarted Step "push" started`,
+		},
+	}, {
+		desc:     "unchanged",
+		previous: []corev1.ContainerState{finished, running},
+		current:  []corev1.ContainerState{finished, running},
+		cond:     building,
+	}, {
+		desc:     "unnamed step ran between reconciles",
+		previous: []corev1.ContainerState{waiting},
+		current:  []corev1.ContainerState{finished},
+		cond:     building,
+		want: []string{
+			"Normal StepStarted Step #1 started",
+			"Normal StepFinished Step #1 finished",
+		},
+	}, {
+		desc:     "build succeeded",
+		previous: []corev1.ContainerState{running, waiting},
+		current:  []corev1.ContainerState{finished, skipped},
+		cond: duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionTrue,
+		},
+		want: []string{
+			"Normal StepFinished Step #1 finished",
+			"Normal StepSkipped Step #2 was skipped",
+			`Normal BuildSucceeded Build "events" succeeded`,
+		},
+	}, {
+		desc:     "build failed",
+		previous: []corev1.ContainerState{running},
+		current:  []corev1.ContainerState{failed},
+		cond: duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Message: "boom",
+		},
+		want: []string{
+			"Warning StepFailed Step #1 failed with exit code 2",
+			"Warning BuildFailed boom",
+		},
+	}, {
+		desc:     "step timed out",
+		previous: []corev1.ContainerState{running},
+		current:  []corev1.ContainerState{running},
+		cond: duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "StepTimeout",
+			Message: "too slow",
+		},
+		want: []string{"Warning StepTimeout too slow"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			build := newBuild("events")
+			build.Spec.Steps = c.steps
+			build.Status.StepStates = c.current
+			build.Status.SetCondition(&c.cond)
+			previous := v1alpha1.BuildStatus{StepStates: c.previous}
+			previous.SetCondition(&building)
+
+			recordTransitions(recorder, build, previous)
+			if d := cmp.Diff(c.want, drainEvents(recorder)); d != "" {
+				t.Errorf("Unexpected events (-want, +got): %s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DefaultEndpoint is the base URL of the Google Cloud Build API.
+const DefaultEndpoint = "https://cloudbuild.googleapis.com/"
+
+// Build is the subset of the Cloud Build v1 Build resource that we use.
+// See https://cloud.google.com/cloud-build/docs/api/reference/rest/v1/projects.builds
+type Build struct {
+	Id           string       `json:"id,omitempty"`
+	Steps        []*BuildStep `json:"steps,omitempty"`
+	Timeout      string       `json:"timeout,omitempty"`
+	Status       string       `json:"status,omitempty"`
+	StatusDetail string       `json:"statusDetail,omitempty"`
+	CreateTime   string       `json:"createTime,omitempty"`
+	StartTime    string       `json:"startTime,omitempty"`
+	FinishTime   string       `json:"finishTime,omitempty"`
+	LogUrl       string       `json:"logUrl,omitempty"`
+}
+
+// BuildStep is a single step of a Cloud Build Build.
+type BuildStep struct {
+	Id         string    `json:"id,omitempty"`
+	Name       string    `json:"name"`
+	Args       []string  `json:"args,omitempty"`
+	Env        []string  `json:"env,omitempty"`
+	Dir        string    `json:"dir,omitempty"`
+	Entrypoint string    `json:"entrypoint,omitempty"`
+	Volumes    []*Volume `json:"volumes,omitempty"`
+	WaitFor    []string  `json:"waitFor,omitempty"`
+	Timeout    string    `json:"timeout,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Timing     *TimeSpan `json:"timing,omitempty"`
+}
+
+// Volume is a volume shared between the steps of a Cloud Build Build.
+type Volume struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// TimeSpan records when a step started and finished.
+type TimeSpan struct {
+	StartTime string `json:"startTime,omitempty"`
+	EndTime   string `json:"endTime,omitempty"`
+}
+
+// Operation is a long-running operation returned by the Cloud Build API.
+type Operation struct {
+	Name     string             `json:"name"`
+	Done     bool               `json:"done,omitempty"`
+	Error    *OperationError    `json:"error,omitempty"`
+	Metadata *OperationMetadata `json:"metadata,omitempty"`
+}
+
+// OperationError describes why an Operation failed.
+type OperationError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// OperationMetadata holds the Build that an Operation is executing.
+type OperationMetadata struct {
+	Build *Build `json:"build,omitempty"`
+}
+
+// Build status values reported by Cloud Build.
+const (
+	StatusQueued        = "QUEUED"
+	StatusWorking       = "WORKING"
+	StatusSuccess       = "SUCCESS"
+	StatusFailure       = "FAILURE"
+	StatusInternalError = "INTERNAL_ERROR"
+	StatusTimeout       = "TIMEOUT"
+	StatusCancelled     = "CANCELLED"
+)
+
+// Client is a minimal client for the Cloud Build v1 REST API.
+type Client struct {
+	// HTTPClient is used to make requests. It is expected to add
+	// credentials to outgoing requests.
+	HTTPClient *http.Client
+	// Endpoint is the base URL of the API, e.g. DefaultEndpoint.
+	Endpoint string
+}
+
+// CreateBuild starts the given build in the project and returns the
+// Operation tracking it.
+func (c *Client) CreateBuild(project string, b *Build) (*Operation, error) {
+	op := &Operation{}
+	if err := c.do("POST", fmt.Sprintf("v1/projects/%s/builds", project), b, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// GetOperation returns the current state of the named Operation.
+func (c *Client) GetOperation(name string) (*Operation, error) {
+	op := &Operation{}
+	if err := c.do("GET", "v1/"+name, nil, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// CancelBuild cancels the build with the given id in the project.
+func (c *Client) CancelBuild(project, id string) error {
+	return c.do("POST", fmt.Sprintf("v1/projects/%s/builds/%s:cancel", project, id), struct{}{}, &Build{})
+}
+
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	url := strings.TrimSuffix(endpoint, "/") + "/" + path
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,449 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package google implements a builder that executes Builds with Google
+// Cloud Build.
+package google
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/pkg/apis"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+)
+
+const (
+	workspaceDir = "/workspace"
+	// Prefix of the ids of the steps that fetch source. These steps are not
+	// reported in the BuildStatus.
+	sourceStepPrefix = "knative-source-"
+
+	gitImage        = "gcr.io/cloud-builders/git"
+	gcsFetcherImage = "gcr.io/cloud-builders/gcs-fetcher"
+)
+
+// DefaultPollInterval is how often the status of an executing build is
+// refreshed from Cloud Build.
+const DefaultPollInterval = 10 * time.Second
+
+// Builder executes Builds with Google Cloud Build.
+type Builder struct {
+	client  *Client
+	project string
+}
+
+// NewBuilder returns a Builder that executes Builds in the given GCP project.
+func NewBuilder(client *Client, project string) *Builder {
+	return &Builder{
+		client:  client,
+		project: project,
+	}
+}
+
+// Validate checks that the build can be translated to a Cloud Build request.
+func (gb *Builder) Validate(build *v1alpha1.Build) error {
+	_, err := Translate(build)
+	return err
+}
+
+// Prepare returns the status recorded for the build before it is executed.
+func (gb *Builder) Prepare(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	return v1alpha1.BuildStatus{
+		Builder: v1alpha1.GoogleBuildProvider,
+		Google:  &v1alpha1.GoogleSpec{},
+		StartTime: &metav1.Time{
+			Time: time.Now(),
+		},
+	}, nil
+}
+
+// IsStarted returns true once Cloud Build has accepted the build. Builds
+// that were prepared but whose submission failed, or was interrupted, are
+// submitted again.
+func (gb *Builder) IsStarted(status *v1alpha1.BuildStatus) bool {
+	return status.Google != nil && status.Google.Operation != ""
+}
+
+// Execute submits the build to Cloud Build.
+func (gb *Builder) Execute(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	req, err := Translate(build)
+	if err != nil {
+		return build.Status, err
+	}
+	op, err := gb.client.CreateBuild(gb.project, req)
+	if err != nil {
+		return build.Status, err
+	}
+	return StatusFromOperation(build, op), nil
+}
+
+// Status returns the current status of the build's Cloud Build operation.
+func (gb *Builder) Status(build *v1alpha1.Build) (v1alpha1.BuildStatus, error) {
+	if build.Status.Google == nil || build.Status.Google.Operation == "" {
+		// The build is still being submitted.
+		return build.Status, nil
+	}
+	op, err := gb.client.GetOperation(build.Status.Google.Operation)
+	if err != nil {
+		return build.Status, err
+	}
+	return StatusFromOperation(build, op), nil
+}
+
+// Stop cancels the build's Cloud Build operation.
+func (gb *Builder) Stop(build *v1alpha1.Build) error {
+	if build.Status.Google == nil || build.Status.Google.Operation == "" {
+		return nil
+	}
+	op, err := gb.client.GetOperation(build.Status.Google.Operation)
+	if err != nil {
+		return err
+	}
+	if op.Done || op.Metadata == nil || op.Metadata.Build == nil {
+		return nil
+	}
+	return gb.client.CancelBuild(gb.project, op.Metadata.Build.Id)
+}
+
+// PollInterval returns how often executing builds should be reconciled.
+func (gb *Builder) PollInterval() time.Duration {
+	return DefaultPollInterval
+}
+
+// Translate converts a Build, whose template has already been applied, into
+// a Cloud Build request.
+func Translate(build *v1alpha1.Build) (*Build, error) {
+	if len(build.Spec.Sidecars) > 0 {
+		return nil, fmt.Errorf("sidecars are not supported by the Google builder")
+	}
+	if len(build.Spec.StepPolicies) > 0 {
+		return nil, fmt.Errorf("step policies are not supported by the Google builder")
+	}
+	if len(build.Spec.Caches) > 0 {
+		return nil, fmt.Errorf("caches are not supported by the Google builder")
+	}
+	if build.Spec.Artifacts != nil {
+		return nil, fmt.Errorf("artifacts are not supported by the Google builder")
+	}
+
+	var sources []v1alpha1.SourceSpec
+	if build.Spec.Source != nil {
+		sources = []v1alpha1.SourceSpec{*build.Spec.Source}
+	}
+	sources = append(sources, build.Spec.Sources...)
+
+	var steps []*BuildStep
+	subPath := ""
+	for i, source := range sources {
+		id := sourceStepPrefix + strconv.Itoa(i)
+		if source.Name != "" {
+			id = sourceStepPrefix + source.Name
+		}
+		dir := source.TargetPath
+		if dir == "" {
+			dir = "."
+		}
+		switch {
+		case source.Git != nil:
+			if source.Git.Url == "" {
+				return nil, apis.ErrMissingField("b.spec.source.git.url")
+			}
+			if source.Git.Revision == "" {
+				return nil, apis.ErrMissingField("b.spec.source.git.revision")
+			}
+			if len(source.Git.SparseCheckout) > 0 || source.Git.LFS {
+				return nil, fmt.Errorf("sparse checkouts and LFS of git sources are not supported by the Google builder")
+			}
+			if source.Git.Verify != nil {
+				return nil, fmt.Errorf("signature verification of git sources is not supported by the Google builder")
+			}
+			steps = append(steps, &BuildStep{
+				Id:   id + "-clone",
+				Name: gitImage,
+				Args: []string{"clone", source.Git.Url, dir},
+			}, &BuildStep{
+				Id:   id + "-checkout",
+				Name: gitImage,
+				Args: []string{"checkout", source.Git.Revision},
+				Dir:  dir,
+			})
+		case source.GCS != nil:
+			if source.GCS.Location == "" {
+				return nil, apis.ErrMissingField("b.spec.source.gcs.location")
+			}
+			steps = append(steps, &BuildStep{
+				Id:   id,
+				Name: gcsFetcherImage,
+				Args: []string{"--type", string(source.GCS.Type), "--location", source.GCS.Location,
+					"--dest_dir", filepath.Join(workspaceDir, source.TargetPath)},
+			})
+		case source.HTTP != nil:
+			return nil, fmt.Errorf("http sources are not supported by the Google builder")
+		case source.Image != nil:
+			return nil, fmt.Errorf("image sources are not supported by the Google builder")
+		case source.S3 != nil:
+			return nil, fmt.Errorf("s3 sources are not supported by the Google builder")
+		case source.Custom != nil:
+			step, err := containerToStep(*source.Custom)
+			if err != nil {
+				return nil, err
+			}
+			step.Id = id
+			steps = append(steps, step)
+		}
+		// webhook validation checks that only one source has subPath defined
+		if source.SubPath != "" {
+			subPath = source.SubPath
+		}
+	}
+
+	for i, c := range build.Spec.Steps {
+		step, err := containerToStep(c)
+		if err != nil {
+			return nil, err
+		}
+		if step.Dir == "" && subPath != "" {
+			step.Dir = subPath
+		}
+		if i < len(build.Spec.StepTimeouts) && build.Spec.StepTimeouts[i].Duration > 0 {
+			step.Timeout = durationString(build.Spec.StepTimeouts[i].Duration)
+		}
+		steps = append(steps, step)
+	}
+
+	for _, v := range build.Spec.Volumes {
+		if v.EmptyDir == nil {
+			return nil, fmt.Errorf("volume %q: only emptyDir volumes are supported by the Google builder", v.Name)
+		}
+	}
+
+	gb := &Build{Steps: steps}
+	if build.Spec.Timeout != nil {
+		gb.Timeout = durationString(build.Spec.Timeout.Duration)
+	}
+	return gb, nil
+}
+
+// durationString formats a duration as the API expects, in whole seconds.
+func durationString(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d/time.Second))
+}
+
+func containerToStep(c corev1.Container) (*BuildStep, error) {
+	if c.Image == "" {
+		return nil, apis.ErrMissingField("b.spec.steps.image")
+	}
+	step := &BuildStep{
+		Id:   c.Name,
+		Name: c.Image,
+		Args: c.Args,
+		Dir:  c.WorkingDir,
+	}
+	if len(c.Command) > 0 {
+		step.Entrypoint = c.Command[0]
+		step.Args = append(append([]string{}, c.Command[1:]...), c.Args...)
+	}
+	for _, e := range c.Env {
+		if e.ValueFrom != nil {
+			return nil, fmt.Errorf("step %q env %q: valueFrom is not supported by the Google builder", c.Name, e.Name)
+		}
+		step.Env = append(step.Env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	for _, vm := range c.VolumeMounts {
+		step.Volumes = append(step.Volumes, &Volume{
+			Name: vm.Name,
+			Path: vm.MountPath,
+		})
+	}
+	return step, nil
+}
+
+// StatusFromOperation returns a BuildStatus based on the Cloud Build
+// Operation executing the build.
+func StatusFromOperation(build *v1alpha1.Build, op *Operation) v1alpha1.BuildStatus {
+	status := v1alpha1.BuildStatus{
+		Builder: v1alpha1.GoogleBuildProvider,
+		Google: &v1alpha1.GoogleSpec{
+			Operation: op.Name,
+		},
+		StartTime: build.Status.StartTime,
+	}
+
+	if op.Error != nil {
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "BuildExecuteFailed",
+			Message: op.Error.Message,
+		})
+		return status
+	}
+
+	gb := &Build{Status: StatusQueued}
+	if op.Metadata != nil && op.Metadata.Build != nil {
+		gb = op.Metadata.Build
+	}
+	if t := parseTime(gb.StartTime); t != nil {
+		status.StartTime = t
+	}
+	status.CompletionTime = parseTime(gb.FinishTime)
+
+	for _, s := range gb.Steps {
+		if strings.HasPrefix(s.Id, sourceStepPrefix) {
+			continue
+		}
+		state := stepState(s)
+		if state.Terminated != nil {
+			status.StepsCompleted = append(status.StepsCompleted, s.Id)
+		}
+		status.StepStates = append(status.StepStates, state)
+	}
+
+	for _, s := range gb.Steps {
+		if s.Status != StatusTimeout || s.Timeout == "" || strings.HasPrefix(s.Id, sourceStepPrefix) {
+			continue
+		}
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "StepTimeout",
+			Message: fmt.Sprintf("build step %q failed to finish within %q", s.Id, s.Timeout),
+		})
+		return status
+	}
+
+	switch gb.Status {
+	case StatusQueued:
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionUnknown,
+			Reason: "Pending",
+		})
+	case StatusWorking:
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionUnknown,
+			Reason: "Building",
+		})
+	case StatusSuccess:
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionTrue,
+		})
+	case StatusTimeout:
+		timeout := v1alpha1.DefaultTimeout
+		if build.Spec.Timeout != nil {
+			timeout = build.Spec.Timeout.Duration
+		}
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "BuildTimeout",
+			Message: fmt.Sprintf("Build %q failed to finish within %q", build.Name, timeout.String()),
+		})
+	case StatusCancelled:
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  "BuildCancelled",
+			Message: fmt.Sprintf("Build %q was cancelled", build.Name),
+		})
+	default:
+		status.SetCondition(&duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Message: getFailureMessage(gb),
+		})
+	}
+	return status
+}
+
+func stepState(s *BuildStep) corev1.ContainerState {
+	var started, finished metav1.Time
+	if s.Timing != nil {
+		if t := parseTime(s.Timing.StartTime); t != nil {
+			started = *t
+		}
+		if t := parseTime(s.Timing.EndTime); t != nil {
+			finished = *t
+		}
+	}
+	switch s.Status {
+	case "", StatusQueued:
+		return corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{Reason: "Queued"},
+		}
+	case StatusWorking:
+		return corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{StartedAt: started},
+		}
+	case StatusSuccess:
+		return corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				Reason:     "Completed",
+				StartedAt:  started,
+				FinishedAt: finished,
+			},
+		}
+	default:
+		return corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				ExitCode:   1,
+				Reason:     s.Status,
+				StartedAt:  started,
+				FinishedAt: finished,
+			},
+		}
+	}
+}
+
+func getFailureMessage(gb *Build) string {
+	msg := gb.StatusDetail
+	if msg == "" {
+		for _, s := range gb.Steps {
+			if s.Status == StatusFailure {
+				msg = fmt.Sprintf("build step %q (image: %q) failed", s.Id, s.Name)
+				break
+			}
+		}
+	}
+	if msg == "" {
+		msg = "build failed for unspecified reasons."
+	}
+	if gb.LogUrl != "" {
+		msg = fmt.Sprintf("%s; for logs see: %s", msg, gb.LogUrl)
+	}
+	return msg
+}
+
+func parseTime(s string) *metav1.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
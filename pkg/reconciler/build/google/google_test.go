@@ -0,0 +1,396 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/pkg/apis"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const project = "my-project"
+
+var ignoreVolatileTime = cmp.Comparer(func(_, _ apis.VolatileTime) bool { return true })
+
+// fakeGCB is an in-memory implementation of the subset of the Cloud Build
+// API used by the Builder.
+type fakeGCB struct {
+	sync.Mutex
+	t      *testing.T
+	builds map[string]*Build
+	nextID int
+}
+
+func newFakeGCB(t *testing.T) (*fakeGCB, *httptest.Server) {
+	f := &fakeGCB{t: t, builds: map[string]*Build{}}
+	return f, httptest.NewServer(f)
+}
+
+func (f *fakeGCB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	buildsPath := fmt.Sprintf("/v1/projects/%s/builds", project)
+	switch {
+	case r.Method == "POST" && r.URL.Path == buildsPath:
+		b := &Build{}
+		if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.nextID++
+		b.Id = fmt.Sprintf("build-%d", f.nextID)
+		b.Status = StatusQueued
+		f.builds[b.Id] = b
+		f.writeOperation(w, b)
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/operations/"):
+		b, ok := f.builds[strings.TrimPrefix(r.URL.Path, "/v1/operations/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f.writeOperation(w, b)
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, buildsPath+"/") && strings.HasSuffix(r.URL.Path, ":cancel"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, buildsPath+"/"), ":cancel")
+		b, ok := f.builds[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b.Status = StatusCancelled
+		json.NewEncoder(w).Encode(b)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGCB) writeOperation(w http.ResponseWriter, b *Build) {
+	done := b.Status != StatusQueued && b.Status != StatusWorking
+	json.NewEncoder(w).Encode(&Operation{
+		Name:     "operations/" + b.Id,
+		Done:     done,
+		Metadata: &OperationMetadata{Build: b},
+	})
+}
+
+func (f *fakeGCB) update(id string, fn func(*Build)) {
+	f.Lock()
+	defer f.Unlock()
+	fn(f.builds[id])
+}
+
+func TestTranslate(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		spec    v1alpha1.BuildSpec
+		want    *Build
+		wantErr bool
+	}{{
+		desc: "steps",
+		spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{
+				Name:       "compile",
+				Image:      "golang",
+				Command:    []string{"go", "build"},
+				Args:       []string{"./..."},
+				WorkingDir: "/workspace/src",
+				Env:        []corev1.EnvVar{{Name: "CGO_ENABLED", Value: "0"}},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "cache",
+					MountPath: "/cache",
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "cache",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}},
+			Timeout: &metav1.Duration{Duration: 5 * time.Minute},
+		},
+		want: &Build{
+			Steps: []*BuildStep{{
+				Id:         "compile",
+				Name:       "golang",
+				Entrypoint: "go",
+				Args:       []string{"build", "./..."},
+				Dir:        "/workspace/src",
+				Env:        []string{"CGO_ENABLED=0"},
+				Volumes:    []*Volume{{Name: "cache", Path: "/cache"}},
+			}},
+			Timeout: "300s",
+		},
+	}, {
+		desc: "sources",
+		spec: v1alpha1.BuildSpec{
+			Sources: []v1alpha1.SourceSpec{{
+				Name: "repo",
+				Git: &v1alpha1.GitSourceSpec{
+					Url:      "https://github.com/knative/build",
+					Revision: "master",
+				},
+				SubPath: "cmd",
+			}, {
+				Name:       "assets",
+				TargetPath: "assets",
+				GCS: &v1alpha1.GCSSourceSpec{
+					Type:     v1alpha1.GCSArchive,
+					Location: "gs://bucket/assets.tgz",
+				},
+			}},
+			Steps: []corev1.Container{{
+				Image: "ubuntu",
+				Args:  []string{"ls"},
+			}},
+		},
+		want: &Build{
+			Steps: []*BuildStep{{
+				Id:   "knative-source-repo-clone",
+				Name: gitImage,
+				Args: []string{"clone", "https://github.com/knative/build", "."},
+			}, {
+				Id:   "knative-source-repo-checkout",
+				Name: gitImage,
+				Args: []string{"checkout", "master"},
+				Dir:  ".",
+			}, {
+				Id:   "knative-source-assets",
+				Name: gcsFetcherImage,
+				Args: []string{"--type", "Archive", "--location", "gs://bucket/assets.tgz", "--dest_dir", "/workspace/assets"},
+			}, {
+				Name: "ubuntu",
+				Args: []string{"ls"},
+				Dir:  "cmd",
+			}},
+		},
+	}, {
+		desc: "unsupported volume",
+		spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{Image: "ubuntu"}},
+			Volumes: []corev1.Volume{{
+				Name: "secret",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "foo"},
+				},
+			}},
+		},
+		wantErr: true,
+	}, {
+		desc: "unsupported env",
+		spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{
+				Image: "ubuntu",
+				Env: []corev1.EnvVar{{
+					Name:      "POD",
+					ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+				}},
+			}},
+		},
+		wantErr: true,
+	}, {
+		desc: "missing image",
+		spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{Name: "foo"}},
+		},
+		wantErr: true,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := Translate(&v1alpha1.Build{Spec: c.spec})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Translate() = %v, wantErr %v", err, c.wantErr)
+			}
+			if d := cmp.Diff(c.want, got); d != "" {
+				t.Errorf("Translate() diff -want, +got: %s", d)
+			}
+		})
+	}
+}
+
+func TestBuilderLifecycle(t *testing.T) {
+	fake, server := newFakeGCB(t)
+	defer server.Close()
+
+	gb := NewBuilder(&Client{HTTPClient: server.Client(), Endpoint: server.URL}, project)
+	build := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build"},
+		Spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{
+				Name:  "hello",
+				Image: "busybox",
+				Args:  []string{"echo", "hello"},
+			}},
+		},
+	}
+
+	status, err := gb.Prepare(build)
+	if err != nil {
+		t.Fatalf("Prepare() = %v", err)
+	}
+	if gb.IsStarted(&status) {
+		t.Errorf("IsStarted() = true after Prepare()")
+	}
+	build.Status = status
+
+	status, err = gb.Execute(build)
+	if err != nil {
+		t.Fatalf("Execute() = %v", err)
+	}
+	if status.Google == nil || status.Google.Operation != "operations/build-1" {
+		t.Fatalf("Execute() status.Google = %v, want operation %q", status.Google, "operations/build-1")
+	}
+	if !gb.IsStarted(&status) {
+		t.Errorf("IsStarted() = false after Execute()")
+	}
+	if d := cmp.Diff(&duckv1alpha1.Condition{
+		Type:   v1alpha1.BuildSucceeded,
+		Status: corev1.ConditionUnknown,
+		Reason: "Pending",
+	}, status.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+		t.Errorf("Execute() condition diff -want, +got: %s", d)
+	}
+	build.Status = status
+
+	fake.update("build-1", func(b *Build) {
+		b.Status = StatusFailure
+		b.StartTime = "2019-01-01T10:00:00Z"
+		b.FinishTime = "2019-01-01T10:01:00Z"
+		b.LogUrl = "https://console.cloud.google.com/logs"
+		b.Steps[0].Status = StatusFailure
+		b.Steps[0].Timing = &TimeSpan{StartTime: "2019-01-01T10:00:10Z", EndTime: "2019-01-01T10:00:50Z"}
+	})
+	status, err = gb.Status(build)
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if d := cmp.Diff(&duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionFalse,
+		Message: `build step "hello" (image: "busybox") failed; for logs see: https://console.cloud.google.com/logs`,
+	}, status.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+		t.Errorf("Status() condition diff -want, +got: %s", d)
+	}
+	if d := cmp.Diff([]string{"hello"}, status.StepsCompleted); d != "" {
+		t.Errorf("Status() steps completed diff -want, +got: %s", d)
+	}
+	if len(status.StepStates) != 1 || status.StepStates[0].Terminated == nil || status.StepStates[0].Terminated.ExitCode != 1 {
+		t.Errorf("Status() step states = %v, want one failed step", status.StepStates)
+	}
+	if status.CompletionTime == nil || !status.CompletionTime.Time.Equal(time.Date(2019, 1, 1, 10, 1, 0, 0, time.UTC)) {
+		t.Errorf("Status() completion time = %v", status.CompletionTime)
+	}
+}
+
+func TestBuilderStop(t *testing.T) {
+	fake, server := newFakeGCB(t)
+	defer server.Close()
+
+	gb := NewBuilder(&Client{HTTPClient: server.Client(), Endpoint: server.URL}, project)
+	build := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build"},
+		Spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{Image: "busybox"}},
+		},
+	}
+	status, err := gb.Execute(build)
+	if err != nil {
+		t.Fatalf("Execute() = %v", err)
+	}
+	build.Status = status
+	fake.update("build-1", func(b *Build) { b.Status = StatusWorking })
+
+	if err := gb.Stop(build); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	status, err = gb.Status(build)
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if d := cmp.Diff(&duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "BuildCancelled",
+		Message: `Build "build" was cancelled`,
+	}, status.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+		t.Errorf("Status() condition diff -want, +got: %s", d)
+	}
+}
+
+func TestBuilderExecuteFailure(t *testing.T) {
+	fake, server := newFakeGCB(t)
+	defer server.Close()
+	var unavailable bool
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unavailable {
+			http.Error(w, "backend unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	gb := NewBuilder(&Client{HTTPClient: flaky.Client(), Endpoint: flaky.URL}, project)
+	build := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build"},
+		Spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{Image: "busybox"}},
+		},
+	}
+	status, err := gb.Prepare(build)
+	if err != nil {
+		t.Fatalf("Prepare() = %v", err)
+	}
+	build.Status = status
+
+	unavailable = true
+	if _, err := gb.Execute(build); err == nil {
+		t.Fatal("Execute() succeeded, want error")
+	}
+	// The prepared build wasn't accepted, so it's submitted again.
+	if gb.IsStarted(&build.Status) {
+		t.Fatal("IsStarted() = true after Execute() failed")
+	}
+	unavailable = false
+	status, err = gb.Execute(build)
+	if err != nil {
+		t.Fatalf("Execute() = %v", err)
+	}
+	if !gb.IsStarted(&status) || status.Google.Operation != "operations/build-1" {
+		t.Errorf("Execute() status.Google = %v, want operation %q", status.Google, "operations/build-1")
+	}
+}
+
+func TestClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "permission denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	c := &Client{HTTPClient: server.Client(), Endpoint: server.URL}
+	if _, err := c.CreateBuild(project, &Build{}); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("CreateBuild() = %v, want permission denied error", err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"flag"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/notify"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The sink notified about builds that don't specify one.
+var defaultNotifySink = flag.String("default-notify-sink", "",
+	"The URI of the sink to which CloudEvents are sent about Builds that don't specify one; if empty, only Builds that specify a sink are notified.")
+
+const (
+	// notifyAttempts is how many times delivery of a CloudEvent is
+	// attempted; the delay between attempts starts at notifyBackoff and
+	// doubles after each.
+	notifyAttempts = 5
+	notifyBackoff  = time.Second
+	// notifyTimeout bounds each attempt.
+	notifyTimeout = 10 * time.Second
+	// maxNotificationFailures is how many notification failures are kept
+	// in a build's status.
+	maxNotificationFailures = 10
+)
+
+// notify sends a CloudEvent about the build to its sink, if it has one.
+func (c *Reconciler) notify(build *v1alpha1.Build, eventType, step string) {
+	sink := build.Spec.Notify
+	if sink == "" {
+		sink = *defaultNotifySink
+	}
+	if sink == "" || c.notifier == nil {
+		return
+	}
+	c.notifier.Send(build.Namespace+"/"+build.Name, notify.New(sink, eventType, build, step))
+}
+
+// notifyTransitions sends CloudEvents about the changes between the
+// previous and the current status of the build: its steps completing, and
+// the build itself finishing.
+func (c *Reconciler) notifyTransitions(build *v1alpha1.Build, previous v1alpha1.BuildStatus) {
+	for i, state := range build.Status.StepStates {
+		term := state.Terminated
+		if term == nil || term.Reason == "Skipped" {
+			continue
+		}
+		if i < len(previous.StepStates) && previous.StepStates[i].Terminated != nil {
+			continue
+		}
+		c.notify(build, notify.StepCompleted, stepTag(build, i))
+	}
+	if isDone(&build.Status) && !isDone(&previous) {
+		c.notify(build, doneEventType(build), "")
+	}
+}
+
+// doneEventType returns the type of the CloudEvent about the build
+// finishing.
+func doneEventType(build *v1alpha1.Build) string {
+	cond := build.Status.GetCondition(v1alpha1.BuildSucceeded)
+	if cond == nil {
+		return notify.BuildFailed
+	}
+	switch outcome(cond.Status, cond.Reason) {
+	case outcomeSucceeded:
+		return notify.BuildSucceeded
+	case outcomeTimeout:
+		return notify.BuildTimedOut
+	case outcomeCancelled:
+		return notify.BuildCancelled
+	default:
+		return notify.BuildFailed
+	}
+}
+
+// recordNotificationFailure records on the build, and in an Event, that a
+// CloudEvent about it couldn't be delivered.
+func (c *Reconciler) recordNotificationFailure(n notify.Notification, deliveryErr error) {
+	c.Logger.Warnf("Failed to deliver %s event about build %q to %s: %v", n.EventType, n.Data.Name, n.Sink, deliveryErr)
+	build := &v1alpha1.Build{ObjectMeta: metav1.ObjectMeta{Name: n.Data.Name, Namespace: n.Data.Namespace}}
+	statusLock(build)
+	defer statusUnlock(build)
+	newb, err := c.buildclientset.BuildV1alpha1().Builds(build.Namespace).Get(build.Name, metav1.GetOptions{})
+	if err != nil {
+		c.Logger.Errorf("Failed to record notification failure of build %q: %v", build.Name, err)
+		return
+	}
+	// The build may have been deleted and re-created with the same name.
+	if newb.UID != n.Data.UID {
+		return
+	}
+	failures := append(newb.Status.NotificationFailures, v1alpha1.NotificationFailure{
+		EventType: n.EventType,
+		Sink:      n.Sink,
+		Time:      metav1.Now(),
+		Message:   deliveryErr.Error(),
+	})
+	if len(failures) > maxNotificationFailures {
+		failures = failures[len(failures)-maxNotificationFailures:]
+	}
+	newb.Status.NotificationFailures = failures
+	if _, err := c.buildclientset.BuildV1alpha1().Builds(build.Namespace).UpdateStatus(newb); err != nil {
+		c.Logger.Errorf("Failed to record notification failure of build %q: %v", build.Name, err)
+		return
+	}
+	c.recorder.Eventf(newb, corev1.EventTypeWarning, "NotificationFailed", "Failed to deliver %s event to %s: %v", n.EventType, n.Sink, deliveryErr)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	fakebuildclient "github.com/knative/build/pkg/client/injection/client/fake"
+	"github.com/knative/build/pkg/notify"
+	"github.com/knative/pkg/controller"
+	fakekubeclient "github.com/knative/pkg/injection/clients/kubeclient/fake"
+	rtesting "github.com/knative/pkg/reconciler/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// eventSink records the types of the CloudEvents it receives, and responds
+// to them with status.
+type eventSink struct {
+	status int
+
+	mu    sync.Mutex
+	types []string
+}
+
+func (s *eventSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.types = append(s.types, r.Header.Get("CE-EventType"))
+	s.mu.Unlock()
+	w.WriteHeader(s.status)
+}
+
+func (s *eventSink) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.types...)
+}
+
+// runBuild reconciles the build until its pod runs, and then again once
+// the pod is in the given phase.
+func runBuild(t *testing.T, ctx context.Context, b *v1alpha1.Build, phase corev1.PodPhase, configure func(*Reconciler)) {
+	t.Helper()
+	f := &fixture{t: t, objects: []runtime.Object{b}}
+	f.createBuild(ctx, b)
+	f.createServiceAccount(ctx)
+	r := f.newReconciler(ctx)
+	configure(r.(*Reconciler))
+	f.updateIndex(ctx, b)
+
+	if err := r.Reconcile(ctx, getKey(b, t)); err != nil {
+		t.Fatalf("error syncing build: %v", err)
+	}
+	b, err := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace).Get(b.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching build: %v", err)
+	}
+	pods := fakekubeclient.Get(ctx).CoreV1().Pods(b.Namespace)
+	p, err := pods.Get(b.Status.Cluster.PodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting pod: %v", err)
+	}
+	p.Status = corev1.PodStatus{Phase: phase}
+	if _, err := pods.Update(p); err != nil {
+		t.Fatalf("error updating pod: %v", err)
+	}
+	f.updatePodIndex(ctx, p)
+	f.updateIndex(ctx, b)
+	if err := r.Reconcile(ctx, getKey(b, t)); err != nil {
+		t.Fatalf("error syncing build: %v", err)
+	}
+}
+
+func TestNotifyFlow(t *testing.T) {
+	sink := &eventSink{status: http.StatusAccepted}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	b := newBuild("notify")
+	b.Spec.Notify = server.URL
+	runBuild(t, ctx, b, corev1.PodSucceeded, func(*Reconciler) {})
+
+	want := []string{notify.BuildStarted, notify.BuildSucceeded}
+	waitUntil(t, func() bool { return len(sink.received()) >= len(want) })
+	if d := cmp.Diff(want, sink.received()); d != "" {
+		t.Errorf("Unexpected CloudEvents (-want, +got): %s", d)
+	}
+}
+
+func TestNotifyFlowDefaultSink(t *testing.T) {
+	sink := &eventSink{status: http.StatusAccepted}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	defer func(sink string) { *defaultNotifySink = sink }(*defaultNotifySink)
+	*defaultNotifySink = server.URL
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	runBuild(t, ctx, newBuild("notify-default"), corev1.PodFailed, func(*Reconciler) {})
+
+	want := []string{notify.BuildStarted, notify.BuildFailed}
+	waitUntil(t, func() bool { return len(sink.received()) >= len(want) })
+	if d := cmp.Diff(want, sink.received()); d != "" {
+		t.Errorf("Unexpected CloudEvents (-want, +got): %s", d)
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	sink := &eventSink{status: http.StatusInternalServerError}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	ctx, informers := rtesting.SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
+		t.Fatalf("Failed to start informers %v", err)
+	}
+
+	b := newBuild("notify-failure")
+	b.Spec.Notify = server.URL
+	runBuild(t, ctx, b, corev1.PodSucceeded, func(r *Reconciler) {
+		r.notifier.Attempts = 2
+		r.notifier.Backoff = time.Millisecond
+	})
+
+	buildClient := fakebuildclient.Get(ctx).BuildV1alpha1().Builds(b.Namespace)
+	var failures []v1alpha1.NotificationFailure
+	waitUntil(t, func() bool {
+		b, err := buildClient.Get(b.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error fetching build: %v", err)
+		}
+		failures = b.Status.NotificationFailures
+		return len(failures) == 2
+	})
+	for i, want := range []string{notify.BuildStarted, notify.BuildSucceeded} {
+		if f := failures[i]; f.EventType != want || f.Sink != server.URL || !strings.Contains(f.Message, "500") {
+			t.Errorf("Unexpected notification failure %d: %+v", i, f)
+		}
+	}
+	// Each event was attempted twice.
+	if got := len(sink.received()); got != 4 {
+		t.Errorf("Sink received %d CloudEvents, want 4", got)
+	}
+	var warnings int
+	for _, e := range recordedEvents(ctx) {
+		if strings.HasPrefix(e, "Warning NotificationFailed ") {
+			warnings++
+		}
+	}
+	if warnings != 2 {
+		t.Errorf("Recorded %d NotificationFailed events, want 2", warnings)
+	}
+}
+
+// waitUntil waits until cond returns true.
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("Timed out waiting")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+)
+
+const (
+	// A label with the following identifies the cache held by a claim.
+	cacheNameLabelKey = "build.knative.dev/cacheName"
+	// CacheHolderAnnotationKey is the annotation on a cache's claim that
+	// records the UID of the build that may write to it.
+	CacheHolderAnnotationKey = "build.knative.dev/cacheHolder"
+	// Prefix to add to the name of cache volumes.
+	cacheVolumePrefix = "cache-"
+)
+
+// The storage requested for caches that don't specify their size.
+var cacheSize = flag.String("cache-size", "5Gi",
+	"The storage requested for build caches that don't specify their size.")
+
+// The access mode requested for cache claims. Builds that share a cache
+// while another build writes to it mount its claim concurrently, possibly
+// from other nodes, so the default requires storage that supports
+// ReadWriteMany. With ReadWriteOnce, a build that shares a cache with a
+// running build on another node stays Pending until that build finishes.
+var cacheAccessMode = flag.String("cache-access-mode", string(corev1.ReadWriteMany),
+	"The access mode requested for build caches: ReadWriteMany or ReadWriteOnce.")
+
+// CacheClaimName returns the name of the claim that holds the cache for
+// its key.
+func CacheClaimName(cache v1alpha1.CacheSpec) string {
+	sum := sha256.Sum256([]byte(cache.Key))
+	return fmt.Sprintf("build-cache-%s-%s", cache.Name, hex.EncodeToString(sum[:])[:16])
+}
+
+// MakeCacheClaim returns the claim to provision for the cache, held by the
+// build.
+func MakeCacheClaim(build *v1alpha1.Build, cache v1alpha1.CacheSpec) (*corev1.PersistentVolumeClaim, error) {
+	size := cache.Size
+	if size == nil {
+		q, err := resource.ParseQuantity(*cacheSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache size %q: %v", *cacheSize, err)
+		}
+		size = &q
+	}
+	mode := corev1.PersistentVolumeAccessMode(*cacheAccessMode)
+	switch mode {
+	case corev1.ReadWriteMany, corev1.ReadWriteOnce:
+	default:
+		return nil, fmt.Errorf("invalid cache access mode %q", *cacheAccessMode)
+	}
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			// Claims outlive the build that provisions them, so that later
+			// builds may reuse them.
+			Namespace: build.Namespace,
+			Name:      CacheClaimName(cache),
+			Labels: map[string]string{
+				cacheNameLabelKey: cache.Name,
+			},
+			Annotations: map[string]string{
+				CacheHolderAnnotationKey: string(build.UID),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{mode},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: *size,
+				},
+			},
+			StorageClassName: cache.StorageClassName,
+		},
+	}, nil
+}
+
+// cacheVolumes returns the volumes and mounts for the caches recorded in
+// the build's status.
+func cacheVolumes(build *v1alpha1.Build) ([]corev1.Volume, []corev1.VolumeMount) {
+	mountPaths := map[string]string{}
+	for _, c := range build.Spec.Caches {
+		mountPaths[c.Name] = c.MountPath
+	}
+
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for _, c := range build.Status.Caches {
+		path, ok := mountPaths[c.Name]
+		if !ok {
+			continue
+		}
+		volumes = append(volumes, corev1.Volume{
+			Name: cacheVolumePrefix + c.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: c.ClaimName,
+					ReadOnly:  c.ReadOnly,
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      cacheVolumePrefix + c.Name,
+			MountPath: path,
+			ReadOnly:  c.ReadOnly,
+		})
+	}
+	return volumes, mounts
+}
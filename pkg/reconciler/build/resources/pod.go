@@ -21,12 +21,15 @@ package resources
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,9 +38,13 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/artifacts"
 	"github.com/knative/build/pkg/credentials"
-	"github.com/knative/build/pkg/credentials/dockercreds"
-	"github.com/knative/build/pkg/credentials/gitcreds"
+	// Register the credential builders that creds-init runs.
+	_ "github.com/knative/build/pkg/credentials/all"
+	"github.com/knative/build/pkg/entrypoint"
+	"github.com/knative/build/pkg/gitverify"
+	"github.com/knative/build/pkg/httpfetch"
 	"github.com/knative/pkg/apis"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 )
@@ -82,12 +89,48 @@ const (
 	unnamedInitContainerPrefix = "build-step-unnamed-"
 	// A label with the following is added to the pod to identify the pods belonging to a build.
 	buildNameLabelKey = "build.knative.dev/buildName"
+	// An annotation with the following records the timeout of each step
+	// container that has one, as a JSON object of container name to duration.
+	stepTimeoutsAnnotationKey = "build.knative.dev/stepTimeouts"
+	// An annotation with the following records the comma-separated names
+	// of the results that the pod's steps must report.
+	resultsAnnotationKey = "build.knative.dev/results"
+	// An annotation with the following records that the pod's steps run as
+	// regular containers, ordered by the entrypoint.
+	stepRunnerAnnotationKey = "build.knative.dev/stepRunner"
+	// Prefix to add to the name of sidecar containers.
+	sidecarPrefix        = "sidecar-"
+	unnamedSidecarPrefix = "sidecar-unnamed-"
 	// Name of the credential initialization container.
 	credsInit = "credential-initializer"
 	// Names for source containers.
 	gitSource    = "git-source"
 	gcsSource    = "gcs-source"
+	httpSource   = "http-source"
+	imageSource  = "image-source"
+	s3Source     = "s3-source"
 	customSource = "custom-source"
+	// Name of the container that uploads the build's artifacts.
+	artifactUploader = "artifact-uploader"
+	// Name of the volume of the PersistentVolumeClaim to which artifacts
+	// are copied, and where it is mounted.
+	artifactsVolume = "build-artifacts"
+	artifactsDir    = "/var/build-artifacts"
+	// ko places the binary of each of our images at /ko-app/<name>.
+	koAppDir = "/ko-app"
+	// Name of the container that places the entrypoint binary.
+	placeTools = "place-tools"
+	// Directory into which the entrypoint binary and the files that order
+	// steps are placed.
+	toolsDir = "/builder/tools"
+)
+
+const (
+	// InitContainerStepRunner runs each step as an init container.
+	InitContainerStepRunner = "InitContainer"
+	// EntrypointStepRunner runs each step as a regular container, ordered
+	// by an injected entrypoint binary; it is used by builds with sidecars.
+	EntrypointStepRunner = "Entrypoint"
 )
 
 var (
@@ -100,18 +143,40 @@ var (
 	// The container that just prints build successful.
 	nopImage = flag.String("nop-image", "override-with-nop:latest",
 		"The container image run at the end of the build to log build success")
+	// The container with the entrypoint binary that orders steps run as
+	// regular containers.
+	entrypointImage = flag.String("entrypoint-image", "override-with-entrypoint:latest",
+		"The container image containing the entrypoint binary that orders steps run as regular containers.")
+	// How the steps of builds without sidecars are run.
+	stepRunner = flag.String("step-runner", InitContainerStepRunner,
+		"How the steps of builds without sidecars are run (InitContainer or Entrypoint). With Entrypoint, every step must specify its command, as images' entrypoints aren't used.")
 	gcsFetcherImage = flag.String("gcs-fetcher-image", "gcr.io/cloud-builders/gcs-fetcher:latest",
 		"The container image containing our GCS fetcher binary.")
+	// The container with the binary that fetches HTTP sources.
+	httpFetcherImage = flag.String("http-fetcher-image", "override-with-http-fetcher:latest",
+		"The container image containing our HTTP fetcher binary.")
+	// The container with the binary that fetches image sources.
+	imageFetcherImage = flag.String("image-fetcher-image", "override-with-image-fetcher:latest",
+		"The container image containing our image fetcher binary.")
+	// The container with the binary that fetches S3 sources.
+	s3FetcherImage = flag.String("s3-fetcher-image", "override-with-s3-fetcher:latest",
+		"The container image containing our S3 fetcher binary.")
+	// The container with the binary that uploads artifacts.
+	artifactUploaderImage = flag.String("artifact-uploader-image", "override-with-artifact-uploader:latest",
+		"The container image containing our artifact uploader binary.")
 )
 
 // TODO(mattmoor): Should we move this somewhere common, because of the flag?
-func gitToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, error) {
+// gitToContainer returns the container that fetches the Git source, and
+// the volume holding the keys that its signature is verified against, if
+// it is verified.
+func gitToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, *corev1.Volume, error) {
 	git := source.Git
 	if git.Url == "" {
-		return nil, apis.ErrMissingField("b.spec.source.git.url")
+		return nil, nil, apis.ErrMissingField("b.spec.source.git.url")
 	}
 	if git.Revision == "" {
-		return nil, apis.ErrMissingField("b.spec.source.git.revision")
+		return nil, nil, apis.ErrMissingField("b.spec.source.git.revision")
 	}
 
 	args := []string{"-url", git.Url,
@@ -121,24 +186,66 @@ func gitToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, e
 	if source.TargetPath != "" {
 		args = append(args, []string{"-path", source.TargetPath}...)
 	}
+	if git.Depth != nil {
+		args = append(args, "-depth", strconv.Itoa(int(*git.Depth)))
+	}
+	for _, p := range git.SparseCheckout {
+		args = append(args, "-sparse_checkout", p)
+	}
+	if git.Submodules != nil {
+		if *git.Submodules {
+			args = append(args, "-checkout_submodules")
+		} else {
+			args = append(args, "-submodules=false")
+		}
+	}
+	if git.LFS {
+		args = append(args, "-lfs")
+	}
 
-	containerName := initContainerPrefix + gitSource + "-"
-
-	// update container name to suffix source name
-	if source.Name != "" {
-		containerName = containerName + source.Name
-	} else {
-		containerName = containerName + strconv.Itoa(index)
+	volumeMounts := implicitVolumeMounts
+	var volume *corev1.Volume
+	if v := git.Verify; v != nil {
+		name := fmt.Sprintf("%s-%d-keys", gitSource, index)
+		mountPath := filepath.Join("/var/build-git-keys", strconv.Itoa(index))
+		args = append(args, "-verify", string(v.Type), "-trusted_keys_dir", mountPath)
+		volumeMounts = append(volumeMounts[:len(volumeMounts):len(volumeMounts)], corev1.VolumeMount{
+			Name:      name,
+			MountPath: mountPath,
+			ReadOnly:  true,
+		})
+		volume = &corev1.Volume{Name: name}
+		if v.TrustedKeysSecret != "" {
+			volume.Secret = &corev1.SecretVolumeSource{
+				SecretName: v.TrustedKeysSecret,
+			}
+		} else {
+			volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: v.TrustedKeysConfigMap},
+			}
+		}
 	}
 
 	return &corev1.Container{
-		Name:         containerName,
+		Name:         gitContainerName(source, index),
 		Image:        *gitImage,
 		Args:         args,
-		VolumeMounts: implicitVolumeMounts,
+		VolumeMounts: volumeMounts,
 		WorkingDir:   workspaceDir,
 		Env:          implicitEnvVars,
-	}, nil
+	}, volume, nil
+}
+
+// gitContainerName returns the name of the container that fetches the
+// index'th source of a build, which is a Git source.
+func gitContainerName(source v1alpha1.SourceSpec, index int) string {
+	containerName := initContainerPrefix + gitSource + "-"
+
+	// update container name to suffix source name
+	if source.Name != "" {
+		return containerName + source.Name
+	}
+	return containerName + strconv.Itoa(index)
 }
 
 func gcsToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, error) {
@@ -172,6 +279,194 @@ func gcsToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, e
 	}, nil
 }
 
+// httpToContainer returns the container that fetches the HTTP source, and
+// the volume holding its request headers, if it has any.
+func httpToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, *corev1.Volume, error) {
+	h := source.HTTP
+	if h.URL == "" {
+		return nil, nil, apis.ErrMissingField("b.spec.source.http.url")
+	}
+	format := h.Format
+	if format == "" {
+		format = v1alpha1.HTTPFile
+	}
+	args := []string{"-url", h.URL,
+		"-format", string(format),
+		"-dest_dir", filepath.Join(workspaceDir, source.TargetPath),
+	}
+	if h.SHA256 != "" {
+		args = append(args, "-sha256", h.SHA256)
+	}
+
+	containerName := initContainerPrefix + httpSource + "-"
+	if source.Name != "" {
+		containerName = containerName + source.Name
+	} else {
+		containerName = containerName + strconv.Itoa(index)
+	}
+
+	volumeMounts := implicitVolumeMounts
+	var volume *corev1.Volume
+	if h.HeadersSecret != "" {
+		name := fmt.Sprintf("%s-%d-headers", httpSource, index)
+		mountPath := filepath.Join("/var/build-http-headers", strconv.Itoa(index))
+		args = append(args, "-headers_dir", mountPath)
+		volumeMounts = append(volumeMounts[:len(volumeMounts):len(volumeMounts)], corev1.VolumeMount{
+			Name:      name,
+			MountPath: mountPath,
+			ReadOnly:  true,
+		})
+		volume = &corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: h.HeadersSecret,
+				},
+			},
+		}
+	}
+
+	return &corev1.Container{
+		Name:         containerName,
+		Image:        *httpFetcherImage,
+		Args:         args,
+		VolumeMounts: volumeMounts,
+		WorkingDir:   workspaceDir,
+		Env:          implicitEnvVars,
+	}, volume, nil
+}
+
+func imageToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, error) {
+	image := source.Image
+	if image.Reference == "" {
+		return nil, apis.ErrMissingField("b.spec.source.image.reference")
+	}
+	args := []string{"-image", image.Reference,
+		"-dest_dir", filepath.Join(workspaceDir, source.TargetPath),
+	}
+	if image.Path != "" {
+		args = append(args, "-path", image.Path)
+	}
+
+	return &corev1.Container{
+		Name:         imageContainerName(source, index),
+		Image:        *imageFetcherImage,
+		Args:         args,
+		VolumeMounts: implicitVolumeMounts,
+		WorkingDir:   workspaceDir,
+		Env:          implicitEnvVars,
+	}, nil
+}
+
+// imageContainerName returns the name of the container that fetches the
+// image source.
+func imageContainerName(source v1alpha1.SourceSpec, index int) string {
+	if source.Name != "" {
+		return initContainerPrefix + imageSource + "-" + source.Name
+	}
+	return initContainerPrefix + imageSource + "-" + strconv.Itoa(index)
+}
+
+func s3ToContainer(source v1alpha1.SourceSpec, index int) (*corev1.Container, error) {
+	s3 := source.S3
+	if s3.Bucket == "" {
+		return nil, apis.ErrMissingField("b.spec.source.s3.bucket")
+	}
+	args := []string{"-bucket", s3.Bucket}
+	if s3.Prefix != "" {
+		args = append(args, "-prefix", s3.Prefix)
+	} else {
+		args = append(args, "-key", s3.Key)
+		if s3.Type != "" {
+			args = append(args, "-type", string(s3.Type))
+		}
+	}
+	if s3.Endpoint != "" {
+		args = append(args, "-endpoint", s3.Endpoint)
+	}
+	if s3.Region != "" {
+		args = append(args, "-region", s3.Region)
+	}
+	args = append(args, "-dest_dir", filepath.Join(workspaceDir, source.TargetPath))
+
+	containerName := initContainerPrefix + s3Source + "-"
+	if source.Name != "" {
+		containerName = containerName + source.Name
+	} else {
+		containerName = containerName + strconv.Itoa(index)
+	}
+
+	return &corev1.Container{
+		Name:         containerName,
+		Image:        *s3FetcherImage,
+		Args:         args,
+		VolumeMounts: implicitVolumeMounts,
+		WorkingDir:   workspaceDir,
+		Env:          implicitEnvVars,
+	}, nil
+}
+
+// artifactsToContainer returns the step that uploads the build's artifacts
+// from the workspace, mounted at subPath, and the volume of the claim to
+// which they are copied, if any.
+func artifactsToContainer(a *v1alpha1.ArtifactsSpec, workspaceSubPath string) (*corev1.Container, *corev1.Volume) {
+	var args []string
+	for _, p := range a.Paths {
+		args = append(args, "-path", p)
+	}
+
+	var volumeMounts []corev1.VolumeMount
+	for _, imp := range implicitVolumeMounts {
+		if imp.Name == "workspace" {
+			imp.SubPath = workspaceSubPath
+		}
+		volumeMounts = append(volumeMounts, imp)
+	}
+
+	var volume *corev1.Volume
+	if s3 := a.Destination.S3; s3 != nil {
+		args = append(args, "-bucket", s3.Bucket)
+		if s3.Prefix != "" {
+			args = append(args, "-prefix", s3.Prefix)
+		}
+		if s3.Endpoint != "" {
+			args = append(args, "-endpoint", s3.Endpoint)
+		}
+		if s3.Region != "" {
+			args = append(args, "-region", s3.Region)
+		}
+	} else if pvc := a.Destination.PersistentVolumeClaim; pvc != nil {
+		args = append(args, "-dest_dir", artifactsDir, "-claim_name", pvc.ClaimName)
+		if pvc.Path != "" {
+			args = append(args, "-claim_path", pvc.Path)
+		}
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      artifactsVolume,
+			MountPath: artifactsDir,
+			SubPath:   pvc.Path,
+		})
+		volume = &corev1.Volume{
+			Name: artifactsVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pvc.ClaimName,
+				},
+			},
+		}
+	}
+
+	return &corev1.Container{
+		Name:  initContainerPrefix + artifactUploader,
+		Image: *artifactUploaderImage,
+		// The command is needed when the step is run by the entrypoint.
+		Command:      []string{filepath.Join(koAppDir, artifactUploader)},
+		Args:         args,
+		VolumeMounts: volumeMounts,
+		WorkingDir:   workspaceDir,
+		Env:          implicitEnvVars,
+	}, volume
+}
+
 func customToContainer(source *corev1.Container, name string) (*corev1.Container, error) {
 	if source.Name != "" {
 		return nil, apis.ErrMissingField("b.spec.source.name")
@@ -199,7 +494,7 @@ func makeCredentialInitializer(build *v1alpha1.Build, kubeclient kubernetes.Inte
 		return nil, nil, err
 	}
 
-	builders := []credentials.Builder{dockercreds.NewBuilder(), gitcreds.NewBuilder()}
+	builders := credentials.Builders()
 
 	// Collect the volume declarations, there mounts into the cred-init container, and the arguments to it.
 	volumes := []corev1.Volume{}
@@ -282,21 +577,50 @@ func MakePod(build *v1alpha1.Build, kubeclient kubernetes.Interface) (*corev1.Po
 		sources = append(sources, source)
 	}
 	workspaceSubPath := ""
+	// Custom sources are prepended to the steps, so are skipped when
+	// matching steps to their timeouts.
+	stepTimeouts := build.Spec.StepTimeouts
+	var customSources int
+	var sourceVolumes []corev1.Volume
 
 	for i, source := range sources {
 		switch {
 		case source.Git != nil:
-			git, err := gitToContainer(source, i)
+			git, volume, err := gitToContainer(source, i)
 			if err != nil {
 				return nil, err
 			}
 			initContainers = append(initContainers, *git)
+			if volume != nil {
+				sourceVolumes = append(sourceVolumes, *volume)
+			}
 		case source.GCS != nil:
 			gcs, err := gcsToContainer(source, i)
 			if err != nil {
 				return nil, err
 			}
 			initContainers = append(initContainers, *gcs)
+		case source.HTTP != nil:
+			http, volume, err := httpToContainer(source, i)
+			if err != nil {
+				return nil, err
+			}
+			initContainers = append(initContainers, *http)
+			if volume != nil {
+				sourceVolumes = append(sourceVolumes, *volume)
+			}
+		case source.Image != nil:
+			image, err := imageToContainer(source, i)
+			if err != nil {
+				return nil, err
+			}
+			initContainers = append(initContainers, *image)
+		case source.S3 != nil:
+			s3, err := s3ToContainer(source, i)
+			if err != nil {
+				return nil, err
+			}
+			initContainers = append(initContainers, *s3)
 		case source.Custom != nil:
 			cust, err := customToContainer(source.Custom, source.Name)
 			if err != nil {
@@ -304,11 +628,17 @@ func MakePod(build *v1alpha1.Build, kubeclient kubernetes.Interface) (*corev1.Po
 			}
 			// Prepend the custom container to the steps, to be augmented later with env, volume mounts, etc.
 			build.Spec.Steps = append([]corev1.Container{*cust}, build.Spec.Steps...)
+			stepTimeouts = append([]metav1.Duration{{}}, stepTimeouts...)
+			customSources++
 		}
 		// webhook validation checks that only one source has subPath defined
 		workspaceSubPath = source.SubPath
 	}
 
+	cacheVolumes, cacheMounts := cacheVolumes(build)
+
+	timeouts := map[string]string{}
+	var steps []corev1.Container
 	for i, step := range build.Spec.Steps {
 		step.Env = append(implicitEnvVars, step.Env...)
 		// TODO(mattmoor): Check that volumeMounts match volumes.
@@ -330,6 +660,11 @@ func MakePod(build *v1alpha1.Build, kubeclient kubernetes.Interface) (*corev1.Po
 				step.VolumeMounts = append(step.VolumeMounts, imp)
 			}
 		}
+		for _, cm := range cacheMounts {
+			if !requestedVolumeMounts.Has(filepath.Clean(cm.MountPath)) {
+				step.VolumeMounts = append(step.VolumeMounts, cm)
+			}
+		}
 
 		if step.WorkingDir == "" {
 			step.WorkingDir = workspaceDir
@@ -339,13 +674,85 @@ func MakePod(build *v1alpha1.Build, kubeclient kubernetes.Interface) (*corev1.Po
 		} else {
 			step.Name = fmt.Sprintf("%v%v", initContainerPrefix, step.Name)
 		}
+		if i < len(stepTimeouts) && stepTimeouts[i].Duration > 0 {
+			timeouts[step.Name] = stepTimeouts[i].Duration.String()
+		}
 
-		initContainers = append(initContainers, step)
+		steps = append(steps, step)
+	}
+	// The artifacts are uploaded by a step after the build's own steps.
+	stepPolicies := build.Spec.StepPolicies
+	var artifactVolumes []corev1.Volume
+	if a := build.Spec.Artifacts; a != nil {
+		uploader, volume := artifactsToContainer(a, workspaceSubPath)
+		steps = append(steps, *uploader)
+		if volume != nil {
+			artifactVolumes = append(artifactVolumes, *volume)
+		}
+		if a.Always {
+			stepPolicies = append(stepPolicies[:len(stepPolicies):len(stepPolicies)], v1alpha1.StepPolicy{
+				Step:      artifactUploader,
+				RunPolicy: v1alpha1.RunAlways,
+			})
+		}
+	}
+	if len(build.Spec.Results) > 0 {
+		var names []string
+		for _, r := range build.Spec.Results {
+			names = append(names, r.Name)
+		}
+		annotations[resultsAnnotationKey] = strings.Join(names, ",")
+	}
+	if len(timeouts) > 0 {
+		b, err := json.Marshal(timeouts)
+		if err != nil {
+			return nil, err
+		}
+		annotations[stepTimeoutsAnnotationKey] = string(b)
 	}
 	// Add our implicit volumes and any volumes needed for secrets to the explicitly
 	// declared user volumes.
 	volumes := append(build.Spec.Volumes, implicitVolumes...)
 	volumes = append(volumes, secrets...)
+	volumes = append(volumes, sourceVolumes...)
+	volumes = append(volumes, cacheVolumes...)
+	volumes = append(volumes, artifactVolumes...)
+
+	var containers []corev1.Container
+	// Only steps run as regular containers can run after a step failed.
+	if len(build.Spec.Sidecars) > 0 || len(stepPolicies) > 0 || *stepRunner == EntrypointStepRunner {
+		// Custom sources still run as init containers, like the other
+		// sources, so their images' entrypoints are used and they aren't
+		// reported as steps.
+		initContainers = append(initContainers, steps[:customSources]...)
+		// The policies of a build that uses a template are only checked
+		// against its steps once the template is applied.
+		if err := v1alpha1.ValidateStepPolicies(build.Spec.StepPolicies, build.Spec.Steps[customSources:]); err != nil {
+			return nil, err
+		}
+		steps, err = entrypointSteps(steps[customSources:], stepPolicies)
+		if err != nil {
+			return nil, err
+		}
+		initContainers = append(initContainers, corev1.Container{
+			Name:         initContainerPrefix + placeTools,
+			Image:        *entrypointImage,
+			Args:         []string{"-copy_to", filepath.Join(toolsDir, "entrypoint")},
+			VolumeMounts: []corev1.VolumeMount{toolsVolumeMount},
+		})
+		containers = append(steps, sidecarContainers(build.Spec.Sidecars)...)
+		volumes = append(volumes, corev1.Volume{
+			Name:         toolsVolumeMount.Name,
+			VolumeSource: emptyVolumeSource,
+		})
+		annotations[stepRunnerAnnotationKey] = EntrypointStepRunner
+	} else {
+		initContainers = append(initContainers, steps...)
+		containers = []corev1.Container{{
+			Name:  "nop",
+			Image: *nopImage,
+		}}
+	}
 	if err := v1alpha1.ValidateVolumes(volumes); err != nil {
 		return nil, err
 	}
@@ -376,12 +783,9 @@ func MakePod(build *v1alpha1.Build, kubeclient kubernetes.Interface) (*corev1.Po
 		},
 		Spec: corev1.PodSpec{
 			// If the build fails, don't restart it.
-			RestartPolicy:  corev1.RestartPolicyNever,
-			InitContainers: initContainers,
-			Containers: []corev1.Container{{
-				Name:  "nop",
-				Image: *nopImage,
-			}},
+			RestartPolicy:      corev1.RestartPolicyNever,
+			InitContainers:     initContainers,
+			Containers:         containers,
 			ServiceAccountName: build.Spec.ServiceAccountName,
 			Volumes:            volumes,
 			NodeSelector:       build.Spec.NodeSelector,
@@ -390,6 +794,77 @@ func MakePod(build *v1alpha1.Build, kubeclient kubernetes.Interface) (*corev1.Po
 	}, nil
 }
 
+// toolsVolumeMount mounts the directory holding the entrypoint binary and
+// the files that order steps.
+var toolsVolumeMount = corev1.VolumeMount{
+	Name:      "tools",
+	MountPath: toolsDir,
+}
+
+// entrypointSteps rewrites the steps to run their commands through the
+// entrypoint binary, which waits for the previous step to finish and applies
+// the step's policy. Each step must specify its command, since the
+// entrypoint of its image isn't known.
+func entrypointSteps(steps []corev1.Container, policies []v1alpha1.StepPolicy) ([]corev1.Container, error) {
+	names := sets.NewString()
+	for _, step := range steps {
+		names.Insert(step.Name)
+	}
+	byStep := map[string]v1alpha1.StepPolicy{}
+	for _, p := range policies {
+		if !names.Has(initContainerPrefix + p.Step) {
+			return nil, apis.ErrInvalidValue(p.Step, "b.spec.stepPolicies.step")
+		}
+		byStep[initContainerPrefix+p.Step] = p
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		if len(step.Command) == 0 {
+			return nil, &apis.FieldError{
+				Message: fmt.Sprintf("step %q must specify a command to run as a regular container", step.Name),
+				Paths:   []string{"command"},
+			}
+		}
+		args := []string{"-step_name", step.Name}
+		if p, ok := byStep[step.Name]; ok {
+			if p.RunPolicy != "" {
+				args = append(args, "-run_policy", string(p.RunPolicy))
+			}
+			if p.When != nil {
+				args = append(args, "-when_step", initContainerPrefix+p.When.Step, "-when_outcome", string(p.When.Outcome))
+			}
+		}
+		if i > 0 {
+			args = append(args, "-wait_file", filepath.Join(toolsDir, strconv.Itoa(i-1)))
+		}
+		if i < len(steps)-1 {
+			args = append(args, "-post_file", filepath.Join(toolsDir, strconv.Itoa(i)))
+		}
+		args = append(args, "-entrypoint", step.Command[0], "--")
+		args = append(args, step.Command[1:]...)
+		step.Args = append(args, step.Args...)
+		step.Command = []string{filepath.Join(toolsDir, "entrypoint")}
+		step.VolumeMounts = append(step.VolumeMounts, toolsVolumeMount)
+	}
+	return steps, nil
+}
+
+// sidecarContainers names the build's sidecars so that they can be told
+// apart from its steps.
+func sidecarContainers(sidecars []corev1.Container) []corev1.Container {
+	var containers []corev1.Container
+	for i, sidecar := range sidecars {
+		if sidecar.Name == "" {
+			sidecar.Name = fmt.Sprintf("%v%d", unnamedSidecarPrefix, i)
+		} else {
+			sidecar.Name = sidecarPrefix + sidecar.Name
+		}
+		containers = append(containers, sidecar)
+	}
+	return containers
+}
+
 // GetUniquePodName returns a unique name based on the build's name.
 func GetUniquePodName(name string) (string, error) {
 	// Generate a short random hex string.
@@ -413,25 +888,49 @@ func BuildStatusFromPod(p *corev1.Pod, buildSpec v1alpha1.BuildSpec) v1alpha1.Bu
 		StartTime: &p.CreationTimestamp,
 	}
 
-	// Always ignore the first pod status, which is creds-init.
-	skip := 1
-	if buildSpec.Source != nil {
-		// If the build specifies source, skip another container status, which
-		// is the source-fetching container.
-		skip++
-	}
-	// Also skip multiple sources specified by the build.
-	skip += len(buildSpec.Sources)
-	if skip <= len(p.Status.InitContainerStatuses) {
-		for _, s := range p.Status.InitContainerStatuses[skip:] {
-			if s.State.Terminated != nil {
-				status.StepsCompleted = append(status.StepsCompleted, s.Name)
-			}
+	declared := declaredResults(p)
+	for _, s := range stepStatuses(p, buildSpec) {
+		if term := s.State.Terminated; term != nil && term.ExitCode == 0 && term.Message == entrypoint.SkippedMessage {
+			status.StepsSkipped = append(status.StepsSkipped, s.Name)
+			s.State.Terminated = term.DeepCopy()
+			s.State.Terminated.Reason = "Skipped"
 			status.StepStates = append(status.StepStates, s.State)
+			continue
+		}
+		if s.State.Terminated != nil {
+			status.StepsCompleted = append(status.StepsCompleted, s.Name)
+			// The uploader reports the artifacts rather than results.
+			if s.State.Terminated.ExitCode == 0 && s.Name != initContainerPrefix+artifactUploader {
+				status.Results = addResults(status.Results, s.State.Terminated.Message)
+			}
+		}
+		status.StepStates = append(status.StepStates, s.State)
+	}
+
+	status.Results = declaredOnly(status.Results, declared)
+	status.SourcesStatus = sourcesStatus(p, buildSpec)
+	status.Artifacts, status.ArtifactsOmitted = artifactsStatus(p)
+
+	if p.Status.Phase == corev1.PodPending || p.Status.Phase == corev1.PodRunning {
+		if step, timeout := TimedOutStep(p, time.Now()); step != "" {
+			status.SetCondition(&duckv1alpha1.Condition{
+				Type:    v1alpha1.BuildSucceeded,
+				Status:  corev1.ConditionFalse,
+				Reason:  "StepTimeout",
+				Message: fmt.Sprintf("build step %q failed to finish within %q", step, timeout.String()),
+			})
+			status.CompletionTime = &metav1.Time{Time: time.Now()}
+			return status
 		}
 	}
 
-	switch p.Status.Phase {
+	phase := p.Status.Phase
+	if phase == corev1.PodRunning && p.Annotations[stepRunnerAnnotationKey] == EntrypointStepRunner {
+		// Sidecars keep the pod running after its steps finish.
+		phase = entrypointPhase(p, status.StepStates)
+	}
+
+	switch phase {
 	case corev1.PodRunning:
 		status.SetCondition(&duckv1alpha1.Condition{
 			Type:   v1alpha1.BuildSucceeded,
@@ -439,10 +938,17 @@ func BuildStatusFromPod(p *corev1.Pod, buildSpec v1alpha1.BuildSpec) v1alpha1.Bu
 			Reason: "Building",
 		})
 	case corev1.PodFailed:
+		var reason string
 		msg := getFailureMessage(p)
+		if m := checksumMismatch(p); m != "" {
+			reason, msg = httpfetch.ChecksumMismatchReason, m
+		} else if m := unverifiedRevision(p); m != "" {
+			reason, msg = gitverify.UnverifiedRevisionReason, m
+		}
 		status.SetCondition(&duckv1alpha1.Condition{
 			Type:    v1alpha1.BuildSucceeded,
 			Status:  corev1.ConditionFalse,
+			Reason:  reason,
 			Message: msg,
 		})
 	case corev1.PodPending:
@@ -454,6 +960,15 @@ func BuildStatusFromPod(p *corev1.Pod, buildSpec v1alpha1.BuildSpec) v1alpha1.Bu
 			Message: msg,
 		})
 	case corev1.PodSucceeded:
+		if missing := missingResults(status.Results, declared); len(missing) > 0 {
+			status.SetCondition(&duckv1alpha1.Condition{
+				Type:    v1alpha1.BuildSucceeded,
+				Status:  corev1.ConditionFalse,
+				Reason:  "MissingResults",
+				Message: fmt.Sprintf("build steps did not report the declared results %s", strings.Join(missing, ", ")),
+			})
+			break
+		}
 		status.SetCondition(&duckv1alpha1.Condition{
 			Type:   v1alpha1.BuildSucceeded,
 			Status: corev1.ConditionTrue,
@@ -462,9 +977,354 @@ func BuildStatusFromPod(p *corev1.Pod, buildSpec v1alpha1.BuildSpec) v1alpha1.Bu
 	return status
 }
 
+// stepStatuses returns the statuses of the pod's steps, in order.
+func stepStatuses(p *corev1.Pod, buildSpec v1alpha1.BuildSpec) []corev1.ContainerStatus {
+	if p.Annotations[stepRunnerAnnotationKey] == EntrypointStepRunner {
+		// Sources, including custom ones, run as init containers, so
+		// the steps are the containers other than the sidecars.
+		var statuses []corev1.ContainerStatus
+		for _, s := range containerStatuses(p.Status.ContainerStatuses, p.Spec.Containers) {
+			if strings.HasPrefix(s.Name, initContainerPrefix) {
+				statuses = append(statuses, s)
+			}
+		}
+		return statuses
+	}
+
+	// Always ignore the first pod status, which is creds-init.
+	skip := 1
+	if buildSpec.Source != nil {
+		// If the build specifies source, skip another container status, which
+		// is the source-fetching container.
+		skip++
+	}
+	// Also skip multiple sources specified by the build.
+	skip += len(buildSpec.Sources)
+	if skip > len(p.Status.InitContainerStatuses) {
+		return nil
+	}
+	return p.Status.InitContainerStatuses[skip:]
+}
+
+// allStatuses returns the statuses of the pod's init containers followed by
+// those of its containers, in the order the containers run.
+func allStatuses(p *corev1.Pod) []corev1.ContainerStatus {
+	statuses := append([]corev1.ContainerStatus{}, p.Status.InitContainerStatuses...)
+	return append(statuses, containerStatuses(p.Status.ContainerStatuses, p.Spec.Containers)...)
+}
+
+// containerStatuses orders statuses like the containers they belong to,
+// since the kubelet doesn't report them in order. Statuses of unknown
+// containers come last.
+func containerStatuses(statuses []corev1.ContainerStatus, containers []corev1.Container) []corev1.ContainerStatus {
+	if len(containers) == 0 {
+		return statuses
+	}
+	byName := map[string]corev1.ContainerStatus{}
+	for _, s := range statuses {
+		byName[s.Name] = s
+	}
+	var ordered []corev1.ContainerStatus
+	for _, c := range containers {
+		if s, ok := byName[c.Name]; ok {
+			ordered = append(ordered, s)
+			delete(byName, c.Name)
+		}
+	}
+	for _, s := range statuses {
+		if _, ok := byName[s.Name]; ok {
+			ordered = append(ordered, s)
+		}
+	}
+	return ordered
+}
+
+// entrypointPhase returns the phase of a running pod whose steps run as
+// regular containers, based on the states of its steps: it has succeeded or
+// failed once all of them have terminated.
+func entrypointPhase(p *corev1.Pod, states []corev1.ContainerState) corev1.PodPhase {
+	steps := 0
+	for _, c := range p.Spec.Containers {
+		if strings.HasPrefix(c.Name, initContainerPrefix) {
+			steps++
+		}
+	}
+	if len(states) < steps {
+		return corev1.PodRunning
+	}
+	phase := corev1.PodSucceeded
+	for _, s := range states {
+		if s.Terminated == nil {
+			return corev1.PodRunning
+		}
+		if s.Terminated.ExitCode != 0 {
+			phase = corev1.PodFailed
+		}
+	}
+	return phase
+}
+
+// StopSidecars returns a copy of the pod whose running sidecars are replaced
+// by the nop image, which exits right away, so that the pod completes once
+// its steps have. It returns nil if the pod has no running sidecars.
+func StopSidecars(p *corev1.Pod) *corev1.Pod {
+	running := map[string]bool{}
+	for _, s := range p.Status.ContainerStatuses {
+		running[s.Name] = s.State.Terminated == nil
+	}
+	var stopped *corev1.Pod
+	for i, c := range p.Spec.Containers {
+		if !strings.HasPrefix(c.Name, sidecarPrefix) || !running[c.Name] || c.Image == *nopImage {
+			continue
+		}
+		if stopped == nil {
+			stopped = p.DeepCopy()
+		}
+		stopped.Spec.Containers[i].Image = *nopImage
+	}
+	return stopped
+}
+
+// sourcesStatus returns the commits and digests that the build's Git and
+// image sources resolved to, and the signers of the verified Git sources,
+// as reported by the fetchers that have finished.
+func sourcesStatus(p *corev1.Pod, buildSpec v1alpha1.BuildSpec) []v1alpha1.SourceStatus {
+	var sources []v1alpha1.SourceSpec
+	if buildSpec.Source != nil {
+		sources = append(sources, *buildSpec.Source)
+	}
+	sources = append(sources, buildSpec.Sources...)
+
+	terminated := map[string]*corev1.ContainerStateTerminated{}
+	for _, s := range p.Status.InitContainerStatuses {
+		if s.State.Terminated != nil && s.State.Terminated.ExitCode == 0 {
+			terminated[s.Name] = s.State.Terminated
+		}
+	}
+
+	var statuses []v1alpha1.SourceStatus
+	for i, source := range sources {
+		var name string
+		switch {
+		case source.Git != nil:
+			name = gitContainerName(source, i)
+		case source.Image != nil:
+			name = imageContainerName(source, i)
+		default:
+			continue
+		}
+		term, ok := terminated[name]
+		if !ok {
+			continue
+		}
+		status := v1alpha1.SourceStatus{Name: source.Name}
+		for _, r := range addResults(nil, term.Message) {
+			switch {
+			case r.Name == "commit" && source.Git != nil:
+				status.Commit = r.Value
+			case r.Name == "signer" && source.Git != nil:
+				status.Signer = r.Value
+			case r.Name == "signingKey" && source.Git != nil:
+				status.SigningKey = r.Value
+			case r.Name == "digest" && source.Image != nil:
+				status.Digest = r.Value
+			}
+		}
+		if status.Commit != "" || status.Digest != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// artifactsStatus returns the artifacts reported by the uploader, once it
+// has succeeded, and the number of artifacts it left out.
+func artifactsStatus(p *corev1.Pod) ([]v1alpha1.ArtifactStatus, int32) {
+	for _, s := range allStatuses(p) {
+		if s.Name != initContainerPrefix+artifactUploader {
+			continue
+		}
+		term := s.State.Terminated
+		if term == nil || term.ExitCode != 0 || term.Message == entrypoint.SkippedMessage {
+			return nil, 0
+		}
+		uploaded, omitted := artifacts.ParseStatus(term.Message)
+		var statuses []v1alpha1.ArtifactStatus
+		for _, a := range uploaded {
+			statuses = append(statuses, v1alpha1.ArtifactStatus{Path: a.Path, URI: a.URI, SHA256: a.SHA256})
+		}
+		return statuses, int32(omitted)
+	}
+	return nil, 0
+}
+
+// declaredResults returns the names of the results that the pod's steps
+// must report, or nil if they declare none.
+func declaredResults(p *corev1.Pod) []string {
+	raw := p.Annotations[resultsAnnotationKey]
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+// declaredOnly returns the results whose names are declared, in the order
+// they were reported.
+func declaredOnly(results []v1alpha1.BuildResult, declared []string) []v1alpha1.BuildResult {
+	var filtered []v1alpha1.BuildResult
+	for _, r := range results {
+		for _, name := range declared {
+			if r.Name == name {
+				filtered = append(filtered, r)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// missingResults returns the declared results that weren't reported.
+func missingResults(results []v1alpha1.BuildResult, declared []string) []string {
+	var missing []string
+	for _, name := range declared {
+		found := false
+		for _, r := range results {
+			found = found || r.Name == name
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// addResults adds the results reported in a step's termination message to
+// results. Each result is reported on a line of the form "name=value"; other
+// lines are ignored. A result reported by a later step replaces that of an
+// earlier step with the same name.
+func addResults(results []v1alpha1.BuildResult, msg string) []v1alpha1.BuildResult {
+	for _, line := range strings.Split(msg, "\n") {
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			continue
+		}
+		r := v1alpha1.BuildResult{
+			Name:  strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+		}
+		if r.Name == "" || strings.ContainsAny(r.Name, " \t") {
+			continue
+		}
+
+		replaced := false
+		for j := range results {
+			if results[j].Name == r.Name {
+				results[j] = r
+				replaced = true
+			}
+		}
+		if !replaced {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// stepTimeouts returns the timeouts of the pod's step containers, by
+// container name.
+func stepTimeouts(p *corev1.Pod) map[string]time.Duration {
+	raw, ok := p.Annotations[stepTimeoutsAnnotationKey]
+	if !ok {
+		return nil
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal([]byte(raw), &encoded); err != nil {
+		return nil
+	}
+	timeouts := map[string]time.Duration{}
+	for name, v := range encoded {
+		if d, err := time.ParseDuration(v); err == nil {
+			timeouts[name] = d
+		}
+	}
+	return timeouts
+}
+
+// runningStep is a step of a pod that is running its command.
+type runningStep struct {
+	name    string
+	started time.Time
+}
+
+// runningSteps returns the steps of the pod that are running their
+// commands, in order. In entrypoint mode every step's container runs from
+// the start of the pod, but the entrypoint only starts a step's command
+// once the previous step has finished.
+func runningSteps(p *corev1.Pod) []runningStep {
+	var steps []runningStep
+	if p.Annotations[stepRunnerAnnotationKey] != EntrypointStepRunner {
+		for _, s := range allStatuses(p) {
+			if s.State.Running != nil {
+				steps = append(steps, runningStep{name: s.Name, started: s.State.Running.StartedAt.Time})
+			}
+		}
+		return steps
+	}
+	statuses := stepStatuses(p, v1alpha1.BuildSpec{})
+	for i, s := range statuses {
+		if s.State.Running == nil {
+			continue
+		}
+		started := s.State.Running.StartedAt.Time
+		if i > 0 {
+			previous := statuses[i-1].State.Terminated
+			if previous == nil {
+				// This and the later steps are waiting for the previous one.
+				break
+			}
+			if previous.FinishedAt.After(started) {
+				started = previous.FinishedAt.Time
+			}
+		}
+		steps = append(steps, runningStep{name: s.Name, started: started})
+	}
+	return steps
+}
+
+// TimedOutStep returns the name of the first running step of the pod that
+// has run longer than its timeout at the given time, and that timeout. It
+// returns an empty name if no step has timed out.
+func TimedOutStep(p *corev1.Pod, now time.Time) (string, time.Duration) {
+	timeouts := stepTimeouts(p)
+	for _, s := range runningSteps(p) {
+		timeout, ok := timeouts[s.name]
+		if !ok {
+			continue
+		}
+		if now.Sub(s.started) > timeout {
+			return s.name, timeout
+		}
+	}
+	return "", 0
+}
+
+// NextStepTimeout returns how long after the given time the pod's running
+// step will time out, and false if no running step has a timeout.
+func NextStepTimeout(p *corev1.Pod, now time.Time) (time.Duration, bool) {
+	timeouts := stepTimeouts(p)
+	for _, s := range runningSteps(p) {
+		timeout, ok := timeouts[s.name]
+		if !ok {
+			continue
+		}
+		return s.started.Add(timeout).Sub(now), true
+	}
+	return 0, false
+}
+
 func getWaitingMessage(pod *corev1.Pod) string {
 	// First, try to surface reason for pending/unknown about the actual build step.
-	for _, status := range pod.Status.InitContainerStatuses {
+	for _, status := range allStatuses(pod) {
 		wait := status.State.Waiting
 		if wait != nil && wait.Message != "" {
 			return fmt.Sprintf("build step %q is pending with reason %q",
@@ -489,9 +1349,44 @@ func getWaitingMessage(pod *corev1.Pod) string {
 	return "Pending"
 }
 
+// checksumMismatch returns a message describing the HTTP or S3 source of the
+// pod whose checksum didn't match, or "" if there is none.
+func checksumMismatch(pod *corev1.Pod) string {
+	for _, s := range pod.Status.InitContainerStatuses {
+		term := s.State.Terminated
+		if term == nil || term.ExitCode == 0 {
+			continue
+		}
+		if !strings.HasPrefix(s.Name, initContainerPrefix+httpSource) && !strings.HasPrefix(s.Name, initContainerPrefix+s3Source) {
+			continue
+		}
+		if strings.HasPrefix(term.Message, httpfetch.ChecksumMismatchReason) {
+			return fmt.Sprintf("source fetched by %q did not match its checksum: %s",
+				s.Name, strings.TrimPrefix(term.Message, httpfetch.ChecksumMismatchReason+": "))
+		}
+	}
+	return ""
+}
+
+// unverifiedRevision returns a message describing the Git source of the pod
+// whose revision wasn't signed by a trusted key, or "" if there is none.
+func unverifiedRevision(pod *corev1.Pod) string {
+	for _, s := range pod.Status.InitContainerStatuses {
+		term := s.State.Terminated
+		if term == nil || term.ExitCode == 0 || !strings.HasPrefix(s.Name, initContainerPrefix+gitSource) {
+			continue
+		}
+		if strings.HasPrefix(term.Message, gitverify.UnverifiedRevisionReason) {
+			return fmt.Sprintf("source fetched by %q failed signature verification: %s",
+				s.Name, strings.TrimPrefix(term.Message, gitverify.UnverifiedRevisionReason+": "))
+		}
+	}
+	return ""
+}
+
 func getFailureMessage(pod *corev1.Pod) string {
 	// First, try to surface an error about the actual build step that failed.
-	for _, status := range pod.Status.InitContainerStatuses {
+	for _, status := range allStatuses(pod) {
 		term := status.State.Terminated
 		if term != nil && term.ExitCode != 0 {
 			return fmt.Sprintf("build step %q exited with code %d (image: %q); for logs run: kubectl -n %s logs %s -c %s",
@@ -20,6 +20,7 @@ import (
 	"crypto/rand"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -29,6 +30,9 @@ import (
 	fakek8s "k8s.io/client-go/kubernetes/fake"
 
 	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/entrypoint"
+	"github.com/knative/build/pkg/gitverify"
+	"github.com/knative/build/pkg/httpfetch"
 	"github.com/knative/pkg/apis"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	"github.com/knative/pkg/system"
@@ -47,6 +51,9 @@ var (
 
 func TestMakePod(t *testing.T) {
 	subPath := "subpath"
+	fullDepth := int32(0)
+	noSubmodules := false
+	checkoutSubmodules := true
 	implicitVolumeMountsWithSubPath := []corev1.VolumeMount{}
 	for _, vm := range implicitVolumeMounts {
 		if vm.Name == "workspace" {
@@ -148,6 +155,118 @@ func TestMakePod(t *testing.T) {
 			Containers: []corev1.Container{nopContainer},
 			Volumes:    implicitVolumes,
 		},
+	}, {
+		desc: "git-source-fetch-options",
+		b: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				Git: &v1alpha1.GitSourceSpec{
+					Url:            "github.com/my/monorepo",
+					Revision:       "master",
+					Depth:          &fullDepth,
+					SparseCheckout: []string{"services/api/", "/go.mod"},
+					Submodules:     &noSubmodules,
+					LFS:            true,
+				},
+			},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:  initContainerPrefix + gitSource + "-0",
+				Image: *gitImage,
+				Args: []string{"-url", "github.com/my/monorepo", "-revision", "master", "-depth", "0",
+					"-sparse_checkout", "services/api/", "-sparse_checkout", "/go.mod", "-submodules=false", "-lfs"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes:    implicitVolumes,
+		},
+	}, {
+		desc: "git-source-checkout-submodules",
+		b: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				Git: &v1alpha1.GitSourceSpec{
+					Url:        "github.com/my/repo",
+					Revision:   "master",
+					Submodules: &checkoutSubmodules,
+				},
+			},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         initContainerPrefix + gitSource + "-0",
+				Image:        *gitImage,
+				Args:         []string{"-url", "github.com/my/repo", "-revision", "master", "-checkout_submodules"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes:    implicitVolumes,
+		},
+	}, {
+		desc: "git-source-verified",
+		b: v1alpha1.BuildSpec{
+			Sources: []v1alpha1.SourceSpec{{
+				Name: "app",
+				Git: &v1alpha1.GitSourceSpec{
+					Url:      "github.com/my/repo",
+					Revision: "v1.0.0",
+					Verify: &v1alpha1.GitVerifySpec{
+						Type:                 v1alpha1.GitSignatureGPG,
+						TrustedKeysConfigMap: "release-keys",
+					},
+				},
+			}},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:  initContainerPrefix + gitSource + "-app",
+				Image: *gitImage,
+				Args:  []string{"-url", "github.com/my/repo", "-revision", "v1.0.0", "-verify", "GPG", "-trusted_keys_dir", "/var/build-git-keys/0"},
+				Env:   implicitEnvVars,
+				VolumeMounts: append(implicitVolumeMounts, corev1.VolumeMount{
+					Name:      "git-source-0-keys",
+					MountPath: "/var/build-git-keys/0",
+					ReadOnly:  true,
+				}),
+				WorkingDir: workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes: append(implicitVolumes, corev1.Volume{
+				Name: "git-source-0-keys",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "release-keys"},
+					},
+				},
+			}),
+		},
 	}, {
 		desc: "sources",
 		b: v1alpha1.BuildSpec{
@@ -371,6 +490,188 @@ func TestMakePod(t *testing.T) {
 			Containers: []corev1.Container{nopContainer},
 			Volumes:    implicitVolumes,
 		},
+	}, {
+		desc: "http-source-with-headers",
+		b: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				HTTP: &v1alpha1.HTTPSourceSpec{
+					URL:           "https://example.com/src.tar.gz",
+					SHA256:        "6ca13d52ca70c883e0f0bb101e425a89e8624de51db2d2392593af6a84118090",
+					Format:        v1alpha1.HTTPTarGz,
+					HeadersSecret: "auth",
+				},
+				TargetPath: "path/foo",
+			},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:  initContainerPrefix + httpSource + "-0",
+				Image: *httpFetcherImage,
+				Args: []string{"-url", "https://example.com/src.tar.gz", "-format", "tar.gz", "-dest_dir", "/workspace/path/foo",
+					"-sha256", "6ca13d52ca70c883e0f0bb101e425a89e8624de51db2d2392593af6a84118090",
+					"-headers_dir", "/var/build-http-headers/0"},
+				Env: implicitEnvVars,
+				VolumeMounts: append(implicitVolumeMounts[:len(implicitVolumeMounts):len(implicitVolumeMounts)], corev1.VolumeMount{
+					Name:      "http-source-0-headers",
+					MountPath: "/var/build-http-headers/0",
+					ReadOnly:  true,
+				}),
+				WorkingDir: workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes: append(implicitVolumes[:len(implicitVolumes):len(implicitVolumes)], corev1.Volume{
+				Name: "http-source-0-headers",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "auth"},
+				},
+			}),
+		},
+	}, {
+		desc: "image-source-with-path",
+		b: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				Name: "bundle",
+				Image: &v1alpha1.ImageSourceSpec{
+					Reference: "gcr.io/foo/bundle:latest",
+					Path:      "/app",
+				},
+				TargetPath: "path/foo",
+			},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         initContainerPrefix + imageSource + "-bundle",
+				Image:        *imageFetcherImage,
+				Args:         []string{"-image", "gcr.io/foo/bundle:latest", "-dest_dir", "/workspace/path/foo", "-path", "/app"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes:    implicitVolumes,
+		},
+	}, {
+		desc: "s3-source",
+		b: v1alpha1.BuildSpec{
+			Sources: []v1alpha1.SourceSpec{{
+				Name: "minio",
+				S3: &v1alpha1.S3SourceSpec{
+					Type:     v1alpha1.S3Manifest,
+					Bucket:   "src",
+					Key:      "manifest.json",
+					Endpoint: "https://minio.example.com:9000",
+				},
+			}, {
+				S3: &v1alpha1.S3SourceSpec{
+					Bucket: "assets",
+					Prefix: "images/",
+					Region: "eu-west-1",
+				},
+				TargetPath: "assets",
+			}},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         initContainerPrefix + s3Source + "-minio",
+				Image:        *s3FetcherImage,
+				Args:         []string{"-bucket", "src", "-key", "manifest.json", "-type", "Manifest", "-endpoint", "https://minio.example.com:9000", "-dest_dir", "/workspace"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         initContainerPrefix + s3Source + "-1",
+				Image:        *s3FetcherImage,
+				Args:         []string{"-bucket", "assets", "-prefix", "images/", "-region", "eu-west-1", "-dest_dir", "/workspace/assets"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes:    implicitVolumes,
+		},
+	}, {
+		desc: "artifacts-to-s3",
+		b: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				Git: &v1alpha1.GitSourceSpec{
+					Url:      "github.com/my-org/my-repo",
+					Revision: "master",
+				},
+				SubPath: subPath,
+			},
+			Steps: []corev1.Container{{
+				Name:  "name",
+				Image: "image",
+			}},
+			Artifacts: &v1alpha1.ArtifactsSpec{
+				Paths: []string{"bin/*", "report.xml"},
+				Destination: v1alpha1.ArtifactDestination{
+					S3: &v1alpha1.S3ArtifactDestination{
+						Bucket:   "builds",
+						Prefix:   "my-repo/",
+						Endpoint: "https://storage.googleapis.com",
+					},
+				},
+			},
+		},
+		want: &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{{
+				Name:         initContainerPrefix + credsInit,
+				Image:        *credsImage,
+				Args:         []string{},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         initContainerPrefix + gitSource + "-0",
+				Image:        *gitImage,
+				Args:         []string{"-url", "github.com/my-org/my-repo", "-revision", "master"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMounts,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         "build-step-name",
+				Image:        "image",
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMountsWithSubPath,
+				WorkingDir:   workspaceDir,
+			}, {
+				Name:         initContainerPrefix + artifactUploader,
+				Image:        *artifactUploaderImage,
+				Command:      []string{"/ko-app/artifact-uploader"},
+				Args:         []string{"-path", "bin/*", "-path", "report.xml", "-bucket", "builds", "-prefix", "my-repo/", "-endpoint", "https://storage.googleapis.com"},
+				Env:          implicitEnvVars,
+				VolumeMounts: implicitVolumeMountsWithSubPath,
+				WorkingDir:   workspaceDir,
+			}},
+			Containers: []corev1.Container{nopContainer},
+			Volumes:    implicitVolumes,
+		},
 	}, {
 		desc: "custom-source-with-subpath",
 		b: v1alpha1.BuildSpec{
@@ -430,6 +731,8 @@ func TestMakePod(t *testing.T) {
 					"-basic-docker=multi-creds=https://us.gcr.io",
 					"-basic-git=multi-creds=github.com",
 					"-basic-git=multi-creds=gitlab.com",
+					"-basic-netrc=multi-creds=example.com",
+					"-basic-npm=multi-creds=https://npm.example.com/",
 				},
 				Env:          implicitEnvVars,
 				VolumeMounts: implicitVolumeMountsWithSecrets,
@@ -460,6 +763,8 @@ func TestMakePod(t *testing.T) {
 							"build.knative.dev/docker-1": "https://docker.io",
 							"build.knative.dev/git-0":    "github.com",
 							"build.knative.dev/git-1":    "gitlab.com",
+							"build.knative.dev/netrc-0":  "example.com",
+							"build.knative.dev/npm-0":    "https://npm.example.com/",
 						}},
 					Type: "kubernetes.io/basic-auth",
 					Data: map[string][]byte{
@@ -510,10 +815,11 @@ func TestMakePod(t *testing.T) {
 
 func TestBuildStatusFromPod(t *testing.T) {
 	for _, c := range []struct {
-		desc      string
-		podStatus corev1.PodStatus
-		buildSpec v1alpha1.BuildSpec
-		want      v1alpha1.BuildStatus
+		desc        string
+		annotations map[string]string
+		podStatus   corev1.PodStatus
+		buildSpec   v1alpha1.BuildSpec
+		want        v1alpha1.BuildStatus
 	}{{
 		desc:      "empty",
 		podStatus: corev1.PodStatus{},
@@ -615,6 +921,81 @@ func TestBuildStatusFromPod(t *testing.T) {
 				},
 			}},
 		},
+	}, {
+		desc: "sources-commits-and-digests",
+		podStatus: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				// creds-init; ignored.
+			}, {
+				Name: "build-step-git-source-0",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message: "commit=8f2e0c1\n",
+					},
+				},
+			}, {
+				Name: "build-step-git-source-docs",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message: "commit=4a5b6c7\nsigner=Jane Doe <jane@example.com>\nsigningKey=AE4B3D8C\n",
+					},
+				},
+			}, {
+				Name: "build-step-gcs-source-2",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{},
+				},
+			}, {
+				Name: "build-step-image-source-bundle",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message: "digest=sha256:abc\n",
+					},
+				},
+			}},
+		},
+		buildSpec: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				Git: &v1alpha1.GitSourceSpec{
+					Url:      "example.com",
+					Revision: "master",
+				},
+			},
+			Sources: []v1alpha1.SourceSpec{{
+				Name: "docs",
+				Git: &v1alpha1.GitSourceSpec{
+					Url:      "example.com/docs",
+					Revision: "v1.0",
+					Verify: &v1alpha1.GitVerifySpec{
+						Type:                 v1alpha1.GitSignatureGPG,
+						TrustedKeysConfigMap: "release-keys",
+					},
+				},
+			}, {
+				GCS: &v1alpha1.GCSSourceSpec{
+					Type:     v1alpha1.GCSArchive,
+					Location: "gs://foo/bar",
+				},
+			}, {
+				Name: "bundle",
+				Image: &v1alpha1.ImageSourceSpec{
+					Reference: "gcr.io/foo/bundle:latest",
+				},
+			}},
+		},
+		want: v1alpha1.BuildStatus{
+			SourcesStatus: []v1alpha1.SourceStatus{{
+				Commit: "8f2e0c1",
+			}, {
+				Name:       "docs",
+				Commit:     "4a5b6c7",
+				Signer:     "Jane Doe <jane@example.com>",
+				SigningKey: "AE4B3D8C",
+			}, {
+				Name:   "bundle",
+				Digest: "sha256:abc",
+			}},
+		},
 	}, {
 		desc:      "success",
 		podStatus: corev1.PodStatus{Phase: corev1.PodSucceeded},
@@ -626,6 +1007,90 @@ func TestBuildStatusFromPod(t *testing.T) {
 				}},
 			},
 		},
+	}, {
+		desc:        "success-with-results",
+		annotations: map[string]string{resultsAnnotationKey: "commit,digest"},
+		podStatus: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				// creds-init status; ignored
+			}, {
+				Name: "build",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message: "commit=abc123\nnot a result\ndigest=sha256:old",
+					},
+				},
+			}, {
+				Name: "push",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message: "digest = sha256:new\n",
+					},
+				},
+			}},
+		},
+		want: v1alpha1.BuildStatus{
+			StepsCompleted: []string{"build", "push"},
+			StepStates: []corev1.ContainerState{{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: "commit=abc123\nnot a result\ndigest=sha256:old",
+				},
+			}, {
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: "digest = sha256:new\n",
+				},
+			}},
+			Results: []v1alpha1.BuildResult{{
+				Name:  "commit",
+				Value: "abc123",
+			}, {
+				Name:  "digest",
+				Value: "sha256:new",
+			}},
+			Status: duckv1alpha1.Status{
+				Conditions: []duckv1alpha1.Condition{{
+					Type:   v1alpha1.BuildSucceeded,
+					Status: corev1.ConditionTrue,
+				}},
+			},
+		},
+	}, {
+		desc:        "success-with-missing-results",
+		annotations: map[string]string{resultsAnnotationKey: "digest,url"},
+		podStatus: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				// creds-init status; ignored
+			}, {
+				Name: "build",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message: "undeclared=value\ndigest=sha256:abc",
+					},
+				},
+			}},
+		},
+		want: v1alpha1.BuildStatus{
+			StepsCompleted: []string{"build"},
+			StepStates: []corev1.ContainerState{{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: "undeclared=value\ndigest=sha256:abc",
+				},
+			}},
+			Results: []v1alpha1.BuildResult{{
+				Name:  "digest",
+				Value: "sha256:abc",
+			}},
+			Status: duckv1alpha1.Status{
+				Conditions: []duckv1alpha1.Condition{{
+					Type:    v1alpha1.BuildSucceeded,
+					Status:  corev1.ConditionFalse,
+					Reason:  "MissingResults",
+					Message: "build steps did not report the declared results url",
+				}},
+			},
+		},
 	}, {
 		desc:      "running",
 		podStatus: corev1.PodStatus{Phase: corev1.PodRunning},
@@ -650,6 +1115,8 @@ func TestBuildStatusFromPod(t *testing.T) {
 				State: corev1.ContainerState{
 					Terminated: &corev1.ContainerStateTerminated{
 						ExitCode: 123,
+						// Failed steps don't report results.
+						Message: "digest=sha256:abc",
 					},
 				},
 			}},
@@ -659,6 +1126,7 @@ func TestBuildStatusFromPod(t *testing.T) {
 			StepStates: []corev1.ContainerState{{
 				Terminated: &corev1.ContainerStateTerminated{
 					ExitCode: 123,
+					Message:  "digest=sha256:abc",
 				},
 			}},
 			Status: duckv1alpha1.Status{
@@ -783,6 +1251,7 @@ func TestBuildStatusFromPod(t *testing.T) {
 					Name:              "pod",
 					Namespace:         system.Namespace(),
 					CreationTimestamp: now,
+					Annotations:       c.annotations,
 				},
 				Status: c.podStatus,
 			}
@@ -802,3 +1271,585 @@ func TestBuildStatusFromPod(t *testing.T) {
 		})
 	}
 }
+
+func TestMakePodStepTimeouts(t *testing.T) {
+	cs := fakek8s.NewSimpleClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	b := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+		Spec: v1alpha1.BuildSpec{
+			Source: &v1alpha1.SourceSpec{
+				Custom: &corev1.Container{Image: "source"},
+			},
+			Steps: []corev1.Container{{
+				Name:  "fast",
+				Image: "image",
+			}, {
+				Name:  "slow",
+				Image: "image",
+			}},
+			StepTimeouts: []metav1.Duration{{Duration: time.Minute}},
+			Results:      []v1alpha1.ResultSpec{{Name: "digest"}, {Name: "url"}},
+		},
+		Status: v1alpha1.BuildStatus{
+			Cluster: &v1alpha1.ClusterSpec{
+				PodName: "build-name-pod-616161",
+			},
+		},
+	}
+	got, err := MakePod(b, cs)
+	if err != nil {
+		t.Fatalf("MakePod: %v", err)
+	}
+	if d := cmp.Diff(`{"build-step-fast":"1m0s"}`, got.Annotations[stepTimeoutsAnnotationKey]); d != "" {
+		t.Errorf("Diff step timeouts annotation:\n%s", d)
+	}
+	if d := cmp.Diff("digest,url", got.Annotations[resultsAnnotationKey]); d != "" {
+		t.Errorf("Diff results annotation:\n%s", d)
+	}
+}
+
+func TestBuildStatusFromPodStepTimeout(t *testing.T) {
+	started := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   system.Namespace(),
+			Annotations: map[string]string{stepTimeoutsAnnotationKey: `{"build-step-slow":"1m0s","build-step-next":"1h0m0s"}`},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				// creds-init status; ignored
+			}, {
+				Name: "build-step-slow",
+				State: corev1.ContainerState{
+					Running: &corev1.ContainerStateRunning{StartedAt: started},
+				},
+			}, {
+				Name: "build-step-next",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{},
+				},
+			}},
+		},
+	}
+
+	got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	if d := cmp.Diff(&duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "StepTimeout",
+		Message: `build step "build-step-slow" failed to finish within "1m0s"`,
+	}, got.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+		t.Errorf("Diff condition:\n%s", d)
+	}
+	if got.CompletionTime == nil {
+		t.Errorf("CompletionTime not set for timed out step")
+	}
+
+	// Before the step runs over, the build is still running and the
+	// reconciler is told when to check back.
+	p.Status.InitContainerStatuses[1].State.Running.StartedAt = metav1.Now()
+	got = BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	if cond := got.GetCondition(v1alpha1.BuildSucceeded); cond.Status != corev1.ConditionUnknown {
+		t.Errorf("Condition = %v, want Unknown", cond)
+	}
+	if d, ok := NextStepTimeout(p, time.Now()); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("NextStepTimeout() = %v, %v; want under a minute", d, ok)
+	}
+}
+
+func TestBuildStatusFromPodEntrypointStepTimeout(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration) metav1.Time { return metav1.NewTime(now.Add(-ago)) }
+	running := func(started metav1.Time) corev1.ContainerState {
+		return corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: started}}
+	}
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: system.Namespace(),
+			Annotations: map[string]string{
+				stepRunnerAnnotationKey:   EntrypointStepRunner,
+				stepTimeoutsAnnotationKey: `{"build-step-build":"1h0m0s","build-step-test":"1m0s"}`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "build-step-build"},
+				{Name: "build-step-test"},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			// Both steps' containers started with the pod.
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "build-step-build",
+				State: running(at(10 * time.Minute)),
+			}, {
+				Name:  "build-step-test",
+				State: running(at(10 * time.Minute)),
+			}},
+		},
+	}
+
+	// The second step waiting for the first doesn't count against its
+	// timeout.
+	if step, _ := TimedOutStep(p, now); step != "" {
+		t.Errorf("TimedOutStep() = %q while the first step runs, want none", step)
+	}
+	if d, ok := NextStepTimeout(p, now); !ok || d < 49*time.Minute {
+		t.Errorf("NextStepTimeout() = %v, %v; want the first step's", d, ok)
+	}
+
+	// The second step's command starts once the first step finishes.
+	p.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+		StartedAt:  at(10 * time.Minute),
+		FinishedAt: at(30 * time.Second),
+	}}
+	if step, _ := TimedOutStep(p, now); step != "" {
+		t.Errorf("TimedOutStep() = %q 30s into the second step, want none", step)
+	}
+	if d, ok := NextStepTimeout(p, now); !ok || d > 30*time.Second {
+		t.Errorf("NextStepTimeout() = %v, %v; want under 30s", d, ok)
+	}
+	p.Status.ContainerStatuses[0].State.Terminated.FinishedAt = at(2 * time.Minute)
+	got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	if cond := got.GetCondition(v1alpha1.BuildSucceeded); cond == nil || cond.Reason != "StepTimeout" {
+		t.Errorf("Condition = %v, want StepTimeout", cond)
+	}
+}
+
+func TestMakePodWithSidecars(t *testing.T) {
+	cs := fakek8s.NewSimpleClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	b := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+		Spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{
+				Name:    "build",
+				Image:   "docker",
+				Command: []string{"docker", "build"},
+				Args:    []string{"."},
+			}, {
+				Name:    "test",
+				Image:   "image",
+				Command: []string{"make"},
+			}, {
+				Name:    "cleanup",
+				Image:   "image",
+				Command: []string{"rm", "-rf"},
+				Args:    []string{"out"},
+			}},
+			StepPolicies: []v1alpha1.StepPolicy{{
+				Step:      "cleanup",
+				RunPolicy: v1alpha1.RunAlways,
+			}},
+			Sidecars: []corev1.Container{{
+				Name:  "dind",
+				Image: "docker:dind",
+			}},
+		},
+		Status: v1alpha1.BuildStatus{
+			Cluster: &v1alpha1.ClusterSpec{
+				PodName: "build-name-pod-616161",
+			},
+		},
+	}
+	got, err := MakePod(b, cs)
+	if err != nil {
+		t.Fatalf("MakePod: %v", err)
+	}
+	if got.Annotations[stepRunnerAnnotationKey] != EntrypointStepRunner {
+		t.Errorf("Annotations = %v, want step runner %q", got.Annotations, EntrypointStepRunner)
+	}
+
+	placeTools := got.Spec.InitContainers[len(got.Spec.InitContainers)-1]
+	if d := cmp.Diff(corev1.Container{
+		Name:         "build-step-place-tools",
+		Image:        *entrypointImage,
+		Args:         []string{"-copy_to", "/builder/tools/entrypoint"},
+		VolumeMounts: []corev1.VolumeMount{toolsVolumeMount},
+	}, placeTools); d != "" {
+		t.Errorf("Diff place-tools container:\n%s", d)
+	}
+
+	type container struct {
+		Name    string
+		Image   string
+		Command []string
+		Args    []string
+	}
+	var containers []container
+	for _, c := range got.Spec.Containers {
+		containers = append(containers, container{c.Name, c.Image, c.Command, c.Args})
+	}
+	if d := cmp.Diff([]container{{
+		Name:    "build-step-build",
+		Image:   "docker",
+		Command: []string{"/builder/tools/entrypoint"},
+		Args:    []string{"-step_name", "build-step-build", "-post_file", "/builder/tools/0", "-entrypoint", "docker", "--", "build", "."},
+	}, {
+		Name:    "build-step-test",
+		Image:   "image",
+		Command: []string{"/builder/tools/entrypoint"},
+		Args:    []string{"-step_name", "build-step-test", "-wait_file", "/builder/tools/0", "-post_file", "/builder/tools/1", "-entrypoint", "make", "--"},
+	}, {
+		Name:    "build-step-cleanup",
+		Image:   "image",
+		Command: []string{"/builder/tools/entrypoint"},
+		Args:    []string{"-step_name", "build-step-cleanup", "-run_policy", "Always", "-wait_file", "/builder/tools/1", "-entrypoint", "rm", "--", "-rf", "out"},
+	}, {
+		Name:  "sidecar-dind",
+		Image: "docker:dind",
+	}}, containers); d != "" {
+		t.Errorf("Diff containers:\n%s", d)
+	}
+
+	// Custom sources run as init containers with their images'
+	// entrypoints, rather than as steps.
+	b.Spec.Source = &v1alpha1.SourceSpec{Custom: &corev1.Container{Image: "source"}}
+	got, err = MakePod(b, cs)
+	if err != nil {
+		t.Fatalf("MakePod() with a custom source: %v", err)
+	}
+	var initNames, names []string
+	for _, c := range got.Spec.InitContainers {
+		initNames = append(initNames, c.Name)
+	}
+	for _, c := range got.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	if d := cmp.Diff([]string{"build-step-credential-initializer", "build-step-custom-source", "build-step-place-tools"}, initNames); d != "" {
+		t.Errorf("Diff init containers:\n%s", d)
+	}
+	if d := cmp.Diff([]string{"build-step-build", "build-step-test", "build-step-cleanup", "sidecar-dind"}, names); d != "" {
+		t.Errorf("Diff containers:\n%s", d)
+	}
+	if cmd := got.Spec.InitContainers[1].Command; len(cmd) != 0 {
+		t.Errorf("Custom source command = %v, want the image's entrypoint", cmd)
+	}
+	b.Spec.Source = nil
+
+	b.Spec.StepPolicies[0].Step = "missing"
+	if _, err := MakePod(b, cs); err == nil {
+		t.Errorf("MakePod() with a policy for a missing step succeeded, want error")
+	}
+
+	b.Spec.StepPolicies = nil
+	b.Spec.Steps[1].Command = nil
+	if _, err := MakePod(b, cs); err == nil {
+		t.Errorf("MakePod() with a step without command succeeded, want error")
+	}
+}
+
+func TestMakePodArtifactsAlways(t *testing.T) {
+	cs := fakek8s.NewSimpleClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	b := &v1alpha1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+		Spec: v1alpha1.BuildSpec{
+			Steps: []corev1.Container{{
+				Name:    "test",
+				Image:   "image",
+				Command: []string{"make"},
+				Args:    []string{"test"},
+			}},
+			Artifacts: &v1alpha1.ArtifactsSpec{
+				Paths: []string{"reports"},
+				Destination: v1alpha1.ArtifactDestination{
+					PersistentVolumeClaim: &v1alpha1.PVCArtifactDestination{
+						ClaimName: "test-reports",
+						Path:      "build-name",
+					},
+				},
+				Always: true,
+			},
+		},
+		Status: v1alpha1.BuildStatus{
+			Cluster: &v1alpha1.ClusterSpec{
+				PodName: "build-name-pod-616161",
+			},
+		},
+	}
+	got, err := MakePod(b, cs)
+	if err != nil {
+		t.Fatalf("MakePod: %v", err)
+	}
+	if got.Annotations[stepRunnerAnnotationKey] != EntrypointStepRunner {
+		t.Errorf("Annotations = %v, want step runner %q", got.Annotations, EntrypointStepRunner)
+	}
+
+	if len(got.Spec.Containers) != 2 {
+		t.Fatalf("Containers = %v, want the step and the uploader", got.Spec.Containers)
+	}
+	uploader := got.Spec.Containers[1]
+	if d := cmp.Diff(corev1.Container{
+		Name:    "build-step-artifact-uploader",
+		Image:   *artifactUploaderImage,
+		Command: []string{"/builder/tools/entrypoint"},
+		Args: []string{"-step_name", "build-step-artifact-uploader", "-run_policy", "Always", "-wait_file", "/builder/tools/0",
+			"-entrypoint", "/ko-app/artifact-uploader", "--",
+			"-path", "reports", "-dest_dir", "/var/build-artifacts", "-claim_name", "test-reports", "-claim_path", "build-name"},
+		Env: implicitEnvVars,
+		VolumeMounts: append(implicitVolumeMounts, corev1.VolumeMount{
+			Name:      "build-artifacts",
+			MountPath: "/var/build-artifacts",
+			SubPath:   "build-name",
+		}, toolsVolumeMount),
+		WorkingDir: workspaceDir,
+	}, uploader); d != "" {
+		t.Errorf("Diff uploader container:\n%s", d)
+	}
+
+	var claims []string
+	for _, v := range got.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims = append(claims, v.Name+"="+v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	if d := cmp.Diff([]string{"build-artifacts=test-reports"}, claims); d != "" {
+		t.Errorf("Diff claims:\n%s", d)
+	}
+	// The build's own step policies are unchanged.
+	if len(b.Spec.StepPolicies) != 0 {
+		t.Errorf("StepPolicies = %v, want none", b.Spec.StepPolicies)
+	}
+}
+
+func TestBuildStatusFromPodArtifacts(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   system.Namespace(),
+			Annotations: map[string]string{resultsAnnotationKey: "digest"},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "build-step-credential-initializer",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+			}, {
+				Name:  "build-step-build",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "digest=sha256:1234\n"}},
+			}, {
+				Name: "build-step-artifact-uploader",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"artifacts":[` +
+						`{"path":"bin/app","uri":"s3://builds/bin/app","sha256":"` + sum + `"},` +
+						`{"path":"bin/a=b","uri":"s3://builds/bin/a=b","sha256":"` + sum + `"}` +
+						`],"omitted":3}`,
+				}},
+			}},
+		},
+	}
+
+	got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	if d := cmp.Diff([]v1alpha1.ArtifactStatus{
+		{Path: "bin/app", URI: "s3://builds/bin/app", SHA256: sum},
+		{Path: "bin/a=b", URI: "s3://builds/bin/a=b", SHA256: sum},
+	}, got.Artifacts); d != "" {
+		t.Errorf("Diff artifacts:\n%s", d)
+	}
+	if got.ArtifactsOmitted != 3 {
+		t.Errorf("ArtifactsOmitted = %d, want 3", got.ArtifactsOmitted)
+	}
+	if d := cmp.Diff([]v1alpha1.BuildResult{{Name: "digest", Value: "sha256:1234"}}, got.Results); d != "" {
+		t.Errorf("Diff results:\n%s", d)
+	}
+}
+
+func TestBuildStatusFromPodWithSidecars(t *testing.T) {
+	terminated := func(code int32) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code}}
+	}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	for _, c := range []struct {
+		desc     string
+		statuses []corev1.ContainerStatus
+		want     duckv1alpha1.Condition
+	}{{
+		desc: "running",
+		statuses: []corev1.ContainerStatus{
+			{Name: "sidecar-dind", State: running},
+			{Name: "build-step-test", State: running},
+			{Name: "build-step-build", State: terminated(0)},
+		},
+		want: duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionUnknown,
+			Reason: "Building",
+		},
+	}, {
+		desc: "steps succeeded",
+		statuses: []corev1.ContainerStatus{
+			{Name: "sidecar-dind", State: running},
+			{Name: "build-step-test", State: terminated(0)},
+			{Name: "build-step-build", State: terminated(0)},
+		},
+		want: duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		desc: "step failed",
+		statuses: []corev1.ContainerStatus{
+			{Name: "sidecar-dind", State: running},
+			{Name: "build-step-test", State: terminated(1)},
+			{Name: "build-step-build", State: terminated(2)},
+		},
+		want: duckv1alpha1.Condition{
+			Type:    v1alpha1.BuildSucceeded,
+			Status:  corev1.ConditionFalse,
+			Message: `build step "build-step-build" exited with code 2 (image: ""); for logs run: kubectl -n knative-testing logs pod -c build-step-build`,
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			p := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pod",
+					Namespace:   system.Namespace(),
+					Annotations: map[string]string{stepRunnerAnnotationKey: EntrypointStepRunner},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "build-step-build"},
+						{Name: "build-step-test"},
+						{Name: "sidecar-dind"},
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "build-step-credential-initializer", State: terminated(0)},
+						{Name: "build-step-place-tools", State: terminated(0)},
+					},
+					ContainerStatuses: c.statuses,
+				},
+			}
+			got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+			if d := cmp.Diff(&c.want, got.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+				t.Errorf("Diff condition:\n%s", d)
+			}
+			// Step states are reported in the order of the steps.
+			if d := cmp.Diff([]corev1.ContainerState{c.statuses[2].State, c.statuses[1].State}, got.StepStates); d != "" {
+				t.Errorf("Diff step states:\n%s", d)
+			}
+
+			stopped := StopSidecars(p)
+			if stopped == nil || stopped.Spec.Containers[2].Image != *nopImage {
+				t.Errorf("StopSidecars() = %v, want sidecar replaced by %q", stopped, *nopImage)
+			}
+		})
+	}
+}
+
+func TestBuildStatusFromPodSkippedStep(t *testing.T) {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   system.Namespace(),
+			Annotations: map[string]string{stepRunnerAnnotationKey: EntrypointStepRunner},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "build-step-build"},
+				{Name: "build-step-push"},
+				{Name: "build-step-upload-logs"},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "build-step-build",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+			}, {
+				Name:  "build-step-push",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: entrypoint.SkippedMessage}},
+			}, {
+				Name:  "build-step-upload-logs",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+			}},
+		},
+	}
+
+	got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	if d := cmp.Diff([]string{"build-step-build", "build-step-upload-logs"}, got.StepsCompleted); d != "" {
+		t.Errorf("Diff steps completed:\n%s", d)
+	}
+	if d := cmp.Diff([]string{"build-step-push"}, got.StepsSkipped); d != "" {
+		t.Errorf("Diff steps skipped:\n%s", d)
+	}
+	if d := cmp.Diff(corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+		Reason:  "Skipped",
+		Message: entrypoint.SkippedMessage,
+	}}, got.StepStates[1]); d != "" {
+		t.Errorf("Diff skipped step state:\n%s", d)
+	}
+	if cond := got.GetCondition(v1alpha1.BuildSucceeded); cond == nil || cond.Status != corev1.ConditionFalse {
+		t.Errorf("Condition = %v, want failed", cond)
+	}
+}
+
+func TestBuildStatusFromPodUnverifiedRevision(t *testing.T) {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: system.Namespace(),
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "build-step-credential-initializer",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+			}, {
+				Name: "build-step-git-source-app",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  (&gitverify.UnverifiedError{Object: "commit 8f2e0c1", Detail: "no good signature"}).Error(),
+				}},
+			}},
+		},
+	}
+	got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	want := &duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "UnverifiedRevision",
+		Message: `source fetched by "build-step-git-source-app" failed signature verification: commit 8f2e0c1 is not signed by a trusted key: no good signature`,
+	}
+	if d := cmp.Diff(want, got.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+		t.Errorf("Diff condition:\n%s", d)
+	}
+}
+
+func TestBuildStatusFromPodChecksumMismatch(t *testing.T) {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: system.Namespace(),
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "build-step-credential-initializer",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+			}, {
+				Name: "build-step-http-source-src",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  (&httpfetch.ChecksumMismatchError{Want: "abc", Got: "def"}).Error(),
+				}},
+			}},
+		},
+	}
+	got := BuildStatusFromPod(p, v1alpha1.BuildSpec{})
+	want := &duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ChecksumMismatch",
+		Message: `source fetched by "build-step-http-source-src" did not match its checksum: expected sha256 abc, got def`,
+	}
+	if d := cmp.Diff(want, got.GetCondition(v1alpha1.BuildSucceeded), ignoreVolatileTime); d != "" {
+		t.Errorf("Diff condition:\n%s", d)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// shouldRetry returns true if the attempt described by status failed and the
+// build has retries left. Cancelled and timed out builds, including those
+// with a step that timed out, are not retried.
+func shouldRetry(build *v1alpha1.Build, status v1alpha1.BuildStatus) bool {
+	if len(build.Status.RetriesStatus) >= build.Spec.Retries || isCancelled(build.Spec) {
+		return false
+	}
+	cond := status.GetCondition(v1alpha1.BuildSucceeded)
+	if cond == nil || cond.Status != corev1.ConditionFalse {
+		return false
+	}
+	switch cond.Reason {
+	case "BuildCancelled", "BuildTimeout", "StepTimeout":
+		return false
+	}
+	return true
+}
+
+// retryBuild records the failed attempt described by status, and resets the
+// build's status so that its builder starts it again once the backoff for
+// this retry has passed.
+func (c *Reconciler) retryBuild(build *v1alpha1.Build, status v1alpha1.BuildStatus) error {
+	attempt := v1alpha1.AttemptStatus{
+		StartTime:      status.StartTime,
+		CompletionTime: attemptCompletion(status),
+		StepStates:     status.StepStates,
+	}
+	if status.Cluster != nil {
+		attempt.PodName = status.Cluster.PodName
+	}
+	if status.Google != nil {
+		attempt.Operation = status.Google.Operation
+	}
+	if cond := status.GetCondition(v1alpha1.BuildSucceeded); cond != nil {
+		attempt.Message = cond.Message
+	}
+
+	retries := append(build.Status.RetriesStatus, attempt)
+	delay := retryBackoff(build.Spec, len(retries))
+	c.Logger.Infof("Build %q failed attempt %d of %d, retrying in %s", build.Name, len(retries), build.Spec.Retries+1, delay)
+
+	statusLock(build)
+	build.Status = v1alpha1.BuildStatus{
+		Builder:       status.Builder,
+		StartTime:     build.Status.StartTime,
+		RetriesStatus: retries,
+	}
+	build.Status.SetCondition(&duckv1alpha1.Condition{
+		Type:    v1alpha1.BuildSucceeded,
+		Status:  corev1.ConditionUnknown,
+		Reason:  "Retrying",
+		Message: fmt.Sprintf("attempt %d failed: %s", len(retries), attempt.Message),
+	})
+	statusUnlock(build)
+
+	if c.enqueueAfter != nil {
+		c.enqueueAfter(build, delay)
+	}
+	if err := c.updateStatus(build); err != nil {
+		return err
+	}
+	c.recorder.Eventf(build, corev1.EventTypeWarning, "Retrying", "Attempt %d failed, retrying in %s: %s", len(retries), delay, attempt.Message)
+	return nil
+}
+
+// attemptCompletion returns when the attempt described by status finished:
+// when its last step terminated, or else when its builder saw it finish.
+func attemptCompletion(status v1alpha1.BuildStatus) *metav1.Time {
+	var last *metav1.Time
+	for _, state := range status.StepStates {
+		if term := state.Terminated; term != nil && !term.FinishedAt.IsZero() {
+			if last == nil || last.Before(&term.FinishedAt) {
+				last = term.FinishedAt.DeepCopy()
+			}
+		}
+	}
+	if last != nil {
+		return last
+	}
+	if status.CompletionTime != nil {
+		return status.CompletionTime
+	}
+	return &metav1.Time{Time: time.Now()}
+}
+
+// retryWait returns how long to wait before the build's next attempt may
+// start.
+func retryWait(build *v1alpha1.Build) time.Duration {
+	retries := build.Status.RetriesStatus
+	if len(retries) == 0 {
+		return 0
+	}
+	last := retries[len(retries)-1].CompletionTime
+	if last == nil {
+		return 0
+	}
+	return time.Until(last.Add(retryBackoff(build.Spec, len(retries))))
+}
+
+// maxRetryBackoff is the longest that doubling the backoff makes the delay
+// before a retry.
+const maxRetryBackoff = time.Hour
+
+// retryBackoff returns the delay before the given retry (counting from 1);
+// the delay doubles with each retry, up to maxRetryBackoff.
+func retryBackoff(spec v1alpha1.BuildSpec, retry int) time.Duration {
+	if spec.RetryBackoff == nil || retry < 1 {
+		return 0
+	}
+	base := spec.RetryBackoff.Duration
+	d := base
+	for i := 1; i < retry && d > 0 && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff && base < maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShouldRetry(t *testing.T) {
+	failed := func(reason string) v1alpha1.BuildStatus {
+		s := v1alpha1.BuildStatus{}
+		s.SetCondition(&duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionFalse,
+			Reason: reason,
+		})
+		return s
+	}
+	running := v1alpha1.BuildStatus{}
+	running.SetCondition(&duckv1alpha1.Condition{
+		Type:   v1alpha1.BuildSucceeded,
+		Status: corev1.ConditionUnknown,
+	})
+
+	for _, c := range []struct {
+		desc    string
+		spec    v1alpha1.BuildSpec
+		retries int
+		status  v1alpha1.BuildStatus
+		want    bool
+	}{{
+		desc:   "no retries",
+		status: failed(""),
+	}, {
+		desc:   "failed",
+		spec:   v1alpha1.BuildSpec{Retries: 2},
+		status: failed(""),
+		want:   true,
+	}, {
+		desc:    "retries exhausted",
+		spec:    v1alpha1.BuildSpec{Retries: 2},
+		retries: 2,
+		status:  failed(""),
+	}, {
+		desc:   "running",
+		spec:   v1alpha1.BuildSpec{Retries: 2},
+		status: running,
+	}, {
+		desc:   "timed out",
+		spec:   v1alpha1.BuildSpec{Retries: 2},
+		status: failed("BuildTimeout"),
+	}, {
+		desc:   "cancelled",
+		spec:   v1alpha1.BuildSpec{Retries: 2},
+		status: failed("BuildCancelled"),
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			b := &v1alpha1.Build{Spec: c.spec}
+			b.Status.RetriesStatus = make([]v1alpha1.AttemptStatus, c.retries)
+			if got := shouldRetry(b, c.status); got != c.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	spec := v1alpha1.BuildSpec{
+		RetryBackoff: &metav1.Duration{Duration: 10 * time.Second},
+	}
+	for retry, want := range map[int]time.Duration{
+		1:    10 * time.Second,
+		2:    20 * time.Second,
+		3:    40 * time.Second,
+		10:   time.Hour,
+		1000: time.Hour,
+	} {
+		if got := retryBackoff(spec, retry); got != want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", retry, got, want)
+		}
+	}
+	if got := retryBackoff(v1alpha1.BuildSpec{}, 1); got != 0 {
+		t.Errorf("retryBackoff() without backoff = %v, want 0", got)
+	}
+}
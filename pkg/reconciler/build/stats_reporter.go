@@ -0,0 +1,310 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/pkg/metrics"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	buildCountStat        = stats.Int64("build_count", "Number of builds that finished", stats.UnitDimensionless)
+	buildDurationStat     = stats.Int64("build_duration", "Duration of builds, from their start to their completion", stats.UnitMilliseconds)
+	buildQueueLatencyStat = stats.Int64("build_queue_latency", "Time from a build starting to its first step running", stats.UnitMilliseconds)
+	stepDurationStat      = stats.Int64("step_duration", "Duration of build steps", stats.UnitMilliseconds)
+	activeBuildsStat      = stats.Int64("active_builds", "Number of builds that haven't finished", stats.UnitDimensionless)
+
+	// Bucket boundaries are 10s, 30s, 1m, 2m, 5m, 10m, 20m, 30m and 1h.
+	durationDistribution = view.Distribution(10000, 30000, 60000, 120000, 300000, 600000, 1200000, 1800000, 3600000)
+	// Bucket boundaries are 100ms, 1s, 5s, 10s, 30s, 1m, 5m and 10m.
+	latencyDistribution = view.Distribution(100, 1000, 5000, 10000, 30000, 60000, 300000, 600000)
+
+	namespaceTagKey    = mustNewTagKey("namespace")
+	templateTagKey     = mustNewTagKey("template")
+	templateKindTagKey = mustNewTagKey("template_kind")
+	outcomeTagKey      = mustNewTagKey("outcome")
+	stepTagKey         = mustNewTagKey("step")
+
+	buildTagKeys = []tag.Key{namespaceTagKey, templateTagKey, templateKindTagKey}
+)
+
+// The outcomes by which finished builds are counted.
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeTimeout   = "timeout"
+	outcomeCancelled = "cancelled"
+)
+
+// activeBuildsInterval is how often the number of active builds is reported.
+const activeBuildsInterval = 30 * time.Second
+
+func init() {
+	err := view.Register(
+		&view.View{
+			Description: "Number of builds that finished",
+			Measure:     buildCountStat,
+			Aggregation: view.Count(),
+			TagKeys:     append(buildTagKeys, outcomeTagKey),
+		},
+		&view.View{
+			Description: "Duration of builds, from their start to their completion",
+			Measure:     buildDurationStat,
+			Aggregation: durationDistribution,
+			TagKeys:     append(buildTagKeys, outcomeTagKey),
+		},
+		&view.View{
+			Description: "Time from a build starting to its first step running",
+			Measure:     buildQueueLatencyStat,
+			Aggregation: latencyDistribution,
+			TagKeys:     buildTagKeys,
+		},
+		&view.View{
+			Description: "Duration of build steps",
+			Measure:     stepDurationStat,
+			Aggregation: durationDistribution,
+			TagKeys:     append(buildTagKeys, stepTagKey),
+		},
+		&view.View{
+			Description: "Number of builds that haven't finished",
+			Measure:     activeBuildsStat,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{namespaceTagKey},
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// StatsReporter reports the metrics of builds.
+type StatsReporter interface {
+	// ReportDone reports the outcome and duration of a finished build.
+	ReportDone(build *v1alpha1.Build) error
+
+	// ReportQueueLatency reports how long a build waited for its first
+	// step to run.
+	ReportQueueLatency(build *v1alpha1.Build, latency time.Duration) error
+
+	// ReportStepDuration reports how long one of a build's steps ran.
+	ReportStepDuration(build *v1alpha1.Build, step string, duration time.Duration) error
+
+	// ReportActiveBuilds reports the number of unfinished builds in a
+	// namespace.
+	ReportActiveBuilds(namespace string, count int64) error
+}
+
+type reporter struct{}
+
+// NewStatsReporter creates a reporter that records build metrics with
+// OpenCensus; they are exported as configured by config-observability.
+func NewStatsReporter() StatsReporter {
+	return reporter{}
+}
+
+// ReportDone reports the outcome and duration of a finished build.
+func (reporter) ReportDone(build *v1alpha1.Build) error {
+	cond := build.Status.GetCondition(v1alpha1.BuildSucceeded)
+	if cond == nil || cond.Status == corev1.ConditionUnknown {
+		return nil
+	}
+	ctx, err := buildContext(build, tag.Insert(outcomeTagKey, outcome(cond.Status, cond.Reason)))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, buildCountStat.M(1))
+	if start := build.Status.StartTime; start != nil && !start.IsZero() {
+		end := time.Now()
+		if build.Status.CompletionTime != nil {
+			end = build.Status.CompletionTime.Time
+		}
+		metrics.Record(ctx, buildDurationStat.M(int64(end.Sub(start.Time)/time.Millisecond)))
+	}
+	return nil
+}
+
+// ReportQueueLatency reports how long a build waited for its first step
+// to run.
+func (reporter) ReportQueueLatency(build *v1alpha1.Build, latency time.Duration) error {
+	ctx, err := buildContext(build)
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, buildQueueLatencyStat.M(int64(latency/time.Millisecond)))
+	return nil
+}
+
+// ReportStepDuration reports how long one of a build's steps ran.
+func (reporter) ReportStepDuration(build *v1alpha1.Build, step string, duration time.Duration) error {
+	ctx, err := buildContext(build, tag.Insert(stepTagKey, step))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, stepDurationStat.M(int64(duration/time.Millisecond)))
+	return nil
+}
+
+// ReportActiveBuilds reports the number of unfinished builds in a
+// namespace.
+func (reporter) ReportActiveBuilds(namespace string, count int64) error {
+	ctx, err := tag.New(context.Background(), tag.Insert(namespaceTagKey, namespace))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, activeBuildsStat.M(count))
+	return nil
+}
+
+// buildContext returns a context tagged with the build's namespace and
+// template, if it has one, and the given mutators.
+func buildContext(build *v1alpha1.Build, mutators ...tag.Mutator) (context.Context, error) {
+	mutators = append(mutators, tag.Insert(namespaceTagKey, build.Namespace))
+	if t := build.Spec.Template; t != nil {
+		kind := t.Kind
+		if kind == "" {
+			kind = v1alpha1.BuildTemplateKind
+		}
+		mutators = append(mutators,
+			tag.Insert(templateTagKey, t.Name),
+			tag.Insert(templateKindTagKey, string(kind)))
+	}
+	return tag.New(context.Background(), mutators...)
+}
+
+// outcome classifies a finished build by its Succeeded condition.
+func outcome(status corev1.ConditionStatus, reason string) string {
+	switch {
+	case status == corev1.ConditionTrue:
+		return outcomeSucceeded
+	case reason == "BuildTimeout" || reason == "StepTimeout":
+		return outcomeTimeout
+	case reason == "BuildCancelled":
+		return outcomeCancelled
+	default:
+		return outcomeFailed
+	}
+}
+
+// reportTransitions reports the metrics of the changes between the
+// previous and the current status of the build: its first step starting,
+// its steps finishing, and the build itself finishing.
+func (c *Reconciler) reportTransitions(build *v1alpha1.Build, previous v1alpha1.BuildStatus) {
+	// Retried builds keep the start time of their first attempt, so only
+	// the first attempt has a meaningful queue latency.
+	if start := build.Status.StartTime; start != nil && len(build.Status.RetriesStatus) == 0 && !anyStepStarted(previous.StepStates) {
+		for _, state := range build.Status.StepStates {
+			if at, ok := stepStartedAt(state); ok {
+				c.report(c.stats.ReportQueueLatency(build, at.Sub(start.Time)))
+				break
+			}
+		}
+	}
+	for i, state := range build.Status.StepStates {
+		term := state.Terminated
+		if term == nil || term.Reason == "Skipped" || term.StartedAt.IsZero() {
+			continue
+		}
+		if i < len(previous.StepStates) && previous.StepStates[i].Terminated != nil {
+			continue
+		}
+		c.report(c.stats.ReportStepDuration(build, stepTag(build, i), term.FinishedAt.Sub(term.StartedAt.Time)))
+	}
+	if isDone(&build.Status) && !isDone(&previous) {
+		c.report(c.stats.ReportDone(build))
+	}
+}
+
+// reportActiveBuilds reports the number of unfinished builds in each
+// namespace, including those that no longer have any.
+func (c *Reconciler) reportActiveBuilds(reported map[string]bool) {
+	builds, err := c.buildsLister.List(labels.Everything())
+	if err != nil {
+		c.Logger.Errorf("Failed to list builds: %v", err)
+		return
+	}
+	active := map[string]int64{}
+	for _, build := range builds {
+		if !isDone(&build.Status) {
+			active[build.Namespace]++
+		}
+	}
+	for namespace := range reported {
+		if _, ok := active[namespace]; !ok {
+			c.report(c.stats.ReportActiveBuilds(namespace, 0))
+			delete(reported, namespace)
+		}
+	}
+	for namespace, count := range active {
+		c.report(c.stats.ReportActiveBuilds(namespace, count))
+		reported[namespace] = true
+	}
+}
+
+// report logs errors reporting metrics, which shouldn't fail reconciles.
+func (c *Reconciler) report(err error) {
+	if err != nil {
+		c.Logger.Warnf("Failed to report build metrics: %v", err)
+	}
+}
+
+// anyStepStarted returns true if any of the steps has started running.
+func anyStepStarted(states []corev1.ContainerState) bool {
+	for _, state := range states {
+		if _, ok := stepStartedAt(state); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stepStartedAt returns when the step started running, if it has.
+func stepStartedAt(state corev1.ContainerState) (metav1.Time, bool) {
+	switch {
+	case state.Running != nil:
+		return state.Running.StartedAt, !state.Running.StartedAt.IsZero()
+	case state.Terminated != nil && state.Terminated.Reason != "Skipped":
+		return state.Terminated.StartedAt, !state.Terminated.StartedAt.IsZero()
+	}
+	return metav1.Time{}, false
+}
+
+// stepTag names the build's i-th step in metrics; like in Events, steps
+// that come from a template are numbered.
+func stepTag(build *v1alpha1.Build, i int) string {
+	if build.Spec.Template == nil && i < len(build.Spec.Steps) && build.Spec.Steps[i].Name != "" {
+		return build.Spec.Steps[i].Name
+	}
+	return strconv.Itoa(i + 1)
+}
+
+func mustNewTagKey(s string) tag.Key {
+	tagKey, err := tag.NewKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return tagKey
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	logtesting "github.com/knative/pkg/logging/testing"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fakebuildinformer "github.com/knative/build/pkg/client/injection/informers/build/v1alpha1/build/fake"
+	rtesting "github.com/knative/pkg/reconciler/testing"
+)
+
+// fakeStats records the metrics reported to it.
+type fakeStats struct {
+	reports []string
+}
+
+func (f *fakeStats) ReportDone(build *v1alpha1.Build) error {
+	cond := build.Status.GetCondition(v1alpha1.BuildSucceeded)
+	f.reports = append(f.reports, "done "+outcome(cond.Status, cond.Reason))
+	return nil
+}
+
+func (f *fakeStats) ReportQueueLatency(build *v1alpha1.Build, latency time.Duration) error {
+	f.reports = append(f.reports, fmt.Sprintf("queue %s", latency))
+	return nil
+}
+
+func (f *fakeStats) ReportStepDuration(build *v1alpha1.Build, step string, duration time.Duration) error {
+	f.reports = append(f.reports, fmt.Sprintf("step %s %s", step, duration))
+	return nil
+}
+
+func (f *fakeStats) ReportActiveBuilds(namespace string, count int64) error {
+	f.reports = append(f.reports, fmt.Sprintf("active %s %d", namespace, count))
+	return nil
+}
+
+func TestReportDone(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	for _, c := range []struct {
+		namespace string
+		template  *v1alpha1.TemplateInstantiationSpec
+		cond      duckv1alpha1.Condition
+		outcome   string
+		kind      string
+	}{{
+		namespace: "stats-succeeded",
+		template:  &v1alpha1.TemplateInstantiationSpec{Name: "kaniko"},
+		cond:      duckv1alpha1.Condition{Status: corev1.ConditionTrue},
+		outcome:   outcomeSucceeded,
+		kind:      "BuildTemplate",
+	}, {
+		namespace: "stats-failed",
+		template:  &v1alpha1.TemplateInstantiationSpec{Name: "kaniko", Kind: v1alpha1.ClusterBuildTemplateKind},
+		cond:      duckv1alpha1.Condition{Status: corev1.ConditionFalse},
+		outcome:   outcomeFailed,
+		kind:      "ClusterBuildTemplate",
+	}, {
+		namespace: "stats-timeout",
+		cond:      duckv1alpha1.Condition{Status: corev1.ConditionFalse, Reason: "StepTimeout"},
+		outcome:   outcomeTimeout,
+	}, {
+		namespace: "stats-cancelled",
+		cond:      duckv1alpha1.Condition{Status: corev1.ConditionFalse, Reason: "BuildCancelled"},
+		outcome:   outcomeCancelled,
+	}} {
+		t.Run(c.outcome, func(t *testing.T) {
+			build := newBuild("stats")
+			build.Namespace = c.namespace
+			build.Spec.Template = c.template
+			build.Status.StartTime = &metav1.Time{Time: start}
+			build.Status.CompletionTime = &metav1.Time{Time: start.Add(5 * time.Minute)}
+			c.cond.Type = v1alpha1.BuildSucceeded
+			build.Status.SetCondition(&c.cond)
+
+			if err := NewStatsReporter().ReportDone(build); err != nil {
+				t.Fatalf("ReportDone: %v", err)
+			}
+
+			wantTags := []tag.Tag{
+				{Key: namespaceTagKey, Value: c.namespace},
+				{Key: outcomeTagKey, Value: c.outcome},
+			}
+			if c.template != nil {
+				wantTags = append(wantTags,
+					tag.Tag{Key: templateTagKey, Value: c.template.Name},
+					tag.Tag{Key: templateKindTagKey, Value: c.kind})
+			}
+			count := findRow(t, "build_count", c.namespace)
+			if d := cmp.Diff(wantTags, count.Tags, cmp.Comparer(func(a, b tag.Key) bool { return a.Name() == b.Name() })); d != "" {
+				t.Errorf("Unexpected tags (-want, +got): %s", d)
+			}
+			if got := count.Data.(*view.CountData).Value; got != 1 {
+				t.Errorf("build_count = %d, want 1", got)
+			}
+			duration := findRow(t, "build_duration", c.namespace).Data.(*view.DistributionData)
+			if duration.Count != 1 || duration.Mean != 300000 {
+				t.Errorf("build_duration has count %d and mean %v, want 1 and 300000", duration.Count, duration.Mean)
+			}
+		})
+	}
+}
+
+// findRow returns the row of the view that is tagged with the namespace.
+func findRow(t *testing.T, name, namespace string) *view.Row {
+	t.Helper()
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		t.Fatalf("RetrieveData(%q): %v", name, err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == namespaceTagKey && tag.Value == namespace {
+				return row
+			}
+		}
+	}
+	t.Fatalf("%s has no row for namespace %q: %v", name, namespace, rows)
+	return nil
+}
+
+func TestReportTransitions(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	at := func(d time.Duration) metav1.Time { return metav1.Time{Time: start.Add(d)} }
+	waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: at(10 * time.Second)}}
+	finished := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{StartedAt: at(10 * time.Second), FinishedAt: at(40 * time.Second)}}
+	next := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{StartedAt: at(40 * time.Second), FinishedAt: at(time.Minute), ExitCode: 1}}
+	skipped := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Skipped"}}
+	building := duckv1alpha1.Condition{
+		Type:   v1alpha1.BuildSucceeded,
+		Status: corev1.ConditionUnknown,
+		Reason: "Building",
+	}
+
+	for _, c := range []struct {
+		desc     string
+		previous []corev1.ContainerState
+		current  []corev1.ContainerState
+		retried  bool
+		cond     duckv1alpha1.Condition
+		want     []string
+	}{{
+		desc:     "first step started",
+		previous: []corev1.ContainerState{waiting, waiting},
+		current:  []corev1.ContainerState{running, waiting},
+		cond:     building,
+		want:     []string{"queue 10s"},
+	}, {
+		desc:     "retried",
+		previous: []corev1.ContainerState{waiting, waiting},
+		current:  []corev1.ContainerState{running, waiting},
+		retried:  true,
+		cond:     building,
+	}, {
+		desc:     "step finished",
+		previous: []corev1.ContainerState{running, waiting},
+		current:  []corev1.ContainerState{finished, waiting},
+		cond:     building,
+		want:     []string{"step compile 30s"},
+	}, {
+		desc:     "build failed",
+		previous: []corev1.ContainerState{finished, waiting, waiting},
+		current:  []corev1.ContainerState{finished, next, skipped},
+		cond: duckv1alpha1.Condition{
+			Type:   v1alpha1.BuildSucceeded,
+			Status: corev1.ConditionFalse,
+		},
+		want: []string{"step 2 20s", "done failed"},
+	}, {
+		desc:    "ran between reconciles",
+		current: []corev1.ContainerState{finished, next},
+		cond:    building,
+		want:    []string{"queue 10s", "step compile 30s", "step 2 20s"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			stats := &fakeStats{}
+			r := &Reconciler{stats: stats, Logger: logtesting.TestLogger(t)}
+			build := newBuild("transitions")
+			build.Spec.Steps = []corev1.Container{{Name: "compile"}}
+			build.Status.StartTime = &metav1.Time{Time: start}
+			build.Status.StepStates = c.current
+			if c.retried {
+				build.Status.RetriesStatus = []v1alpha1.AttemptStatus{{}}
+			}
+			build.Status.SetCondition(&c.cond)
+			previous := v1alpha1.BuildStatus{StepStates: c.previous}
+			previous.SetCondition(&building)
+
+			r.reportTransitions(build, previous)
+			if d := cmp.Diff(c.want, stats.reports); d != "" {
+				t.Errorf("Unexpected reports (-want, +got): %s", d)
+			}
+		})
+	}
+}
+
+func TestReportActiveBuilds(t *testing.T) {
+	ctx, _ := rtesting.SetupFakeContext(t)
+	stats := &fakeStats{}
+	r := &Reconciler{
+		buildsLister: fakebuildinformer.Get(ctx).Lister(),
+		stats:        stats,
+		Logger:       logtesting.TestLogger(t),
+	}
+	indexer := fakebuildinformer.Get(ctx).Informer().GetIndexer()
+	done := newBuild("done")
+	done.Status.SetCondition(&duckv1alpha1.Condition{Type: v1alpha1.BuildSucceeded, Status: corev1.ConditionTrue})
+	running := newBuild("running")
+	other := newBuild("other")
+	other.Namespace = "other"
+	for _, b := range []*v1alpha1.Build{done, running, other} {
+		indexer.Add(b)
+	}
+
+	reported := map[string]bool{}
+	r.reportActiveBuilds(reported)
+	indexer.Delete(other)
+	r.reportActiveBuilds(reported)
+
+	want := []string{
+		// The order of namespaces is random the first time.
+		"active default 1", "active other 1",
+		"active other 0", "active default 1",
+	}
+	got := stats.reports
+	if len(got) == len(want) && got[0] > got[1] {
+		got[0], got[1] = got[1], got[0]
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected reports (-want, +got): %s", d)
+	}
+}
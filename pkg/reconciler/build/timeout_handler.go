@@ -25,18 +25,26 @@ type TimeoutSet struct {
 	logger         *zap.SugaredLogger
 	kubeclientset  kubernetes.Interface
 	buildclientset clientset.Interface
-	stopCh         <-chan struct{}
+	// stop terminates the builds that timed out with their builders.
+	stop func(*v1alpha1.Build) error
+	// finished is called with the builds that timed out.
+	finished func(*v1alpha1.Build)
+	stopCh   <-chan struct{}
 }
 
 // NewTimeoutHandler returns TimeoutSet filled structure
 func NewTimeoutHandler(logger *zap.SugaredLogger,
 	kubeclientset kubernetes.Interface,
 	buildclientset clientset.Interface,
+	stop func(*v1alpha1.Build) error,
+	finished func(*v1alpha1.Build),
 	stopCh <-chan struct{}) *TimeoutSet {
 	return &TimeoutSet{
 		logger:         logger,
 		kubeclientset:  kubeclientset,
 		buildclientset: buildclientset,
+		stop:           stop,
+		finished:       finished,
 		stopCh:         stopCh,
 	}
 }
@@ -108,30 +116,34 @@ func (t *TimeoutSet) release(build *v1alpha1.Build) {
 func (t *TimeoutSet) stopBuild(build *v1alpha1.Build) error {
 	statusLock(build)
 	defer statusUnlock(build)
-	if build.Status.Cluster != nil {
-		if err := t.kubeclientset.CoreV1().Pods(build.Namespace).Delete(build.Status.Cluster.PodName, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
-			return err
-		}
+	// Fetch the latest status, since a retried build may have moved on to
+	// another pod since this goroutine started.
+	newb, err := t.buildclientset.BuildV1alpha1().Builds(build.Namespace).Get(build.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if isDone(&newb.Status) {
+		return nil
+	}
+	if err := t.stop(newb); err != nil && !errors.IsNotFound(err) {
+		return err
 	}
 
 	timeout := defaultTimeout
 	if build.Spec.Timeout != nil {
 		timeout = build.Spec.Timeout.Duration
 	}
-	build.Status.SetCondition(&duckv1alpha1.Condition{
+	newb.Status.SetCondition(&duckv1alpha1.Condition{
 		Type:    v1alpha1.BuildSucceeded,
 		Status:  corev1.ConditionFalse,
 		Reason:  "BuildTimeout",
 		Message: fmt.Sprintf("Build %q failed to finish within %q", build.Name, timeout.String()),
 	})
-	build.Status.CompletionTime = &metav1.Time{time.Now()}
+	newb.Status.CompletionTime = &metav1.Time{time.Now()}
 
-	newb, err := t.buildclientset.BuildV1alpha1().Builds(build.Namespace).Get(build.Name, metav1.GetOptions{})
-	if err != nil {
+	if _, err := t.buildclientset.BuildV1alpha1().Builds(build.Namespace).UpdateStatus(newb); err != nil {
 		return err
 	}
-
-	newb.Status = build.Status
-	_, err = t.buildclientset.BuildV1alpha1().Builds(build.Namespace).UpdateStatus(newb)
-	return err
+	t.finished(newb)
+	return nil
 }
@@ -23,7 +23,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/knative/build/pkg/apis/build/v1alpha1"
-	"github.com/knative/build/pkg/reconciler/build/resources"
+	"github.com/knative/build/pkg/credentials/gitcreds"
+	buildtemplateresources "github.com/knative/build/pkg/reconciler/buildtemplate/resources"
 )
 
 func (ac *Reconciler) validateBuild(b *v1alpha1.Build) error {
@@ -51,7 +52,17 @@ func (ac *Reconciler) validateBuild(b *v1alpha1.Build) error {
 			return validationError("Incorrect Template Kind", "the template kind can only be \"BuildTemplate\" or \"ClusterBuildTemplate\" with \"BuildTemplate\" used as the default if nothing is specified.")
 		}
 
-		if err := validateArguments(b.Spec.Template.Arguments, tmpl); err != nil {
+		// Validate the template with the templates it extends merged in.
+		tmpl, err = buildtemplateresources.Flatten(tmpl, buildtemplateresources.ClientTemplateGetter{Client: ac.buildclientset})
+		if err != nil {
+			return validationError("InvalidBaseTemplate", "%v", err)
+		}
+
+		args, err := resolveArguments(b, ac.kubeclientset)
+		if err != nil {
+			return err
+		}
+		if err := validateArguments(args, tmpl); err != nil {
 			return err
 		}
 
@@ -65,9 +76,17 @@ func (ac *Reconciler) validateBuild(b *v1alpha1.Build) error {
 		}
 	}
 
-	// Ensure the build can be translated to a Pod.
-	_, err = resources.MakePod(b, ac.kubeclientset)
-	return err
+	// Ensure the build, with its template applied, can be executed by its
+	// builder.
+	builder, err := ac.builderFor(b)
+	if err != nil {
+		return err
+	}
+	applied, err := ApplyTemplate(b, tmpl, ac.kubeclientset)
+	if err != nil {
+		return err
+	}
+	return builder.Validate(applied)
 }
 
 // validateSecrets checks that if the Build specifies a ServiceAccount, that it
@@ -106,6 +125,13 @@ func (ac *Reconciler) validateSecrets(b *v1alpha1.Build) error {
 				return validationError("BadSecretAnnotation", `Secret %q has incorrect annotation %q / %q, value should be "https://index.docker.io/v1/"`, se.Name, k, v)
 			}
 		}
+
+		// SSH Secrets must satisfy the host key policy that applies to
+		// them, so that builds don't fail, or trust unknown hosts, when
+		// their credentials are initialized.
+		if err := gitcreds.ValidateSSHSecret(sec); err != nil {
+			return validationError("BadSecretHostKeyPolicy", "Secret %q: %v", se.Name, err)
+		}
 	}
 	return nil
 }
@@ -123,13 +149,21 @@ func validateArguments(args []v1alpha1.ArgumentSpec, tmpl v1alpha1.BuildTemplate
 	// defaults must be satisfied by the build's parameters.
 	if tmpl != nil {
 		tmplParams := map[string]string{} // value is the param description.
+		params := map[string]v1alpha1.ParameterSpec{}
 		for _, p := range tmpl.TemplateSpec().Parameters {
-			if p.Default == nil {
+			if p.Default == nil && p.DefaultValues == nil {
 				tmplParams[p.Name] = p.Description
 			}
+			params[p.Name] = p
 		}
-		for _, p := range args {
-			delete(tmplParams, p.Name)
+		for _, a := range args {
+			delete(tmplParams, a.Name)
+			// Arguments must conform to the parameters they satisfy.
+			if p, ok := params[a.Name]; ok {
+				if err := p.ValidateArgument(a); err != nil {
+					return validationError("InvalidArgument", "argument %q: %v", a.Name, err)
+				}
+			}
 		}
 		if len(tmplParams) > 0 {
 			type pair struct{ name, desc string }
@@ -5,7 +5,7 @@ Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
 
-    http://www.apache.org/licenses/LICENSE-2.0
+	http://www.apache.org/licenses/LICENSE-2.0
 
 Unless required by applicable law or agreed to in writing, software
 distributed under the License is distributed on an "AS IS" BASIS,
@@ -29,14 +29,16 @@ import (
 func TestValidateBuild(t *testing.T) {
 	hasDefault := "has-default"
 	empty := ""
+	optional := true
 	for _, c := range []struct {
-		desc    string
-		build   *v1alpha1.Build
-		tmpl    *v1alpha1.BuildTemplate
-		ctmpl   *v1alpha1.ClusterBuildTemplate
-		sa      *corev1.ServiceAccount
-		secrets []*corev1.Secret
-		reason  string // if "", expect success.
+		desc       string
+		build      *v1alpha1.Build
+		tmpl       *v1alpha1.BuildTemplate
+		ctmpl      *v1alpha1.ClusterBuildTemplate
+		sa         *corev1.ServiceAccount
+		secrets    []*corev1.Secret
+		configMaps []*corev1.ConfigMap
+		reason     string // if "", expect success.
 	}{{
 		build: &v1alpha1.Build{
 			Spec: v1alpha1.BuildSpec{
@@ -179,6 +181,412 @@ func TestValidateBuild(t *testing.T) {
 			},
 		},
 		reason: "UnsatisfiedParameter",
+	}, {
+		desc: "Arg conforms to typed parameters",
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name:  "replicas",
+						Value: "3",
+					}, {
+						Name:  "env",
+						Value: "prod",
+					}, {
+						Name:   "tags",
+						Values: []string{"v1", "latest"},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "replicas",
+					Type: v1alpha1.ParameterTypeInt,
+				}, {
+					Name: "env",
+					Enum: []string{"dev", "prod"},
+				}, {
+					Name:    "tags",
+					Type:    v1alpha1.ParameterTypeArray,
+					Pattern: "[a-z0-9.]+",
+				}},
+			},
+		},
+	}, {
+		desc: "Arg is not an int",
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name:  "replicas",
+						Value: "three",
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "replicas",
+					Type: v1alpha1.ParameterTypeInt,
+				}},
+			},
+		},
+		reason: "InvalidArgument",
+	}, {
+		desc: "Arg is not in enum",
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name:  "env",
+						Value: "staging",
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "env",
+					Enum: []string{"dev", "prod"},
+				}},
+			},
+		},
+		reason: "InvalidArgument",
+	}, {
+		desc: "Array arg element doesn't match pattern",
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name:   "tags",
+						Values: []string{"v1", "Latest!"},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name:    "tags",
+					Type:    v1alpha1.ParameterTypeArray,
+					Pattern: "[a-z0-9.]+",
+				}},
+			},
+		},
+		reason: "InvalidArgument",
+	}, {
+		desc: "Single value for array parameter",
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name:  "tags",
+						Value: "v1",
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "tags",
+					Type: v1alpha1.ParameterTypeArray,
+				}},
+			},
+		},
+		reason: "InvalidArgument",
+	}, {
+		desc: "Arg value from ConfigMap",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "foo",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+								Key:                  "replicas",
+							},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "foo",
+					Type: v1alpha1.ParameterTypeInt,
+				}},
+			},
+		},
+		configMaps: []*corev1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings"},
+			Data:       map[string]string{"replicas": "3", "name": "web"},
+		}},
+	}, {
+		desc: "Arg value from ConfigMap doesn't conform",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "foo",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+								Key:                  "name",
+							},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "foo",
+					Type: v1alpha1.ParameterTypeInt,
+				}},
+			},
+		},
+		configMaps: []*corev1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings"},
+			Data:       map[string]string{"replicas": "3", "name": "web"},
+		}},
+		reason: "InvalidArgument",
+	}, {
+		desc: "Array arg value from ConfigMap",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "tags",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+								Key:                  "missing",
+								Optional:             &optional,
+							},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "tags",
+					Type: v1alpha1.ParameterTypeArray,
+				}},
+			},
+		},
+		configMaps: []*corev1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings"},
+			Data:       map[string]string{"replicas": "3", "name": "web"},
+		}},
+		reason: "InvalidArgument",
+	}, {
+		desc: "Arg value from missing ConfigMap",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "foo",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+								Key:                  "replicas",
+							},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "foo",
+					Type: v1alpha1.ParameterTypeInt,
+				}},
+			},
+		},
+		reason: "ConfigMapNotFound",
+	}, {
+		desc: "Arg value from Secret",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "foo",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+								Key:                  "token",
+							},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "foo",
+					Type: v1alpha1.ParameterTypeInt,
+				}},
+			},
+		},
+		secrets: []*corev1.Secret{{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds"},
+			Data:       map[string][]byte{"token": []byte("12345")},
+		}},
+	}, {
+		desc: "Arg value from missing Secret key",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "foo",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+								Key:                  "password",
+							},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "foo",
+					Type: v1alpha1.ParameterTypeInt,
+				}},
+			},
+		},
+		secrets: []*corev1.Secret{{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds"},
+			Data:       map[string][]byte{"token": []byte("12345")},
+		}},
+		reason: "MissingSecretKey",
+	}, {
+		desc: "Arg value from build field",
+		build: &v1alpha1.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build-name"},
+			Spec: v1alpha1.BuildSpec{
+				Template: &v1alpha1.TemplateInstantiationSpec{
+					Name: "template",
+					Arguments: []v1alpha1.ArgumentSpec{{
+						Name: "foo",
+						ValueFrom: &v1alpha1.ArgumentValueSource{
+							FieldRef: &v1alpha1.BuildFieldSelector{FieldPath: "metadata.name"},
+						},
+					}},
+				},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Parameters: []v1alpha1.ParameterSpec{{
+					Name: "foo",
+					Type: v1alpha1.ParameterTypeString,
+				}},
+			},
+		},
 	}, {
 		desc: "Arg doesn't match any parameter",
 		build: &v1alpha1.Build{
@@ -344,6 +752,59 @@ func TestValidateBuild(t *testing.T) {
 			},
 		}},
 		reason: "BadSecretAnnotation",
+	}, {
+		desc: "SSH secret with known_hosts under strict policy",
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Steps: []corev1.Container{{Image: "hello"}},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		sa: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Secrets:    []corev1.ObjectReference{{Name: "ssh"}},
+		},
+		secrets: []*corev1.Secret{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "ssh",
+				Annotations: map[string]string{
+					"build.knative.dev/git-0":               "github.com",
+					"build.knative.dev/ssh-host-key-policy": "strict",
+				},
+			},
+			Type: corev1.SecretTypeSSHAuth,
+			Data: map[string][]byte{"known_hosts": []byte("github.com ssh-rsa AAAA")},
+		}},
+	}, {
+		build: &v1alpha1.Build{
+			Spec: v1alpha1.BuildSpec{
+				Steps: []corev1.Container{{Image: "hello"}},
+			},
+			Status: v1alpha1.BuildStatus{
+				Cluster: &v1alpha1.ClusterSpec{
+					PodName: "foo",
+				},
+			},
+		},
+		sa: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Secrets:    []corev1.ObjectReference{{Name: "ssh"}},
+		},
+		secrets: []*corev1.Secret{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "ssh",
+				Annotations: map[string]string{
+					"build.knative.dev/git-0":               "github.com",
+					"build.knative.dev/ssh-host-key-policy": "strict",
+				},
+			},
+			Type: corev1.SecretTypeSSHAuth,
+		}},
+		reason: "BadSecretHostKeyPolicy",
 	}} {
 		name := c.desc
 		if c.reason != "" {
@@ -380,6 +841,12 @@ func TestValidateBuild(t *testing.T) {
 					t.Fatalf("Failed to create Secret %q: %v", s.Name, err)
 				}
 			}
+			// Create any necessary ConfigMaps.
+			for _, cm := range c.configMaps {
+				if _, err := client.CoreV1().ConfigMaps("").Create(cm); err != nil {
+					t.Fatalf("Failed to create ConfigMap %q: %v", cm.Name, err)
+				}
+			}
 			testLogger := zap.NewNop().Sugar()
 
 			ac := &Reconciler{
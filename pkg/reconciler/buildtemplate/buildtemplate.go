@@ -50,8 +50,9 @@ type Reconciler struct {
 	// cachingclientset is a clientset for creating caching resources.
 	cachingclientset cachingclientset.Interface
 
-	buildTemplatesLister listers.BuildTemplateLister
-	imagesLister         cachinglisters.ImageLister
+	buildTemplatesLister        listers.BuildTemplateLister
+	clusterBuildTemplatesLister listers.ClusterBuildTemplateLister
+	imagesLister                cachinglisters.ImageLister
 
 	// Sugared logger is easier to use but is not as performant as the
 	// raw logger. In performance critical paths, call logger.Desugar()
@@ -99,7 +100,15 @@ func (c *Reconciler) Reconcile(ctx context.Context, key string) error {
 }
 
 func (c *Reconciler) reconcileImageCaches(ctx context.Context, bt *v1alpha1.BuildTemplate) error {
-	ics := resources.MakeImageCaches(bt)
+	// Cache the images of the steps of the templates it extends too.
+	flat, err := resources.Flatten(bt, resources.ListerTemplateGetter{
+		BuildTemplates:        c.buildTemplatesLister,
+		ClusterBuildTemplates: c.clusterBuildTemplatesLister,
+	})
+	if err != nil {
+		return err
+	}
+	ics := resources.MakeImageCaches(flat.(*v1alpha1.BuildTemplate))
 
 	eics, err := c.imagesLister.Images(bt.Namespace).List(kmeta.MakeVersionLabelSelector(bt))
 	if err != nil {
@@ -20,17 +20,22 @@ import (
 	"context"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
 
 	buildclient "github.com/knative/build/pkg/client/injection/client"
 	btinformer "github.com/knative/build/pkg/client/injection/informers/build/v1alpha1/buildtemplate"
+	cbtinformer "github.com/knative/build/pkg/client/injection/informers/build/v1alpha1/clusterbuildtemplate"
 	cachingclient "github.com/knative/caching/pkg/client/injection/client"
 	imageinformer "github.com/knative/caching/pkg/client/injection/informers/caching/v1alpha1/image"
 	"github.com/knative/pkg/injection/clients/kubeclient"
 
 	"github.com/knative/build/pkg/apis/build/v1alpha1"
+	listers "github.com/knative/build/pkg/client/listers/build/v1alpha1"
+	"github.com/knative/build/pkg/reconciler/buildtemplate/resources"
 	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
+	"github.com/knative/pkg/kmeta"
 	"github.com/knative/pkg/logging"
 	"github.com/knative/pkg/logging/logkey"
 )
@@ -48,18 +53,20 @@ func NewController(
 	buildclientset := buildclient.Get(ctx)
 	cachingclientset := cachingclient.Get(ctx)
 	buildTemplateInformer := btinformer.Get(ctx)
+	clusterBuildTemplateInformer := cbtinformer.Get(ctx)
 	imageInformer := imageinformer.Get(ctx)
 
 	// Enrich the logs with controller name
 	logger = logger.Named(controllerAgentName).With(zap.String(logkey.ControllerType, controllerAgentName))
 
 	r := &Reconciler{
-		kubeclientset:        kubeclientset,
-		buildclientset:       buildclientset,
-		cachingclientset:     cachingclientset,
-		buildTemplatesLister: buildTemplateInformer.Lister(),
-		imagesLister:         imageInformer.Lister(),
-		Logger:               logger,
+		kubeclientset:               kubeclientset,
+		buildclientset:              buildclientset,
+		cachingclientset:            cachingclientset,
+		buildTemplatesLister:        buildTemplateInformer.Lister(),
+		clusterBuildTemplatesLister: clusterBuildTemplateInformer.Lister(),
+		imagesLister:                imageInformer.Lister(),
+		Logger:                      logger,
 	}
 	impl := controller.NewImpl(r, logger, "BuildTemplates")
 
@@ -67,6 +74,17 @@ func NewController(
 	// Set up an event handler for when BuildTemplate resources change
 	buildTemplateInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
 
+	// Reconcile the BuildTemplates that extend a template when it changes,
+	// so that the images of its new steps are cached too.
+	getter := resources.ListerTemplateGetter{
+		BuildTemplates:        r.buildTemplatesLister,
+		ClusterBuildTemplates: r.clusterBuildTemplatesLister,
+	}
+	buildTemplateInformer.Informer().AddEventHandler(controller.HandleAll(
+		enqueueExtending(impl, r.buildTemplatesLister, getter, v1alpha1.BuildTemplateKind)))
+	clusterBuildTemplateInformer.Informer().AddEventHandler(controller.HandleAll(
+		enqueueExtending(impl, r.buildTemplatesLister, getter, v1alpha1.ClusterBuildTemplateKind)))
+
 	imageInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: controller.Filter(v1alpha1.SchemeGroupVersion.WithKind("BuildTemplate")),
 		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
@@ -74,3 +92,23 @@ func NewController(
 
 	return impl
 }
+
+// enqueueExtending returns a handler that enqueues the BuildTemplates that
+// extend the changed template of the kind.
+func enqueueExtending(impl *controller.Impl, lister listers.BuildTemplateLister, getter resources.TemplateGetter, kind v1alpha1.TemplateKind) func(interface{}) {
+	return func(obj interface{}) {
+		object, err := kmeta.DeletionHandlingAccessor(obj)
+		if err != nil {
+			return
+		}
+		bts, err := lister.List(labels.Everything())
+		if err != nil {
+			return
+		}
+		for _, bt := range bts {
+			if resources.Extends(bt, kind, object.GetNamespace(), object.GetName(), getter) {
+				impl.Enqueue(bt)
+			}
+		}
+	}
+}
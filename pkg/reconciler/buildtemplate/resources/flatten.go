@@ -0,0 +1,285 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/knative/build/pkg/apis/build/v1alpha1"
+	clientset "github.com/knative/build/pkg/client/clientset/versioned"
+	listers "github.com/knative/build/pkg/client/listers/build/v1alpha1"
+)
+
+// TemplateGetter fetches the base templates that templates extend.
+type TemplateGetter interface {
+	GetBuildTemplate(namespace, name string) (*v1alpha1.BuildTemplate, error)
+	GetClusterBuildTemplate(name string) (*v1alpha1.ClusterBuildTemplate, error)
+}
+
+// ListerTemplateGetter fetches templates from informers' listers.
+type ListerTemplateGetter struct {
+	BuildTemplates        listers.BuildTemplateLister
+	ClusterBuildTemplates listers.ClusterBuildTemplateLister
+}
+
+// GetBuildTemplate implements TemplateGetter.
+func (g ListerTemplateGetter) GetBuildTemplate(namespace, name string) (*v1alpha1.BuildTemplate, error) {
+	return g.BuildTemplates.BuildTemplates(namespace).Get(name)
+}
+
+// GetClusterBuildTemplate implements TemplateGetter.
+func (g ListerTemplateGetter) GetClusterBuildTemplate(name string) (*v1alpha1.ClusterBuildTemplate, error) {
+	return g.ClusterBuildTemplates.Get(name)
+}
+
+// ClientTemplateGetter fetches templates from the API server.
+type ClientTemplateGetter struct {
+	Client clientset.Interface
+}
+
+// GetBuildTemplate implements TemplateGetter.
+func (g ClientTemplateGetter) GetBuildTemplate(namespace, name string) (*v1alpha1.BuildTemplate, error) {
+	return g.Client.BuildV1alpha1().BuildTemplates(namespace).Get(name, metav1.GetOptions{})
+}
+
+// GetClusterBuildTemplate implements TemplateGetter.
+func (g ClientTemplateGetter) GetClusterBuildTemplate(name string) (*v1alpha1.ClusterBuildTemplate, error) {
+	return g.Client.BuildV1alpha1().ClusterBuildTemplates().Get(name, metav1.GetOptions{})
+}
+
+// Flatten returns a copy of the template with the templates it extends,
+// and those they extend in turn, merged into its spec, which then has no
+// bases. A template extended more than once, such as a base shared by two
+// bases, is merged once, where it is first reached. It returns an error if a
+// template extends itself, directly or indirectly, or if the merged templates
+// declare steps with the same name.
+func Flatten(tmpl v1alpha1.BuildTemplateInterface, getter TemplateGetter) (v1alpha1.BuildTemplateInterface, error) {
+	tmpl = tmpl.Copy()
+	var err error
+	visited := sets.NewString()
+	switch t := tmpl.(type) {
+	case *v1alpha1.BuildTemplate:
+		t.Spec, err = flatten(t.Spec, t.Namespace, []string{templateKey(v1alpha1.BuildTemplateKind, t.Namespace, t.Name)}, visited, getter)
+	case *v1alpha1.ClusterBuildTemplate:
+		t.Spec, err = flatten(t.Spec, "", []string{templateKey(v1alpha1.ClusterBuildTemplateKind, "", t.Name)}, visited, getter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// templateKey identifies a template when detecting cycles.
+func templateKey(kind v1alpha1.TemplateKind, namespace, name string) string {
+	if namespace == "" {
+		return fmt.Sprintf("%s %q", kind, name)
+	}
+	return fmt.Sprintf("%s %q", kind, namespace+"/"+name)
+}
+
+// flatten merges the bases of spec, a template in namespace reached through
+// the templates in path, into it, skipping those in visited, which have
+// already been merged elsewhere.
+func flatten(spec v1alpha1.BuildTemplateSpec, namespace string, path []string, visited sets.String, getter TemplateGetter) (v1alpha1.BuildTemplateSpec, error) {
+	if len(spec.Bases) == 0 {
+		return spec, nil
+	}
+
+	var before, after []v1alpha1.BuildTemplateSpec
+	for _, b := range spec.Bases {
+		var base v1alpha1.BuildTemplateSpec
+		var baseNamespace, key string
+		switch b.Kind {
+		case v1alpha1.ClusterBuildTemplateKind:
+			key = templateKey(b.Kind, "", b.Name)
+			if err := checkCycle(path, key); err != nil {
+				return spec, err
+			}
+			if visited.Has(key) {
+				continue
+			}
+			cbt, err := getter.GetClusterBuildTemplate(b.Name)
+			if err != nil {
+				return spec, err
+			}
+			base = cbt.Spec
+		default:
+			if namespace == "" {
+				return spec, fmt.Errorf("%s extends BuildTemplate %q, but may only extend ClusterBuildTemplates", path[len(path)-1], b.Name)
+			}
+			baseNamespace = namespace
+			key = templateKey(v1alpha1.BuildTemplateKind, namespace, b.Name)
+			if err := checkCycle(path, key); err != nil {
+				return spec, err
+			}
+			if visited.Has(key) {
+				continue
+			}
+			bt, err := getter.GetBuildTemplate(namespace, b.Name)
+			if err != nil {
+				return spec, err
+			}
+			base = bt.Spec
+		}
+
+		visited.Insert(key)
+		base, err := flatten(*base.DeepCopy(), baseNamespace, append(path[:len(path):len(path)], key), visited, getter)
+		if err != nil {
+			return spec, err
+		}
+		if b.Position == v1alpha1.BaseAfter {
+			after = append(after, base)
+		} else {
+			before = append(before, base)
+		}
+	}
+
+	own := spec
+	own.Bases = nil
+	merged := v1alpha1.BuildTemplateSpec{
+		DeprecatedGeneration: spec.DeprecatedGeneration,
+	}
+	// Declarations of the extending template come first, so that they take
+	// precedence over those of its bases.
+	merged.Parameters = append(merged.Parameters, own.Parameters...)
+	merged.Volumes = append(merged.Volumes, own.Volumes...)
+	merged.Results = append(merged.Results, own.Results...)
+	params := sets.NewString()
+	for _, p := range own.Parameters {
+		params.Insert(p.Name)
+	}
+	volumes := sets.NewString()
+	for _, v := range own.Volumes {
+		volumes.Insert(v.Name)
+	}
+	results := sets.NewString()
+	for _, r := range own.Results {
+		results.Insert(r.Name)
+	}
+
+	steps := sets.NewString()
+	hasTimeouts := false
+	for _, part := range append(append(before, own), after...) {
+		for _, s := range part.Steps {
+			if s.Name != "" && steps.Has(s.Name) {
+				return spec, fmt.Errorf("step %q is declared by more than one of the templates extended by %s", s.Name, path[len(path)-1])
+			}
+			steps.Insert(s.Name)
+		}
+		merged.Steps = append(merged.Steps, part.Steps...)
+
+		// Step timeouts are positional, so pad each template's to the
+		// number of its steps.
+		timeouts := make([]metav1.Duration, len(part.Steps))
+		copy(timeouts, part.StepTimeouts)
+		merged.StepTimeouts = append(merged.StepTimeouts, timeouts...)
+		hasTimeouts = hasTimeouts || len(part.StepTimeouts) > 0
+
+		merged.StepPolicies = append(merged.StepPolicies, part.StepPolicies...)
+
+		for _, p := range part.Parameters {
+			if !params.Has(p.Name) {
+				params.Insert(p.Name)
+				merged.Parameters = append(merged.Parameters, p)
+			}
+		}
+		for _, v := range part.Volumes {
+			if !volumes.Has(v.Name) {
+				volumes.Insert(v.Name)
+				merged.Volumes = append(merged.Volumes, v)
+			}
+		}
+		for _, r := range part.Results {
+			if !results.Has(r.Name) {
+				results.Insert(r.Name)
+				merged.Results = append(merged.Results, r)
+			}
+		}
+	}
+	if !hasTimeouts {
+		merged.StepTimeouts = nil
+	}
+	return merged, nil
+}
+
+// checkCycle returns an error if the template identified by key is already
+// in path.
+func checkCycle(path []string, key string) error {
+	for _, p := range path {
+		if p == key {
+			return fmt.Errorf("template cycle: %s", strings.Join(append(path[:len(path):len(path)], key), " extends "))
+		}
+	}
+	return nil
+}
+
+// Extends returns true if tmpl extends the template of the kind with the
+// name, in namespace for BuildTemplates, directly or through the templates
+// it extends. Bases that can't be fetched are ignored.
+func Extends(tmpl v1alpha1.BuildTemplateInterface, kind v1alpha1.TemplateKind, namespace, name string, getter TemplateGetter) bool {
+	switch t := tmpl.(type) {
+	case *v1alpha1.BuildTemplate:
+		return extends(t.Spec, t.Namespace, templateKey(kind, namespace, name), sets.NewString(), getter)
+	case *v1alpha1.ClusterBuildTemplate:
+		return extends(t.Spec, "", templateKey(kind, namespace, name), sets.NewString(), getter)
+	}
+	return false
+}
+
+// extends returns true if spec, a template in namespace, extends the
+// template identified by want, without revisiting the templates in visited.
+func extends(spec v1alpha1.BuildTemplateSpec, namespace, want string, visited sets.String, getter TemplateGetter) bool {
+	for _, b := range spec.Bases {
+		key := templateKey(v1alpha1.BuildTemplateKind, namespace, b.Name)
+		if b.Kind == v1alpha1.ClusterBuildTemplateKind {
+			key = templateKey(b.Kind, "", b.Name)
+		} else if namespace == "" {
+			continue
+		}
+		if key == want {
+			return true
+		}
+		if visited.Has(key) {
+			continue
+		}
+		visited.Insert(key)
+
+		var base v1alpha1.BuildTemplateSpec
+		baseNamespace := namespace
+		if b.Kind == v1alpha1.ClusterBuildTemplateKind {
+			cbt, err := getter.GetClusterBuildTemplate(b.Name)
+			if err != nil {
+				continue
+			}
+			base, baseNamespace = cbt.Spec, ""
+		} else {
+			bt, err := getter.GetBuildTemplate(namespace, b.Name)
+			if err != nil {
+				continue
+			}
+			base = bt.Spec
+		}
+		if extends(base, baseNamespace, want, visited, getter) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/knative/build/pkg/apis/build/v1alpha1"
+	"github.com/knative/build/pkg/client/clientset/versioned/fake"
+)
+
+var templates = []runtime.Object{
+	&v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "setup"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Parameters: []v1alpha1.ParameterSpec{{Name: "IMAGE", Description: "base"}, {Name: "DIR"}},
+			Steps:      []corev1.Container{{Name: "clone", Image: "git"}},
+			Volumes:    []corev1.Volume{{Name: "cache"}},
+		},
+	},
+	&v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "push"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Bases:        []v1alpha1.BaseTemplate{{Name: "notify", Kind: v1alpha1.ClusterBuildTemplateKind, Position: v1alpha1.BaseAfter}},
+			Steps:        []corev1.Container{{Name: "push", Image: "docker"}},
+			StepTimeouts: []metav1.Duration{{Duration: time.Minute}},
+			Results:      []v1alpha1.ResultSpec{{Name: "digest"}},
+		},
+	},
+	&v1alpha1.ClusterBuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "notify"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Steps: []corev1.Container{{Name: "notify", Image: "curl"}},
+		},
+	},
+	&v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "loop"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Bases: []v1alpha1.BaseTemplate{{Name: "loop"}},
+		},
+	},
+	&v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "clone"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Steps: []corev1.Container{{Name: "clone", Image: "other-git"}},
+		},
+	},
+	&v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "test"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Bases: []v1alpha1.BaseTemplate{{Name: "setup"}},
+			Steps: []corev1.Container{{Name: "test", Image: "golang"}},
+		},
+	},
+	&v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "lint"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Bases: []v1alpha1.BaseTemplate{{Name: "setup"}},
+			Steps: []corev1.Container{{Name: "lint", Image: "golangci"}},
+		},
+	},
+}
+
+func TestFlatten(t *testing.T) {
+	getter := ClientTemplateGetter{Client: fake.NewSimpleClientset(templates...)}
+
+	for _, c := range []struct {
+		desc    string
+		tmpl    v1alpha1.BuildTemplateInterface
+		want    v1alpha1.BuildTemplateSpec
+		wantErr string
+	}{{
+		desc: "no bases",
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Steps: []corev1.Container{{Name: "build", Image: "kaniko"}},
+			},
+		},
+		want: v1alpha1.BuildTemplateSpec{
+			Steps: []corev1.Container{{Name: "build", Image: "kaniko"}},
+		},
+	}, {
+		desc: "bases before and after",
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{
+					{Name: "push", Position: v1alpha1.BaseAfter},
+					{Name: "setup"},
+				},
+				Parameters: []v1alpha1.ParameterSpec{{Name: "IMAGE", Description: "own"}},
+				Steps:      []corev1.Container{{Name: "build", Image: "kaniko"}},
+			},
+		},
+		want: v1alpha1.BuildTemplateSpec{
+			Parameters: []v1alpha1.ParameterSpec{{Name: "IMAGE", Description: "own"}, {Name: "DIR"}},
+			Steps: []corev1.Container{
+				{Name: "clone", Image: "git"},
+				{Name: "build", Image: "kaniko"},
+				{Name: "push", Image: "docker"},
+				{Name: "notify", Image: "curl"},
+			},
+			StepTimeouts: []metav1.Duration{{}, {}, {Duration: time.Minute}, {}},
+			Volumes:      []corev1.Volume{{Name: "cache"}},
+			Results:      []v1alpha1.ResultSpec{{Name: "digest"}},
+		},
+	}, {
+		desc: "bases sharing a base",
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{{Name: "test"}, {Name: "lint"}},
+			},
+		},
+		want: v1alpha1.BuildTemplateSpec{
+			Parameters: []v1alpha1.ParameterSpec{{Name: "IMAGE", Description: "base"}, {Name: "DIR"}},
+			Steps: []corev1.Container{
+				{Name: "clone", Image: "git"},
+				{Name: "test", Image: "golang"},
+				{Name: "lint", Image: "golangci"},
+			},
+			Volumes: []corev1.Volume{{Name: "cache"}},
+		},
+	}, {
+		desc: "cluster template extends cluster template",
+		tmpl: &v1alpha1.ClusterBuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{{Name: "notify", Kind: v1alpha1.ClusterBuildTemplateKind}},
+				Steps: []corev1.Container{{Name: "build", Image: "kaniko"}},
+			},
+		},
+		want: v1alpha1.BuildTemplateSpec{
+			Steps: []corev1.Container{
+				{Name: "notify", Image: "curl"},
+				{Name: "build", Image: "kaniko"},
+			},
+		},
+	}, {
+		desc: "cluster template extends build template",
+		tmpl: &v1alpha1.ClusterBuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{{Name: "setup"}},
+			},
+		},
+		wantErr: "may only extend ClusterBuildTemplates",
+	}, {
+		desc: "cycle",
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{{Name: "loop"}},
+			},
+		},
+		wantErr: `template cycle: BuildTemplate "foo/bar" extends BuildTemplate "foo/loop" extends BuildTemplate "foo/loop"`,
+	}, {
+		desc: "duplicate step names",
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{{Name: "setup"}, {Name: "clone"}},
+			},
+		},
+		wantErr: `step "clone" is declared by more than one`,
+	}, {
+		desc: "missing base",
+		tmpl: &v1alpha1.BuildTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: v1alpha1.BuildTemplateSpec{
+				Bases: []v1alpha1.BaseTemplate{{Name: "nope"}},
+			},
+		},
+		wantErr: "not found",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := Flatten(c.tmpl, getter)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("Flatten() = %v, wanted error containing %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Flatten() = %v", err)
+			}
+			if d := cmp.Diff(c.want, got.TemplateSpec()); d != "" {
+				t.Errorf("Diff:\n%s", d)
+			}
+		})
+	}
+}
+
+func TestExtends(t *testing.T) {
+	getter := ClientTemplateGetter{Client: fake.NewSimpleClientset(templates...)}
+	bar := &v1alpha1.BuildTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+		Spec: v1alpha1.BuildTemplateSpec{
+			Bases: []v1alpha1.BaseTemplate{{Name: "test"}, {Name: "push"}, {Name: "nope"}},
+		},
+	}
+
+	for _, c := range []struct {
+		desc            string
+		kind            v1alpha1.TemplateKind
+		namespace, name string
+		want            bool
+	}{{
+		desc:      "direct base",
+		kind:      v1alpha1.BuildTemplateKind,
+		namespace: "foo",
+		name:      "test",
+		want:      true,
+	}, {
+		desc:      "base of base",
+		kind:      v1alpha1.BuildTemplateKind,
+		namespace: "foo",
+		name:      "setup",
+		want:      true,
+	}, {
+		desc: "cluster base of base",
+		kind: v1alpha1.ClusterBuildTemplateKind,
+		name: "notify",
+		want: true,
+	}, {
+		desc:      "missing base",
+		kind:      v1alpha1.BuildTemplateKind,
+		namespace: "foo",
+		name:      "nope",
+		want:      true,
+	}, {
+		desc:      "other namespace",
+		kind:      v1alpha1.BuildTemplateKind,
+		namespace: "other",
+		name:      "test",
+	}, {
+		desc:      "unrelated",
+		kind:      v1alpha1.BuildTemplateKind,
+		namespace: "foo",
+		name:      "lint",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			if got := Extends(bar, c.kind, c.namespace, c.name, getter); got != c.want {
+				t.Errorf("Extends() = %t, want %t", got, c.want)
+			}
+		})
+	}
+}
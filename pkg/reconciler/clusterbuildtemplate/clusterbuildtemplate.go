@@ -34,6 +34,7 @@ import (
 	buildscheme "github.com/knative/build/pkg/client/clientset/versioned/scheme"
 	listers "github.com/knative/build/pkg/client/listers/build/v1alpha1"
 	"github.com/knative/build/pkg/reconciler/buildtemplate"
+	buildtemplateresources "github.com/knative/build/pkg/reconciler/buildtemplate/resources"
 	"github.com/knative/build/pkg/reconciler/clusterbuildtemplate/resources"
 	cachingclientset "github.com/knative/caching/pkg/client/clientset/versioned"
 	cachinglisters "github.com/knative/caching/pkg/client/listers/caching/v1alpha1"
@@ -91,7 +92,14 @@ func (c *Reconciler) Reconcile(ctx context.Context, key string) error {
 }
 
 func (c *Reconciler) reconcileImageCaches(ctx context.Context, cbt *v1alpha1.ClusterBuildTemplate) error {
-	ics := resources.MakeImageCaches(cbt)
+	// Cache the images of the steps of the templates it extends too.
+	flat, err := buildtemplateresources.Flatten(cbt, buildtemplateresources.ListerTemplateGetter{
+		ClusterBuildTemplates: c.clusterBuildTemplatesLister,
+	})
+	if err != nil {
+		return err
+	}
+	ics := resources.MakeImageCaches(flat.(*v1alpha1.ClusterBuildTemplate))
 
 	eics, err := c.imagesLister.Images(system.Namespace()).List(kmeta.MakeVersionLabelSelector(cbt))
 	if err != nil {
@@ -20,6 +20,7 @@ import (
 	"context"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
 
 	buildclient "github.com/knative/build/pkg/client/injection/client"
@@ -30,10 +31,12 @@ import (
 
 	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
+	"github.com/knative/pkg/kmeta"
 	"github.com/knative/pkg/logging"
 	"github.com/knative/pkg/logging/logkey"
 
 	"github.com/knative/build/pkg/apis/build/v1alpha1"
+	buildtemplateresources "github.com/knative/build/pkg/reconciler/buildtemplate/resources"
 )
 
 const controllerAgentName = "clusterbuildtemplate-controller"
@@ -68,6 +71,27 @@ func NewController(
 	// Set up an event handler for when ClusterBuildTemplate resources change
 	clusterBuildTemplateInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
 
+	// Reconcile the ClusterBuildTemplates that extend a ClusterBuildTemplate
+	// when it changes, so that the images of its new steps are cached too.
+	getter := buildtemplateresources.ListerTemplateGetter{
+		ClusterBuildTemplates: r.clusterBuildTemplatesLister,
+	}
+	clusterBuildTemplateInformer.Informer().AddEventHandler(controller.HandleAll(func(obj interface{}) {
+		object, err := kmeta.DeletionHandlingAccessor(obj)
+		if err != nil {
+			return
+		}
+		cbts, err := r.clusterBuildTemplatesLister.List(labels.Everything())
+		if err != nil {
+			return
+		}
+		for _, cbt := range cbts {
+			if buildtemplateresources.Extends(cbt, v1alpha1.ClusterBuildTemplateKind, "", object.GetName(), getter) {
+				impl.Enqueue(cbt)
+			}
+		}
+	}))
+
 	imageInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: controller.Filter(v1alpha1.SchemeGroupVersion.WithKind("ClusterBuildTemplate")),
 		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
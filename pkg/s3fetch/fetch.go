@@ -0,0 +1,349 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package s3fetch fetches the S3 source of a build from Amazon S3 or an
+// S3-compatible service such as MinIO.
+package s3fetch
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	"github.com/knative/build/pkg/credentials/s3creds"
+	"github.com/knative/build/pkg/extract"
+	"github.com/knative/build/pkg/httpfetch"
+)
+
+// Type is the style of source fetched from a key.
+type Type string
+
+const (
+	// Archive unpacks an archive object.
+	Archive Type = "Archive"
+	// Manifest fetches the objects listed by a manifest object.
+	Manifest Type = "Manifest"
+)
+
+// DefaultRegion is the region of buckets whose region isn't given.
+const DefaultRegion = "us-east-1"
+
+// Fetcher fetches the object at Key, or the objects under Prefix, from
+// Bucket into Dest.
+type Fetcher struct {
+	// Endpoint is the URL of the service. Defaults to Amazon S3 in Region.
+	Endpoint string
+	Region   string
+	Bucket   string
+	Key      string
+	Prefix   string
+	Type     Type
+	Dest     string
+	// Credentials, if set, are used to sign requests; otherwise they are
+	// sent anonymously.
+	Credentials *s3creds.Credentials
+	Client      *http.Client
+}
+
+// manifestEntry is an entry of a source manifest, keyed by the path of the
+// file in the workspace, as written by gcs-fetcher's manifest protocol.
+type manifestEntry struct {
+	SourceURL string `json:"sourceUrl"`
+	SHA1Sum   string `json:"sha1Sum"`
+}
+
+// Fetch fetches the source into Dest.
+func (f Fetcher) Fetch() error {
+	if err := os.MkdirAll(f.Dest, 0755); err != nil {
+		return err
+	}
+	switch {
+	case f.Prefix != "":
+		return f.fetchPrefix()
+	case f.Type == Manifest:
+		return f.fetchManifest()
+	case f.Type == Archive || f.Type == "":
+		return f.fetchArchive()
+	default:
+		return fmt.Errorf("unknown type %q", f.Type)
+	}
+}
+
+// fetchArchive downloads the archive at Key to a temporary file and
+// unpacks it according to its extension.
+func (f Fetcher) fetchArchive() error {
+	tmp, err := ioutil.TempFile("", "s3-fetch")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := f.download(f.Bucket, f.Key, tmp, ""); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return httpfetch.Unpack(tmp, archiveFormat(f.Key), f.Dest)
+}
+
+// archiveFormat returns the format of the archive at key, which defaults
+// to a gzipped tar archive.
+func archiveFormat(key string) httpfetch.Format {
+	switch {
+	case strings.HasSuffix(key, ".zip"):
+		return httpfetch.Zip
+	case strings.HasSuffix(key, ".tar"):
+		return httpfetch.Tar
+	default:
+		return httpfetch.TarGz
+	}
+}
+
+// fetchManifest fetches the files listed by the manifest at Key, checking
+// their checksums.
+func (f Fetcher) fetchManifest() error {
+	body, err := f.get(f.Bucket, f.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	manifest := map[string]manifestEntry{}
+	if err := json.NewDecoder(body).Decode(&manifest); err != nil {
+		return fmt.Errorf("reading manifest s3://%s/%s: %v", f.Bucket, f.Key, err)
+	}
+
+	for name, e := range manifest {
+		u, err := url.Parse(e.SourceURL)
+		if err != nil || u.Scheme != "s3" || u.Host == "" {
+			return fmt.Errorf("manifest entry %q has invalid sourceUrl %q", name, e.SourceURL)
+		}
+		p, err := extract.Target(f.Dest, name)
+		if err != nil {
+			return err
+		}
+		if err := f.downloadFile(u.Host, strings.TrimPrefix(u.Path, "/"), p, e.SHA1Sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchPrefix fetches each of the objects under Prefix.
+func (f Fetcher) fetchPrefix() error {
+	token := ""
+	for {
+		list, err := f.list(token)
+		if err != nil {
+			return err
+		}
+		for _, obj := range list.Contents {
+			name := strings.TrimPrefix(strings.TrimPrefix(obj.Key, f.Prefix), "/")
+			// Skip the placeholders that consoles create for folders.
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+			p, err := extract.Target(f.Dest, name)
+			if err != nil {
+				return err
+			}
+			if err := f.downloadFile(f.Bucket, obj.Key, p, ""); err != nil {
+				return err
+			}
+		}
+		if !list.IsTruncated {
+			return nil
+		}
+		token = list.NextContinuationToken
+	}
+}
+
+// listBucketResult is the response to ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (f Fetcher) list(token string) (*listBucketResult, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {f.Prefix}}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	resp, err := f.do(f.Bucket, "", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	list := &listBucketResult{}
+	if err := xml.NewDecoder(resp.Body).Decode(list); err != nil {
+		return nil, fmt.Errorf("listing s3://%s/%s: %v", f.Bucket, f.Prefix, err)
+	}
+	return list, nil
+}
+
+// downloadFile writes the object to the file at p under Dest.
+func (f Fetcher) downloadFile(bucket, key, p, sha1Sum string) error {
+	out, err := extract.Create(f.Dest, p, 0644)
+	if err != nil {
+		return err
+	}
+	if err := f.download(bucket, key, out, sha1Sum); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// download writes the object to w. If sha1Sum is set, the object must have
+// that checksum.
+func (f Fetcher) download(bucket, key string, w io.Writer, sha1Sum string) error {
+	body, err := f.get(bucket, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	h := sha1.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), body); err != nil {
+		return err
+	}
+	if sha1Sum != "" {
+		if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, sha1Sum) {
+			return fmt.Errorf("%s: expected sha1 %s for s3://%s/%s, got %s",
+				httpfetch.ChecksumMismatchReason, strings.ToLower(sha1Sum), bucket, key, got)
+		}
+	}
+	return nil
+}
+
+func (f Fetcher) get(bucket, key string) (io.ReadCloser, error) {
+	resp, err := f.do(bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// s3Error is the body of an error response.
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// do sends a signed GET request for the key of the bucket, and returns the
+// response if it succeeded.
+func (f Fetcher) do(bucket, key string, query url.Values) (*http.Response, error) {
+	u, err := f.objectURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := Sign(req, nil, f.region(), f.Credentials); err != nil {
+		return nil, err
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		e := s3Error{}
+		if err := xml.NewDecoder(resp.Body).Decode(&e); err != nil || e.Code == "" {
+			return nil, fmt.Errorf("fetching s3://%s/%s: unexpected status %s", bucket, key, resp.Status)
+		}
+		return nil, fmt.Errorf("fetching s3://%s/%s: %s: %s", bucket, key, e.Code, e.Message)
+	}
+	return resp, nil
+}
+
+func (f Fetcher) region() string {
+	if f.Region == "" {
+		return DefaultRegion
+	}
+	return f.Region
+}
+
+func (f Fetcher) objectURL(bucket, key string) (*url.URL, error) {
+	return ObjectURL(f.Endpoint, f.region(), bucket, key)
+}
+
+// ObjectURL returns the path-style URL of the key of the bucket, which
+// S3-compatible services support regardless of their DNS setup. An empty
+// endpoint is that of Amazon S3 in the region.
+func ObjectURL(endpoint, region, bucket, key string) (*url.URL, error) {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	p := strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + key
+	u.Path, u.RawPath = p, escapePath(p)
+	return u, nil
+}
+
+// Sign signs the request to a URL returned by ObjectURL with the
+// credentials, if any; the body, if not nil, is read to compute its hash
+// unless the request's X-Amz-Content-Sha256 header is set.
+func Sign(req *http.Request, body io.ReadSeeker, region string, c *s3creds.Credentials) error {
+	if c == nil {
+		return nil
+	}
+	signer := v4.NewSigner(credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken),
+		func(s *v4.Signer) { s.DisableURIPathEscaping = true })
+	_, err := signer.Sign(req, body, "s3", region, time.Now())
+	return err
+}
+
+// escapePath escapes p as S3 expects in the canonical request that is
+// signed: every byte except unreserved characters and "/" is escaped.
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/knative/build/pkg/credentials/s3creds"
+)
+
+var testCredentials = &s3creds.Credentials{
+	AccessKeyID:     "minio",
+	SecretAccessKey: "minio123",
+	SessionToken:    "token",
+}
+
+// testS3 serves objects like an S3-compatible service that requires
+// signed requests.
+type testS3 struct {
+	t *testing.T
+	// objects are keyed by bucket/key.
+	objects map[string][]byte
+}
+
+func (s *testS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.signed(r) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if r.URL.Query().Get("list-type") == "2" {
+		s.list(w, parts[0], r.URL.Query())
+		return
+	}
+	b, ok := s.objects[strings.Join(parts, "/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+		return
+	}
+	w.Write(b)
+}
+
+// signed returns true if the request was signed with testCredentials.
+func (s *testS3) signed(r *http.Request) bool {
+	signTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		s.t.Fatalf("NewRequest() = %v", err)
+	}
+	signer := v4.NewSigner(credentials.NewStaticCredentials(testCredentials.AccessKeyID, testCredentials.SecretAccessKey, testCredentials.SessionToken),
+		func(s *v4.Signer) { s.DisableURIPathEscaping = true })
+	if _, err := signer.Sign(req, nil, "s3", DefaultRegion, signTime); err != nil {
+		s.t.Fatalf("Sign() = %v", err)
+	}
+	return req.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+// list lists the objects under the prefix two at a time.
+func (s *testS3) list(w http.ResponseWriter, bucket string, query map[string][]string) {
+	prefix := bucket + "/" + first(query["prefix"])
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, strings.TrimPrefix(k, bucket+"/"))
+		}
+	}
+	sort.Strings(keys)
+	start, _ := strconv.Atoi(first(query["continuation-token"]))
+	end := start + 2
+	res := listBucketResult{}
+	if end < len(keys) {
+		res.IsTruncated = true
+		res.NextContinuationToken = strconv.Itoa(end)
+	} else {
+		end = len(keys)
+	}
+	for _, k := range keys[start:end] {
+		res.Contents = append(res.Contents, struct{ Key string }{k})
+	}
+	xml.NewEncoder(w).Encode(res)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("WriteHeader() = %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipped(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Create() = %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func sha1Hex(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// readDir returns the contents of the files under dir, keyed by their
+// slash-separated relative paths.
+func readDir(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() = %v", err)
+	}
+	return files
+}
+
+func TestFetch(t *testing.T) {
+	s3 := &testS3{t: t, objects: map[string][]byte{
+		"src/app.tar.gz":       tarGz(t, map[string]string{"main.go": "package main", "pkg/lib.go": "package pkg"}),
+		"src/app v2.zip":       zipped(t, map[string]string{"main.go": "package main // v2"}),
+		"src/manifest.json":    []byte(fmt.Sprintf(`{"main.go": {"sourceUrl": "s3://blobs/a", "sha1Sum": %q}, "docs/README": {"sourceUrl": "s3://blobs/b"}}`, sha1Hex("package main"))),
+		"src/bad.json":         []byte(`{"main.go": {"sourceUrl": "s3://blobs/a", "sha1Sum": "0000000000000000000000000000000000000000"}}`),
+		"src/evil.json":        []byte(`{"../evil": {"sourceUrl": "s3://blobs/a"}}`),
+		"blobs/a":              []byte("package main"),
+		"blobs/b":              []byte("docs"),
+		"tree/dir/":            nil,
+		"tree/dir/a.txt":       []byte("a"),
+		"tree/dir/sub/b.txt":   []byte("b"),
+		"tree/dir/sub/c.txt":   []byte("c"),
+		"tree/dir/sub/d/e.txt": []byte("e"),
+		"tree/other.txt":       []byte("other"),
+	}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	for _, c := range []struct {
+		desc    string
+		f       Fetcher
+		want    map[string]string
+		wantErr string
+	}{{
+		desc: "tar.gz archive",
+		f:    Fetcher{Bucket: "src", Key: "app.tar.gz"},
+		want: map[string]string{"main.go": "package main", "pkg/lib.go": "package pkg"},
+	}, {
+		desc: "zip archive with escaped key",
+		f:    Fetcher{Bucket: "src", Key: "app v2.zip", Type: Archive},
+		want: map[string]string{"main.go": "package main // v2"},
+	}, {
+		desc: "manifest",
+		f:    Fetcher{Bucket: "src", Key: "manifest.json", Type: Manifest},
+		want: map[string]string{"main.go": "package main", "docs/README": "docs"},
+	}, {
+		desc:    "manifest checksum mismatch",
+		f:       Fetcher{Bucket: "src", Key: "bad.json", Type: Manifest},
+		wantErr: "ChecksumMismatch: expected sha1 0000000000000000000000000000000000000000 for s3://blobs/a",
+	}, {
+		desc:    "manifest outside of destination",
+		f:       Fetcher{Bucket: "src", Key: "evil.json", Type: Manifest},
+		wantErr: `"../evil" is outside of the destination`,
+	}, {
+		desc: "prefix",
+		f:    Fetcher{Bucket: "tree", Prefix: "dir/"},
+		want: map[string]string{"a.txt": "a", "sub/b.txt": "b", "sub/c.txt": "c", "sub/d/e.txt": "e"},
+	}, {
+		desc:    "missing key",
+		f:       Fetcher{Bucket: "src", Key: "missing.tar.gz"},
+		wantErr: "fetching s3://src/missing.tar.gz: NoSuchKey: The specified key does not exist.",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "s3-fetch")
+			if err != nil {
+				t.Fatalf("TempDir() = %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			f := c.f
+			f.Endpoint = server.URL
+			f.Credentials = testCredentials
+			f.Dest = filepath.Join(dir, "workspace")
+			err = f.Fetch()
+			if c.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), c.wantErr) {
+					t.Fatalf("Fetch() = %v, wanted %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() = %v", err)
+			}
+			if d := cmp.Diff(c.want, readDir(t, f.Dest)); d != "" {
+				t.Errorf("fetched files diff -want, +got: %v", d)
+			}
+		})
+	}
+}
+
+func TestFetchAnonymous(t *testing.T) {
+	server := httptest.NewServer(&testS3{t: t})
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "s3-fetch")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = Fetcher{Endpoint: server.URL, Bucket: "src", Key: "app.tar.gz", Dest: dir}.Fetch()
+	want := "fetching s3://src/app.tar.gz: AccessDenied: Access Denied."
+	if err == nil || err.Error() != want {
+		t.Errorf("Fetch() = %v, wanted %q", err, want)
+	}
+}
+
+func TestObjectURL(t *testing.T) {
+	for _, c := range []struct {
+		f    Fetcher
+		want string
+	}{{
+		f:    Fetcher{},
+		want: "https://s3.us-east-1.amazonaws.com/bucket/dir/a%2Bb%20c.txt",
+	}, {
+		f:    Fetcher{Region: "eu-west-1"},
+		want: "https://s3.eu-west-1.amazonaws.com/bucket/dir/a%2Bb%20c.txt",
+	}, {
+		f:    Fetcher{Endpoint: "http://minio.example.com:9000/"},
+		want: "http://minio.example.com:9000/bucket/dir/a%2Bb%20c.txt",
+	}} {
+		u, err := c.f.objectURL("bucket", "dir/a+b c.txt")
+		if err != nil {
+			t.Fatalf("objectURL() = %v", err)
+		}
+		if got := u.String(); got != c.want {
+			t.Errorf("objectURL() = %s, wanted %s", got, c.want)
+		}
+	}
+}